@@ -81,18 +81,64 @@ func (client *Client) TransactionReceipt(ctx context.Context, hash common.Hash)
 	return &receipt, nil
 }
 
+// TransactionReceiptResponse returns the receipt of a mined transaction along with whether the block
+// containing it has been orphaned by a reorg seen by the proxy.
+func (client *Client) TransactionReceiptResponse(ctx context.Context, hash common.Hash) (*proxy.TxReceiptResponse, error) {
+	var receipt proxy.TxReceiptResponse
+	if err := client.executeRequest(ctx, &receipt, http.MethodGet, fmt.Sprintf("%v%v", proxy.EthV0TxReceiptPrfx, hash.Hex()), nil); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+func (client *Client) TraceTransaction(ctx context.Context, hash common.Hash) (*proxy.TraceResponse, error) {
+	var trace proxy.TraceResponse
+	if err := client.executeRequest(ctx, &trace, http.MethodGet, fmt.Sprintf("%v%v", proxy.EthV0TxTracePrfx, hash.Hex()), nil); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+func (client *Client) TxPool(ctx context.Context, address common.Address) (*proxy.TxPoolResponse, error) {
+	var pool proxy.TxPoolResponse
+	if err := client.executeRequest(ctx, &pool, http.MethodGet, fmt.Sprintf("%v%v", proxy.EthV0TxPoolPrfx, address.Hex()), nil); err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
 func (client *Client) SendTransaction(ctx context.Context, tx *types.Transaction) (*proxy.TxResponse, error) {
 	b, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
 	var txResponse proxy.TxResponse
-	if err := client.executeRequest(ctx, &txResponse, http.MethodPost, fmt.Sprintf("%v0x%x", proxy.EthV0SendTxPrfx, b), nil); err != nil {
+	if err := client.executeRequest(ctx, &txResponse, http.MethodPost, proxy.EthV0SendTxBodyEndPnt, &proxy.SendTxRequest{Tx: b}); err != nil {
 		return nil, err
 	}
 	return &txResponse, nil
 }
 
+func (client *Client) SimulateTransaction(ctx context.Context, tx *types.Transaction) (*proxy.SimulationResponse, error) {
+	b, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var sim proxy.SimulationResponse
+	if err := client.executeRequest(ctx, &sim, http.MethodPost, proxy.EthV0SimulateEndPnt, &proxy.SendTxRequest{Tx: b}); err != nil {
+		return nil, err
+	}
+	return &sim, nil
+}
+
+func (client *Client) Fee(ctx context.Context) (*proxy.FeeResponse, error) {
+	var fee proxy.FeeResponse
+	if err := client.executeRequest(ctx, &fee, http.MethodGet, proxy.EthV0FeeEndPnt, nil); err != nil {
+		return nil, err
+	}
+	return &fee, nil
+}
+
 func (client *Client) executeRequest(ctx context.Context, result any, method, path string, body any) (err error) {
 
 	op := &requestOp{
@@ -166,6 +212,10 @@ func (client *Client) doRequest(ctx context.Context, method, path string, msg an
 		}
 	}
 
+	if minBlock, ok := minBlockFromContext(ctx); ok {
+		path = fmt.Sprintf("%s?%s=%d", path, proxy.MinBlockKey, minBlock)
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, client.baseURL+path, io.NopCloser(bytes.NewReader(body)))
 	if err != nil {
 		return nil, http.StatusInternalServerError, err
@@ -209,6 +259,21 @@ func (op *requestOp) wait(ctx context.Context) (*jsonResult, error) {
 	}
 }
 
+type minBlockKey struct{}
+
+// WithMinBlock returns a copy of ctx which asks the proxy to serve reads made with it only from
+// upstream nodes that have seen at least the given block, e.g. the block a transaction sent by
+// the caller was mined in.
+func WithMinBlock(ctx context.Context, number uint64) context.Context {
+	return context.WithValue(ctx, minBlockKey{}, number)
+}
+
+// minBlockFromContext returns the minimum block set with WithMinBlock.
+func minBlockFromContext(ctx context.Context) (uint64, bool) {
+	number, ok := ctx.Value(minBlockKey{}).(uint64)
+	return number, ok
+}
+
 type mdHeaderKey struct{}
 
 // headersFromContext is used to extract http.Header from context.
@@ -88,6 +88,16 @@ func TestClient(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	t.Run("simulate-tx", func(t *testing.T) {
+		sim, err := cl.SimulateTransaction(ctx, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sim.Success || sim.GasEstimate != tx.Gas() {
+			t.Fatalf("unexpected simulation result %+v", *sim)
+		}
+	})
+
 	t.Run("send-tx", func(t *testing.T) {
 		t.Logf("sending %v ETH to %v", amount, toAddr.Hex())
 		txResp, err := cl.SendTransaction(ctx, tx)
@@ -100,6 +110,17 @@ func TestClient(t *testing.T) {
 		}
 	})
 
+	t.Run("txpool", func(t *testing.T) {
+
+		pool, err := cl.TxPool(ctx, genesisAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pool.Source != proxy.TxPoolSourceNode || len(pool.Pending) != 1 || pool.Pending[0].Txid != txHash.Hex() {
+			t.Fatalf("unexpected pool contents %+v", *pool)
+		}
+	})
+
 	blkHash := s.Eth.Backend.Commit()
 	t.Logf("new block: %v", blkHash.Hex())
 
@@ -125,6 +146,83 @@ func TestClient(t *testing.T) {
 		}
 	})
 
+	t.Run("tx-by-receipt-response", func(t *testing.T) {
+
+		rec, err := cl.TransactionReceiptResponse(ctx, txHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.BlockHash.Cmp(blkHash) != 0 {
+			t.Fatalf("unexpted blockHash, got %v want %v", rec.BlockHash.Hex(), blkHash.Hex())
+		}
+		if rec.Reorged {
+			t.Fatalf("unexpected reorged receipt")
+		}
+	})
+
+	t.Run("tx-by-receipt-min-block", func(t *testing.T) {
+
+		rec, err := cl.TransactionReceipt(WithMinBlock(ctx, 1), txHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.BlockHash.Cmp(blkHash) != 0 {
+			t.Fatalf("unexpted blockHash, got %v want %v", rec.BlockHash.Hex(), blkHash.Hex())
+		}
+	})
+
+	t.Run("fee", func(t *testing.T) {
+
+		fee, err := cl.Fee(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fee.BlobBaseFee == "" {
+			t.Fatalf("expected blob base fee, got %+v", *fee)
+		}
+	})
+
+	// blob transactions
+
+	blobTx, err := s.Eth.Backend.NewBlobTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("send-blob-tx", func(t *testing.T) {
+		txResp, err := cl.SendTransaction(ctx, blobTx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := txResp.Txid, blobTx.Hash().Hex(); g != w {
+			t.Fatalf("unexpted txid, got %v want %v", g, w)
+		}
+	})
+
+	s.Eth.Backend.Commit()
+
+	t.Run("blob-tx-by-hash", func(t *testing.T) {
+
+		txResp, err := cl.TransactionByHash(ctx, blobTx.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := len(txResp.Tx.BlobHashes()), 1; g != w {
+			t.Fatalf("unexpected blob hash count, got %v want %v", g, w)
+		}
+	})
+
+	t.Run("blob-tx-receipt", func(t *testing.T) {
+
+		rec, err := cl.TransactionReceipt(ctx, blobTx.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.BlobGasUsed == 0 || rec.BlobGasPrice == nil {
+			t.Fatalf("expected blob gas fields, got used=%v price=%v", rec.BlobGasUsed, rec.BlobGasPrice)
+		}
+	})
+
 	// errors
 	t.Run("context-cancelled", func(t *testing.T) {
 		ctxCancelled, cancelFunc := context.WithCancel(ctx)
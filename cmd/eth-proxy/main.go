@@ -67,17 +67,36 @@ func main() {
 
 	cfg.Sanitize()
 
+	if err := cfg.Validate(); err != nil {
+		panic(fmt.Sprintf("invalid config: %v", err))
+	}
+
 	l, err := proxy.NewLogger(cfg.LogLevel, cfg.LogFormat)
 	if err != nil {
 		panic(err)
 	}
 
-	multiClient, err := proxy.NewMultiNodeClient(cfg.URLs, proxy.NewEthClient)
+	multiClient, err := proxy.NewChainClient(cfg.DefaultChain(), proxy.NewEthClientFromConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	chains, err := proxy.NewChains(&cfg, proxy.NewEthClientFromConfig)
 	if err != nil {
 		panic(err)
 	}
 
-	srv := proxy.New(cfg.Port, l, multiClient)
+	var beaconClient *proxy.BeaconClient
+	if len(cfg.Beacon.Nodes) > 0 {
+		if beaconClient, err = proxy.NewBeaconClient(cfg.Beacon); err != nil {
+			panic(err)
+		}
+		if err := beaconClient.SetRetryConfig(cfg.Retry); err != nil {
+			panic(err)
+		}
+	}
+
+	srv := proxy.NewWithChains(&cfg, l, multiClient, beaconClient, chains)
 
 	srv.Start()
 	sigChan := make(chan os.Signal, 1)
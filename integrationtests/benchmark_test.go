@@ -2,6 +2,7 @@ package integrationtests
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"sync/atomic"
@@ -70,3 +71,56 @@ func BenchmarkConcurrentRequests(b *testing.B) {
 		float64(numRequests*1000000)/float64(mean.Microseconds()),
 	)
 }
+
+func BenchmarkConcurrentClients(b *testing.B) {
+
+	const numClients = 1000
+
+	s := stack.MockMultiNodeEthProxyService(b, "error", 3)
+
+	genesisAddr := s.Eth.Backend.BankAccount.From
+
+	endpnt := fmt.Sprintf("%v%v", proxy.EthV0BalancePrfx, genesisAddr.Hex())
+	time.Sleep(10 * time.Millisecond)
+	url := fmt.Sprintf("http://0.0.0.0%v%v", s.Service.Server().Addr(), endpnt)
+
+	// keep a connection open per client so that the benchmark measures the proxy rather than TCP setup
+	client := &http.Client{Transport: &http.Transport{MaxIdleConns: numClients, MaxIdleConnsPerHost: numClients}}
+	defer client.CloseIdleConnections()
+
+	var wg sync.WaitGroup
+	var counter = new(atomic.Int64)
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < numClients; j++ {
+			index := j
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response, err := client.Get(url)
+				if err != nil {
+					b.Errorf("%d: %v", index, err)
+					return
+				}
+				_, _ = io.Copy(io.Discard, response.Body)
+				response.Body.Close()
+				if response.StatusCode != http.StatusOK {
+					b.Errorf("%d: unexpected error code: %v", index, response.StatusCode)
+				}
+				counter.Add(1)
+			}()
+		}
+		wg.Wait()
+	}
+	elapsed := time.Since(start)
+
+	b.ReportMetric(float64(counter.Load())/elapsed.Seconds(), "req/s")
+	b.Logf("executed %d requests from %d concurrent clients in %v (%v req/s)\n",
+		counter.Load(),
+		numClients,
+		elapsed,
+		float64(counter.Load())/elapsed.Seconds(),
+	)
+}
@@ -52,6 +52,26 @@ func Test_E2EStackRead(t *testing.T) {
 			&proxy.BalanceResponse{Balance: stack.OneEther.String()},
 			http.StatusOK,
 		},
+		{
+			"eth-balance-verified",
+			func() string {
+				genesisAddr := s.Eth.Backend.BankAccount.From
+				return fmt.Sprintf("%v%v?%v=true", proxy.EthV0BalancePrfx, genesisAddr.Hex(), proxy.VerifiedKey)
+			},
+			http.MethodGet,
+			&proxy.BalanceResponse{Balance: stack.OneEther.String(), Verified: true},
+			http.StatusOK,
+		},
+		{
+			"eth-nonce-verified",
+			func() string {
+				genesisAddr := s.Eth.Backend.BankAccount.From
+				return fmt.Sprintf("%v%v?%v=true", proxy.EthV0NoncePrfx, genesisAddr.Hex(), proxy.VerifiedKey)
+			},
+			http.MethodGet,
+			&proxy.NonceResponse{Nonce: 0, Verified: true},
+			http.StatusOK,
+		},
 		{
 			"metrics",
 			func() string { return proxy.MetricsEndPnt },
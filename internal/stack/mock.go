@@ -2,6 +2,7 @@ package stack
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/ATMackay/eth-proxy/proxy"
@@ -9,6 +10,37 @@ import (
 )
 
 func MockEthProxyService(t testing.TB, logLevel string) *SvcStack {
+	return mockEthProxyService(t, logLevel, func(bk *BlockchainBackend) proxy.SimpleEthClient {
+		rpcClient, err := bk.RPCClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(rpcClient.Close)
+		return proxy.NewEthClientFromRPC(rpcClient)
+	})
+}
+
+// MockMultiNodeEthProxyService starts a proxy service routing requests to the given number of
+// upstream nodes, each with its own persistent IPC connection to the simulated backend.
+func MockMultiNodeEthProxyService(t testing.TB, logLevel string, nodes int) *SvcStack {
+	return mockEthProxyService(t, logLevel, func(bk *BlockchainBackend) proxy.SimpleEthClient {
+		urls := strings.TrimSuffix(strings.Repeat(bk.IPCPath+",", nodes), ",")
+		multiClient, err := proxy.NewMultiNodeClient(urls, func(url string) (proxy.SimpleEthClient, error) {
+			client, err := proxy.NewEthClient(url)
+			if err != nil {
+				return nil, err
+			}
+			t.Cleanup(client.(interface{ Close() }).Close)
+			return client, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return multiClient
+	})
+}
+
+func mockEthProxyService(t testing.TB, logLevel string, newClient func(bk *BlockchainBackend) proxy.SimpleEthClient) *SvcStack {
 
 	bk, err := NewEthBackend()
 	if err != nil {
@@ -26,6 +58,7 @@ func MockEthProxyService(t testing.TB, logLevel string) *SvcStack {
 		Port:      8080,
 		LogLevel:  logLevel, // change to 'info' or 'debug' to see the proxy service logs
 		LogFormat: "plain",
+		Proof:     proxy.ProofConfig{AllowSingleNode: true}, // the simulated backend is the only node
 	}
 
 	l, err := proxy.NewLogger(cfg.LogLevel, cfg.LogFormat)
@@ -33,9 +66,7 @@ func MockEthProxyService(t testing.TB, logLevel string) *SvcStack {
 		t.Fatal(err)
 	}
 
-	ethClient := bk.Client()
-
-	svc := proxy.New(cfg.Port, l, ethClient)
+	svc := proxy.NewFromConfig(cfg, l, newClient(bk))
 
 	svc.Start()
 
@@ -5,15 +5,22 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
 
 	"github.com/ATMackay/eth-proxy/proxy"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethclient/simulated"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
 )
 
 //
@@ -36,6 +43,7 @@ type SvcStack struct {
 type BlockchainBackend struct {
 	*simulated.Backend
 	BankAccount *EOA
+	IPCPath     string // IPC endpoint of the simulated node
 }
 
 func NewEthBackend() (*BlockchainBackend, error) {
@@ -48,22 +56,34 @@ func NewEthBackend() (*BlockchainBackend, error) {
 
 	log.SetDefault(log.NewLogger(log.DiscardHandler()))
 
+	ipcPath := filepath.Join(os.TempDir(), fmt.Sprintf("eth-proxy-%x.ipc", bankAccount.From[:8]))
+
 	backend := &BlockchainBackend{
-		Backend: simTestBackend(bankAccount.From),
+		Backend: simTestBackend(bankAccount.From, ipcPath),
+		IPCPath: ipcPath,
 	}
 	backend.BankAccount = bankAccount
 
 	return backend, nil
 }
 
-func simTestBackend(testAddr common.Address) *simulated.Backend {
+func simTestBackend(testAddr common.Address, ipcPath string) *simulated.Backend {
 	return simulated.NewBackend(
 		types.GenesisAlloc{
 			testAddr: {Balance: OneEther},
 		},
+		func(nodeConf *node.Config, _ *ethconfig.Config) {
+			nodeConf.IPCPath = ipcPath
+		},
 	)
 }
 
+// RPCClient dials the simulated node over IPC. Unlike the client returned by Client it
+// can make calls outside of the ethclient library (e.g. eth_getProof).
+func (b *BlockchainBackend) RPCClient() (*rpc.Client, error) {
+	return rpc.Dial(b.IPCPath)
+}
+
 type EOA struct {
 	*bind.TransactOpts
 	PrivateKey *ecdsa.PrivateKey
@@ -158,3 +178,56 @@ func (b *BlockchainBackend) NewTx() (*types.Transaction, error) {
 	})
 	return types.SignTx(tx, types.LatestSignerForChainID(chainid), key)
 }
+
+// NewBlobTx creates a signed EIP-4844 transaction carrying a single (empty) blob. The
+// transaction retains its sidecar so that it marshals to the network form.
+func (b *BlockchainBackend) NewBlobTx() (*types.Transaction, error) {
+
+	client := b.Client()
+
+	key := b.BankAccount.PrivateKey
+
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice := new(big.Int).Add(head.BaseFee, big.NewInt(params.GWei))
+
+	fromAddr := crypto.PubkeyToAddress(key.PublicKey)
+	chainid, err := client.ChainID(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := client.PendingNonceAt(context.Background(), fromAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	var blob kzg4844.Blob
+	commitment, err := kzg4844.BlobToCommitment(&blob)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := kzg4844.ComputeBlobProof(&blob, commitment)
+	if err != nil {
+		return nil, err
+	}
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{blob},
+		Commitments: []kzg4844.Commitment{commitment},
+		Proofs:      []kzg4844.Proof{proof},
+	}
+
+	tx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig(chainid),
+		Nonce:      nonce,
+		GasTipCap:  uint256.NewInt(params.GWei),
+		GasFeeCap:  uint256.MustFromBig(gasPrice),
+		Gas:        21000,
+		To:         common.HexToAddress(DummyAddr),
+		BlobFeeCap: uint256.NewInt(params.GWei),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	})
+	return types.SignTx(tx, types.LatestSignerForChainID(chainid), key)
+}
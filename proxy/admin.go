@@ -0,0 +1,411 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	AdminV0NodesEndPnt = "/admin/v0/nodes"  // list and add upstream nodes
+	AdminV0NodesPrfx   = "/admin/v0/nodes/" // drain and remove upstream nodes
+
+	drainPollInterval = 10 * time.Millisecond // interval at which a removed node is checked for in-flight requests
+)
+
+var (
+	adminV0NodeEndPnt      = AdminV0NodesPrfx + IDKey
+	adminV0NodeDrainEndPnt = AdminV0NodesPrfx + IDKey + "/drain"
+)
+
+var (
+	// ErrNodeNotFound is returned when an admin request names an unknown node.
+	ErrNodeNotFound = errors.New("node not found")
+	// ErrLastNode is returned when removing a node would leave the client without upstream nodes.
+	ErrLastNode = errors.New("cannot remove the last upstream node")
+
+	errNodeValidation = errors.New("node validation failed")
+)
+
+// NodeAdmin is implemented by clients whose upstream nodes can be listed, added, drained and removed at runtime.
+type NodeAdmin interface {
+	Nodes() []NodeInfo
+	AddNode(ctx context.Context, cfg NodeConfig) (NodeInfo, error)
+	DrainNode(id string) (NodeInfo, error)
+	RemoveNode(ctx context.Context, id string) error
+}
+
+// chainIDReader is implemented by clients which can report the chain ID of their node.
+type chainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// NodeInfo describes an upstream node and its routing statistics.
+type NodeInfo struct {
+	ID           string       `json:"id"`
+	URL          string       `json:"url"`
+	Transport    Transport    `json:"transport,omitempty"`
+	Tags         []string     `json:"tags,omitempty"`
+	Weight       int          `json:"weight"`
+	State        BreakerState `json:"state"`
+	Draining     bool         `json:"draining,omitempty"`
+	Disconnected bool         `json:"disconnected,omitempty"`
+	Head         uint64       `json:"head,omitempty"`
+	Outstanding  int64        `json:"outstanding"`
+	LatencyMs    int64        `json:"latency_ms,omitempty"`
+	QuotaUsed    int64        `json:"quota_used,omitempty"`
+}
+
+// info returns the current description of the node.
+func (i *item) info() NodeInfo {
+	info := NodeInfo{
+		ID:          i.id,
+		URL:         RedactURL(i.url),
+		Transport:   TransportOf(i.url),
+		Weight:      i.weight,
+		State:       i.breaker.State(),
+		Draining:    i.draining.Load(),
+		Head:        i.head.Load(),
+		Outstanding: i.stats.outstanding.Load(),
+		LatencyMs:   i.stats.latency().Milliseconds(),
+		QuotaUsed:   i.budget.used(),
+	}
+	for tag := range i.tags {
+		info.Tags = append(info.Tags, tag)
+	}
+	slices.Sort(info.Tags)
+	return info
+}
+
+// Nodes returns a description of every node, including those waiting to be redialled, in config order.
+func (m *multiNodeClient) Nodes() []NodeInfo {
+	nodes := m.snapshot()
+	infos := make([]NodeInfo, len(nodes))
+	for i, node := range nodes {
+		infos[i] = node.info()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.disconnected {
+		info := NodeInfo{ID: d.id, URL: RedactURL(d.cfg.URL), Transport: TransportOf(d.cfg.URL), Tags: slices.Sorted(slices.Values(d.cfg.Tags)), Weight: max(d.cfg.Weight, 1), State: BreakerOpen, Disconnected: true}
+		infos = slices.Insert(infos, configPosition(infos, func(info NodeInfo) string { return info.ID }, d.id), info)
+	}
+	return infos
+}
+
+// AddNode dials the node described by cfg and adds it to rotation once it has passed the checks made
+// by connect.
+func (m *multiNodeClient) AddNode(ctx context.Context, cfg NodeConfig) (NodeInfo, error) {
+	if cfg.URL == "" {
+		return NodeInfo{}, fmt.Errorf("%w: no url supplied", errNodeValidation)
+	}
+	client, head, err := m.connect(ctx, cfg)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+
+	m.mu.Lock()
+	node := newItem(fmt.Sprintf("%s%d", m.idPrefix, m.nextID), cfg, client, m.healthCfg)
+	node.head.Store(head)
+	m.nextID++
+	m.updateLocked(func(st *routingState) { st.nodes = append(slices.Clip(st.nodes), node) })
+	m.mu.Unlock()
+	return node.info(), nil
+}
+
+// connect dials url and checks that the node answers a block number request, is not syncing and, where
+// both nodes report it, is on the same chain as the existing nodes. The connection is closed if any
+// check fails.
+func (m *multiNodeClient) connect(ctx context.Context, cfg NodeConfig) (SimpleEthClient, uint64, error) {
+	client, err := m.dial(cfg)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: dial error: %v", errNodeValidation, redactError(err, cfg.URL))
+	}
+	head, err := m.validate(ctx, client)
+	if err != nil {
+		if closer, ok := client.(interface{ Close() }); ok {
+			closer.Close()
+		}
+		return nil, 0, fmt.Errorf("%w: %v", errNodeValidation, err)
+	}
+	return client, head, nil
+}
+
+// validate runs the checks made by connect on a dialled node, returning its latest block number.
+func (m *multiNodeClient) validate(ctx context.Context, client SimpleEthClient) (uint64, error) {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("block number error: %v", err)
+	}
+	if syncReader, ok := client.(ethereum.ChainSyncReader); ok {
+		progress, err := syncReader.SyncProgress(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("sync progress error: %v", err)
+		}
+		if progress != nil {
+			return 0, fmt.Errorf("node is syncing")
+		}
+	}
+	return head, m.checkChainID(ctx, client)
+}
+
+// checkChainID compares the chain ID reported by client with the chain ID set by SetChainID or, if none
+// was set, with the first existing node able to report one.
+func (m *multiNodeClient) checkChainID(ctx context.Context, client SimpleEthClient) error {
+	reader, ok := client.(chainIDReader)
+	if !ok {
+		return nil
+	}
+	want, err := reader.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("chain id error: %v", err)
+	}
+	m.mu.Lock()
+	chainID := m.chainID
+	m.mu.Unlock()
+	if chainID != nil {
+		if want.Cmp(chainID) != 0 {
+			return fmt.Errorf("chain id %v does not match configured chain id %v", want, chainID)
+		}
+		return nil
+	}
+	for _, node := range m.snapshot() {
+		existing, ok := node.client.(chainIDReader)
+		if !ok {
+			continue
+		}
+		got, err := existing.ChainID(ctx)
+		if err != nil {
+			continue
+		}
+		if got.Cmp(want) != 0 {
+			return fmt.Errorf("chain id %v does not match chain id %v of node %s", want, got, node.id)
+		}
+		return nil
+	}
+	return nil
+}
+
+// node returns the node with the given id.
+func (m *multiNodeClient) node(id string) (*item, error) {
+	for _, node := range m.snapshot() {
+		if node.id == id {
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: '%s'", ErrNodeNotFound, id)
+}
+
+// DrainNode takes a node out of rotation. Requests in flight on the node are allowed to finish.
+func (m *multiNodeClient) DrainNode(id string) (NodeInfo, error) {
+	node, err := m.node(id)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+	node.draining.Store(true)
+	return node.info(), nil
+}
+
+// RemoveNode drains a node, waits for its in-flight requests to finish (or ctx to expire) and removes
+// it from the client, closing its connection. Disconnected nodes are no longer redialled.
+func (m *multiNodeClient) RemoveNode(ctx context.Context, id string) error {
+	node, err := m.node(id)
+	if err != nil {
+		return m.removeDisconnected(id, err)
+	}
+	if len(m.snapshot()) == 1 {
+		return ErrLastNode
+	}
+	node.draining.Store(true)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for node.stats.outstanding.Load() > 0 && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	m.mu.Lock()
+	if len(m.snapshot()) == 1 {
+		m.mu.Unlock()
+		node.draining.Store(false)
+		return ErrLastNode // the other nodes were removed while this one was draining
+	}
+	m.updateLocked(func(st *routingState) {
+		st.nodes = slices.DeleteFunc(slices.Clone(st.nodes), func(i *item) bool { return i == node })
+	})
+	m.mu.Unlock()
+
+	for _, gauge := range []*prometheus.GaugeVec{nodeLatencyGauge, nodeQuotaUsedGauge, nodeQuotaLimitGauge} {
+		gauge.DeleteLabelValues(node.id)
+	}
+	if closer, ok := node.client.(interface{ Close() }); ok {
+		closer.Close()
+	}
+	return nil
+}
+
+// removeDisconnected stops redialling the node with the given id, returning notFound if no such node
+// is waiting to be redialled.
+func (m *multiNodeClient) removeDisconnected(id string, notFound error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := len(m.disconnected)
+	m.disconnected = slices.DeleteFunc(m.disconnected, func(d *disconnected) bool { return d.id == id })
+	if len(m.disconnected) == n {
+		return notFound
+	}
+	return nil
+}
+
+// adminErrorCode maps admin errors to an HTTP response code.
+func adminErrorCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNodeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrLastNode):
+		return http.StatusConflict
+	case errors.Is(err, errNodeValidation):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// requireAdminToken rejects requests which do not carry the configured bearer token. Every request is
+// rejected if no token is configured.
+func requireAdminToken(token string, h httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			respondWithError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+			return
+		}
+		h(w, r, p)
+	})
+}
+
+// adminEndpoints returns the node administration endpoints. They respond with 501 if the eth client
+// does not support runtime node management.
+func adminEndpoints(ethClient SimpleEthClient, cfg AdminConfig) []endPoint {
+	admin, _ := ethClient.(NodeAdmin)
+	handle := func(h func(admin NodeAdmin) httprouter.Handle) httprouter.Handle {
+		if admin == nil {
+			return requireAdminToken(cfg.Token, func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+				respondWithError(w, http.StatusNotImplemented, fmt.Errorf("eth client does not support node administration"))
+			})
+		}
+		return requireAdminToken(cfg.Token, h(admin))
+	}
+	return []endPoint{
+		{path: AdminV0NodesEndPnt, handler: handle(AdminNodes), methodType: http.MethodGet},
+		{path: AdminV0NodesEndPnt, handler: handle(AdminAddNode), methodType: http.MethodPost},
+		{path: adminV0NodeDrainEndPnt, handler: handle(AdminDrainNode), methodType: http.MethodPost},
+		{path: adminV0NodeEndPnt, handler: handle(AdminRemoveNode), methodType: http.MethodDelete},
+	}
+}
+
+// AdminNodes returns a handler listing the upstream nodes and their statistics.
+func AdminNodes(admin NodeAdmin) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if err := respondWithJSON(w, http.StatusOK, admin.Nodes()); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
+
+// AdminAddNode returns a handler which dials, validates and adds the node described in the request body.
+func AdminAddNode(admin NodeAdmin) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+		var cfg NodeConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid node config: %v", err))
+			return
+		}
+		if err := checkRuntimeNode(cfg); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid node config: %v", err))
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+
+		info, err := admin.AddNode(ctx, cfg)
+		if err != nil {
+			respondWithError(w, adminErrorCode(err), err)
+			return
+		}
+
+		if err := respondWithJSON(w, http.StatusCreated, info); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
+
+// checkRuntimeNode rejects the settings of a node added at runtime which are only trusted in the config
+// loaded at startup: environment variable references in the URL, secrets read from environment variables
+// or files and TLS certificate files. Otherwise an admin caller could have the proxy send server-side
+// secrets to a node of their choosing.
+func checkRuntimeNode(cfg NodeConfig) error {
+	if strings.Contains(cfg.URL, "$") {
+		return errors.New("environment variables cannot be referenced by nodes added at runtime")
+	}
+	if err := validateNodeURL(cfg.URL); err != nil {
+		return err
+	}
+	secrets := []string{cfg.Auth.Password, cfg.Auth.Bearer, cfg.Auth.JWTSecret}
+	for _, value := range cfg.Auth.Headers {
+		secrets = append(secrets, value)
+	}
+	for _, secret := range secrets {
+		if strings.HasPrefix(secret, envSecretPrefix) || strings.HasPrefix(secret, fileSecretPrefix) {
+			return errors.New("secrets cannot be read from environment variables or files for nodes added at runtime")
+		}
+	}
+	if cfg.TLS.CAFile != "" || cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" {
+		return errors.New("certificate files cannot be configured for nodes added at runtime")
+	}
+	return nil
+}
+
+// AdminDrainNode returns a handler which takes a node out of rotation, letting in-flight requests finish.
+func AdminDrainNode(admin NodeAdmin) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		info, err := admin.DrainNode(p.ByName(IDKey[1:]))
+		if err != nil {
+			respondWithError(w, adminErrorCode(err), err)
+			return
+		}
+		if err := respondWithJSON(w, http.StatusOK, info); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
+
+// AdminRemoveNode returns a handler which drains and removes a node.
+func AdminRemoveNode(admin NodeAdmin) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+
+		if err := admin.RemoveNode(ctx, p.ByName(IDKey[1:])); err != nil {
+			respondWithError(w, adminErrorCode(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
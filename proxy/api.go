@@ -2,13 +2,17 @@ package proxy
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/julienschmidt/httprouter"
 )
@@ -21,13 +25,24 @@ const (
 	AddressKey = ":address"
 	IDKey      = ":id"
 	DataKey    = ":data"
+	SlotKey    = ":slot"
 
 	EthV0BalancePrfx   = "/eth/v0/balance/"    // eth_getBalance proxy endpoint
+	EthV0NoncePrfx     = "/eth/v0/nonce/"      // eth_getTransactionCount proxy endpoint
+	EthV0StoragePrfx   = "/eth/v0/storage/"    // eth_getStorageAt proxy endpoint
 	EthV0TxPrfx        = "/eth/v0/tx/hash/"    // eth_getTransaction proxy endpoint
 	EthV0TxReceiptPrfx = "/eth/v0/tx/receipt/" // eth_getTransactionReceipt proxy endpoint
 	EthV0SendTxPrfx    = "/eth/v0/tx/new/"     // eth_sendRawTransaction proxy endpoint
+	EthV0TxTracePrfx   = "/eth/v0/tx/trace/"   // debug_traceTransaction proxy endpoint
+	EthV0TxPoolPrfx    = "/eth/v0/txpool/"     // txpool_contentFrom proxy endpoint
+
+	EthV0SendTxBodyEndPnt = "/eth/v0/tx/send"     // eth_sendRawTransaction proxy endpoint (tx supplied in request body)
+	EthV0SimulateEndPnt   = "/eth/v0/tx/simulate" // eth_call simulation of a signed transaction (tx supplied in request body)
+	EthV0FeeEndPnt        = "/eth/v0/fee"         // gas and blob fee proxy endpoint
 
 	timeout = 5 * time.Second
+
+	maxTxBodySize = 4 << 20 // large enough to carry a blob transaction with the maximum number of blobs
 )
 
 var (
@@ -35,11 +50,25 @@ var (
 
 	// V0
 	ethV0BalanceEndPnt   = EthV0BalancePrfx + AddressKey
+	ethV0NonceEndPnt     = EthV0NoncePrfx + AddressKey
+	ethV0StorageEndPnt   = EthV0StoragePrfx + AddressKey + "/" + SlotKey
 	ethV0TxEndPnt        = EthV0TxPrfx + IDKey
 	ethV0TxReceiptEndPnt = EthV0TxReceiptPrfx + IDKey
 	ethV0SendTxEndPnt    = EthV0SendTxPrfx + DataKey
+	ethV0TxTraceEndPnt   = EthV0TxTracePrfx + IDKey
+	ethV0TxPoolEndPnt    = EthV0TxPoolPrfx + AddressKey
 )
 
+// isHexHash reports whether s is a 32 byte hex string, with or without the 0x prefix. It validates
+// every hash path and query parameter.
+func isHexHash(s string) bool {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		s = s[2:]
+	}
+	_, err := hex.DecodeString(s)
+	return len(s) == 2*common.HashLength && err == nil
+}
+
 // StatusResponse contains status response fields.
 type StatusResponse struct {
 	Message string `json:"message,omitempty"`
@@ -57,16 +86,20 @@ func Status() httprouter.Handle {
 
 }
 
-// HealthResponse contains health probe response fields.
+// HealthResponse contains health probe response fields. Nodes reports the circuit
+// breaker state of each upstream node and Beacon that of each beacon node.
 type HealthResponse struct {
-	Version  string   `json:"version,omitempty"`
-	Service  string   `json:"service,omitempty"`
-	Failures []string `json:"failures"`
+	Version  string             `json:"version,omitempty"`
+	Service  string             `json:"service,omitempty"`
+	Failures []string           `json:"failures"`
+	Nodes    []NodeHealth       `json:"nodes,omitempty"`
+	Beacon   []BeaconNodeHealth `json:"beacon,omitempty"`
 }
 
 // Health pings the layer one clients. It ensures that the connected geth
-// execution clients are ready to accept incoming proxied requests.
-func Health(ethClient SimpleEthClient) httprouter.Handle {
+// execution clients, and the beacon nodes if beacon is not nil, are ready
+// to accept incoming proxied requests.
+func Health(ethClient SimpleEthClient, beacon *BeaconClient) httprouter.Handle {
 	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		health := &HealthResponse{
 			Service: ServiceName,
@@ -78,14 +111,33 @@ func Health(ethClient SimpleEthClient) httprouter.Handle {
 		// check clients
 		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 		defer cancelFunc()
-		if _, err := ethClient.BlockNumber(ctx); err != nil {
+		var err error
+		if checker, ok := ethClient.(chainTipChecker); ok {
+			err = checker.checkChainTips(ctx)
+		} else {
+			_, err = ethClient.BlockNumber(ctx)
+		}
+		if err != nil {
 			failureArray := strings.Split(err.Error(), "|")
 			trimmed := failureArray[0 : len(failureArray)-1]
 			failures = append(failures, trimmed...)
 		}
+		if beacon != nil {
+			if err := beacon.checkHealth(ctx); err != nil {
+				failureArray := strings.Split(err.Error(), "|")
+				failures = append(failures, failureArray[0:len(failureArray)-1]...)
+			}
+		}
 
 		health.Failures = failures
 
+		if reporter, ok := ethClient.(nodeHealthReporter); ok {
+			health.Nodes = reporter.NodeHealth()
+		}
+		if beacon != nil {
+			health.Beacon = beacon.NodeHealth()
+		}
+
 		if len(health.Failures) > 0 {
 			httpCode = http.StatusServiceUnavailable
 		}
@@ -96,13 +148,20 @@ func Health(ethClient SimpleEthClient) httprouter.Handle {
 	})
 }
 
-// BalanceResp contains balance value formatted as a string.
+// BalanceResp contains balance value formatted as a string. Verified is set if
+// the balance was checked against a state proof at the reported block and Quorum
+// is the number of upstream nodes which agreed on the balance at that block.
 type BalanceResponse struct {
-	Balance string `json:"balance"`
+	Balance  string `json:"balance"`
+	Verified bool   `json:"verified"`
+	Block    uint64 `json:"block,omitempty"`
+	Quorum   int    `json:"quorum,omitempty"`
 }
 
-// Balance handles the getBalance proxy endpoint.
-func Balance(ethClient SimpleEthClient) httprouter.Handle {
+// Balance handles the getBalance proxy endpoint. If quorumCfg is enabled unverified reads
+// are only answered if enough upstream nodes agree. Reads pinned to a block hash cannot be
+// combined with a block number, verified reads or quorum reads.
+func Balance(ethClient SimpleEthClient, proofCfg ProofConfig, quorumCfg QuorumConfig) httprouter.Handle {
 	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 
 		address := p.ByName(AddressKey[1:])
@@ -112,15 +171,67 @@ func Balance(ethClient SimpleEthClient) httprouter.Handle {
 			return
 		}
 
+		verified, number, err := readOptions(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		hash, pinned, err := readBlockHash(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		if pinned && (verified || number != nil || quorumCfg.enabled()) {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("%s cannot be combined with %s, verified or quorum reads", BlockHashKey, BlockKey))
+			return
+		}
+
 		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 		defer cancelFunc()
-		b, err := ethClient.BalanceAt(ctx, common.HexToAddress(address), nil)
+		ctx, err = withMinBlockParam(ctx, r)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		if err := respondWithJSON(w, http.StatusOK, &BalanceResponse{Balance: b.String()}); err != nil {
+		resp := &BalanceResponse{}
+		if pinned {
+			c, err := blockHashClient(ethClient)
+			var b *big.Int
+			if err == nil {
+				b, err = c.BalanceAtHash(ctx, common.HexToAddress(address), hash)
+			}
+			if err != nil {
+				respondWithError(w, pinnedReadErrorCode(err), fmt.Errorf("eth client error: %v", err))
+				return
+			}
+			resp.Balance = b.String()
+		} else if verified {
+			account, err := readVerifiedAccount(ctx, ethClient, proofCfg, common.HexToAddress(address), nil, number)
+			if err != nil {
+				respondWithError(w, readErrorCode(err), fmt.Errorf("verified read error: %v", err))
+				return
+			}
+			resp.Balance, resp.Verified, resp.Block = account.balance.String(), true, account.block
+		} else if quorumCfg.enabled() {
+			b, block, err := quorumRead(ctx, ethClient, quorumCfg, "balance", number, func(ctx context.Context, c SimpleEthClient, n *big.Int) (*big.Int, error) {
+				return c.BalanceAt(ctx, common.HexToAddress(address), n)
+			})
+			if err != nil {
+				respondWithError(w, readErrorCode(err), fmt.Errorf("quorum read error: %v", err))
+				return
+			}
+			resp.Balance, resp.Block, resp.Quorum = b.String(), block, quorumCfg.Agree
+		} else {
+			b, err := ethClient.BalanceAt(ctx, common.HexToAddress(address), number)
+			if err != nil {
+				respondWithError(w, ethClientErrorCode(err), fmt.Errorf("eth client error: %v", err))
+				return
+			}
+			resp.Balance = b.String()
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
 			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
 		}
 
@@ -128,11 +239,158 @@ func Balance(ethClient SimpleEthClient) httprouter.Handle {
 
 }
 
+// NonceResponse contains the account nonce. Verified is set if the nonce was
+// checked against a state proof at the reported block and Quorum is the number
+// of upstream nodes which agreed on the nonce at that block.
+type NonceResponse struct {
+	Nonce    uint64 `json:"nonce"`
+	Verified bool   `json:"verified"`
+	Block    uint64 `json:"block,omitempty"`
+	Quorum   int    `json:"quorum,omitempty"`
+}
+
+// Nonce handles the getTransactionCount proxy endpoint. If quorumCfg is enabled unverified reads
+// are only answered if enough upstream nodes agree.
+func Nonce(ethClient SimpleEthClient, proofCfg ProofConfig, quorumCfg QuorumConfig) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+
+		address := p.ByName(AddressKey[1:])
+
+		if !common.IsHexAddress(address) {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid address format"))
+			return
+		}
+
+		verified, number, err := readOptions(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+		ctx, err = withMinBlockParam(ctx, r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		resp := &NonceResponse{}
+		if verified {
+			account, err := readVerifiedAccount(ctx, ethClient, proofCfg, common.HexToAddress(address), nil, number)
+			if err != nil {
+				respondWithError(w, readErrorCode(err), fmt.Errorf("verified read error: %v", err))
+				return
+			}
+			resp.Nonce, resp.Verified, resp.Block = account.nonce, true, account.block
+		} else if quorumCfg.enabled() {
+			n, block, err := quorumRead(ctx, ethClient, quorumCfg, "nonce", number, func(ctx context.Context, c SimpleEthClient, n *big.Int) (uint64, error) {
+				return c.NonceAt(ctx, common.HexToAddress(address), n)
+			})
+			if err != nil {
+				respondWithError(w, readErrorCode(err), fmt.Errorf("quorum read error: %v", err))
+				return
+			}
+			resp.Nonce, resp.Block, resp.Quorum = n, block, quorumCfg.Agree
+		} else {
+			n, err := ethClient.NonceAt(ctx, common.HexToAddress(address), number)
+			if err != nil {
+				respondWithError(w, ethClientErrorCode(err), fmt.Errorf("eth client error: %v", err))
+				return
+			}
+			resp.Nonce = n
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
+
+// StorageResponse contains the 32 byte value of a contract storage slot. Verified is
+// set if the value was checked against a state proof at the reported block and Quorum
+// is the number of upstream nodes which agreed on the value at that block.
+type StorageResponse struct {
+	Value    string `json:"value"`
+	Verified bool   `json:"verified"`
+	Block    uint64 `json:"block,omitempty"`
+	Quorum   int    `json:"quorum,omitempty"`
+}
+
+// Storage handles the getStorageAt proxy endpoint. If quorumCfg is enabled unverified reads
+// are only answered if enough upstream nodes agree.
+func Storage(ethClient SimpleEthClient, proofCfg ProofConfig, quorumCfg QuorumConfig) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+
+		address := p.ByName(AddressKey[1:])
+
+		if !common.IsHexAddress(address) {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid address format"))
+			return
+		}
+
+		slotHex := p.ByName(SlotKey[1:])
+		slotBytes, err := hexutil.Decode(slotHex)
+		if err != nil || len(slotBytes) > common.HashLength {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid storage slot"))
+			return
+		}
+		slot := common.BytesToHash(slotBytes)
+
+		verified, number, err := readOptions(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+		ctx, err = withMinBlockParam(ctx, r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		resp := &StorageResponse{}
+		if verified {
+			account, err := readVerifiedAccount(ctx, ethClient, proofCfg, common.HexToAddress(address), []common.Hash{slot}, number)
+			if err != nil {
+				respondWithError(w, readErrorCode(err), fmt.Errorf("verified read error: %v", err))
+				return
+			}
+			resp.Value, resp.Verified, resp.Block = account.storage[0].Hex(), true, account.block
+		} else if quorumCfg.enabled() {
+			v, block, err := quorumRead(ctx, ethClient, quorumCfg, "storage", number, func(ctx context.Context, c SimpleEthClient, n *big.Int) (common.Hash, error) {
+				v, err := c.StorageAt(ctx, common.HexToAddress(address), slot, n)
+				return common.BytesToHash(v), err
+			})
+			if err != nil {
+				respondWithError(w, readErrorCode(err), fmt.Errorf("quorum read error: %v", err))
+				return
+			}
+			resp.Value, resp.Block, resp.Quorum = v.Hex(), block, quorumCfg.Agree
+		} else {
+			v, err := ethClient.StorageAt(ctx, common.HexToAddress(address), slot, number)
+			if err != nil {
+				respondWithError(w, ethClientErrorCode(err), fmt.Errorf("eth client error: %v", err))
+				return
+			}
+			resp.Value = common.BytesToHash(v).Hex()
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
+
 // TxResponse contains ethereum transaction data and a pending flag.
 type TxResponse struct {
-	Tx        *types.Transaction `json:"tx,omitempty"`
-	Txid      string             `json:"txid,omitempty"`
-	IsPending bool               `json:"is_pending,omitempty"`
+	Tx         *types.Transaction  `json:"tx,omitempty"`
+	Txid       string              `json:"txid,omitempty"`
+	IsPending  bool                `json:"is_pending,omitempty"`
+	Simulation *SimulationResponse `json:"simulation,omitempty"`
+	Broadcast  []BroadcastResult   `json:"broadcast,omitempty"`
 }
 
 // Tx returns a handler for the eth_getTransaction proxy endpoint.
@@ -141,18 +399,22 @@ func Tx(ethClient SimpleEthClient) httprouter.Handle {
 
 		txid := p.ByName(IDKey[1:])
 
-		txHash := common.HexToHash(txid)
-
-		if len(txHash.Bytes()) != 32 {
+		if !isHexHash(txid) {
 			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid hash"))
 			return
 		}
+		txHash := common.HexToHash(txid)
 
 		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 		defer cancelFunc()
+		ctx, err := withMinBlockParam(ctx, r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
 		tx, pending, err := ethClient.TransactionByHash(ctx, txHash)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+			respondWithError(w, ethClientErrorCode(err), fmt.Errorf("eth client error: %v", err))
 			return
 		}
 
@@ -163,24 +425,57 @@ func Tx(ethClient SimpleEthClient) httprouter.Handle {
 	})
 }
 
-// TxReceipt returns a handler for the eth_getTransactionReceipt proxy endpoint.
+// TxReceiptResponse is a transaction receipt flagged as reorged if the block containing it has been
+// orphaned by a chain reorganization. Reorged receipts may vanish or move to another block.
+type TxReceiptResponse struct {
+	*types.Receipt
+	Reorged bool
+}
+
+// MarshalJSON encodes the receipt fields followed by the reorged flag.
+func (r TxReceiptResponse) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(r.Receipt)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(b[:len(b)-1], `,"reorged":%t}`, r.Reorged), nil
+}
+
+// UnmarshalJSON decodes the receipt fields and the reorged flag.
+func (r *TxReceiptResponse) UnmarshalJSON(b []byte) error {
+	var flag struct {
+		Reorged bool `json:"reorged"`
+	}
+	if err := json.Unmarshal(b, &flag); err != nil {
+		return err
+	}
+	r.Receipt, r.Reorged = new(types.Receipt), flag.Reorged
+	return r.Receipt.UnmarshalJSON(b)
+}
+
+// TxReceipt returns a handler for the eth_getTransactionReceipt proxy endpoint. Receipts for
+// transactions in blocks orphaned by a reorg are flagged if the client tracks reorgs.
 func TxReceipt(ethClient SimpleEthClient) httprouter.Handle {
 	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 
 		txid := p.ByName(IDKey[1:])
 
-		txHash := common.HexToHash(txid)
-
-		if len(txHash.Bytes()) != 32 {
+		if !isHexHash(txid) {
 			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid hash"))
 			return
 		}
+		txHash := common.HexToHash(txid)
 
 		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 		defer cancelFunc()
+		ctx, err := withMinBlockParam(ctx, r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
 		tx, err := ethClient.TransactionReceipt(ctx, txHash)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+			respondWithError(w, ethClientErrorCode(err), fmt.Errorf("eth client error: %v", err))
 			return
 		}
 
@@ -189,15 +484,22 @@ func TxReceipt(ethClient SimpleEthClient) httprouter.Handle {
 			return
 		}
 
-		if err := respondWithJSON(w, http.StatusOK, &tx); err != nil {
+		resp := TxReceiptResponse{Receipt: tx}
+		if reporter, ok := ethClient.(reorgReporter); ok {
+			resp.Reorged = reporter.Reorged(tx)
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, &resp); err != nil {
 			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
 		}
 
 	})
 }
 
-// SendTx returns a handler for the eth_sendRawTransaction proxy endpoint.
-func SendTx(ethClient SimpleEthClient) httprouter.Handle {
+// SendTx returns a handler for the eth_sendRawTransaction proxy endpoint. If requireSimulation
+// is set every transaction is simulated before broadcast, otherwise callers may opt in with
+// the simulate query parameter. Broadcast transactions are recorded in the journal.
+func SendTx(ethClient SimpleEthClient, requireSimulation bool, journal *TxJournal) httprouter.Handle {
 	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 
 		txHex := p.ByName(DataKey[1:])
@@ -208,24 +510,117 @@ func SendTx(ethClient SimpleEthClient) httprouter.Handle {
 			return
 		}
 
-		tx := &types.Transaction{}
+		sendRawTx(w, ethClient, journal, txBytes, requireSimulation || wantsSimulation(r))
+	})
+}
 
-		if err := tx.UnmarshalBinary(txBytes); err != nil {
-			respondWithError(w, http.StatusBadRequest, fmt.Errorf("could not unmarshal tx JSON: %v", err))
+// SendTxRequest carries a signed transaction in its binary encoding. Blob transactions
+// must be supplied in network form (including the blob sidecar).
+type SendTxRequest struct {
+	Tx hexutil.Bytes `json:"tx"`
+}
+
+// SendTxBody returns a handler for the eth_sendRawTransaction proxy endpoint accepting the
+// transaction in the request body. Unlike SendTx it can carry blob transaction payloads.
+func SendTxBody(ethClient SimpleEthClient, requireSimulation bool, journal *TxJournal) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+		var req SendTxRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTxBodySize)).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid tx data: %v", err))
 			return
 		}
 
+		sendRawTx(w, ethClient, journal, req.Tx, requireSimulation || wantsSimulation(r))
+	})
+}
+
+// sendRawTx decodes and validates a binary encoded transaction before broadcasting it. If simulate
+// is set, transactions which fail simulation are rejected and never broadcast.
+func sendRawTx(w http.ResponseWriter, ethClient SimpleEthClient, journal *TxJournal, txBytes []byte, simulate bool) {
+
+	tx := &types.Transaction{}
+
+	if err := tx.UnmarshalBinary(txBytes); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Errorf("could not unmarshal tx JSON: %v", err))
+		return
+	}
+
+	if err := validateBlobTx(tx); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid blob tx: %v", err))
+		return
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+
+	var sim *SimulationResponse
+	if simulate {
+		if sim = simulateOrReject(ctx, w, ethClient, tx); sim == nil {
+			return
+		}
+	}
+
+	var broadcast []BroadcastResult
+	var err error
+	if broadcaster, ok := ethClient.(txBroadcaster); ok {
+		broadcast, err = broadcaster.BroadcastTransaction(ctx, tx)
+	} else {
+		err = ethClient.SendTransaction(ctx, tx)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+		return
+	}
+	journal.record(tx)
+
+	if err := respondWithJSON(w, http.StatusOK, &TxResponse{Txid: tx.Hash().Hex(), Simulation: sim, Broadcast: broadcast}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+	}
+}
+
+// FeeResponse contains the current fee market values formatted as wei strings.
+type FeeResponse struct {
+	GasPrice    string `json:"gas_price"`
+	GasTipCap   string `json:"gas_tip_cap"`
+	BaseFee     string `json:"base_fee,omitempty"`
+	BlobBaseFee string `json:"blob_base_fee,omitempty"`
+}
+
+// Fee returns a handler reporting the suggested gas price and priority fee together
+// with the base fee and blob base fee of the latest block.
+func Fee(ethClient SimpleEthClient) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
 		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 		defer cancelFunc()
 
-		if err := ethClient.SendTransaction(ctx, tx); err != nil {
+		gasPrice, err := ethClient.SuggestGasPrice(ctx)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+			return
+		}
+		tip, err := ethClient.SuggestGasTipCap(ctx)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+			return
+		}
+		head, err := ethClient.HeaderByNumber(ctx, nil)
+		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
 			return
 		}
 
-		if err := respondWithJSON(w, http.StatusOK, &TxResponse{Txid: tx.Hash().Hex()}); err != nil {
-			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		resp := &FeeResponse{GasPrice: gasPrice.String(), GasTipCap: tip.String()}
+		if head.BaseFee != nil {
+			resp.BaseFee = head.BaseFee.String()
+		}
+		if head.ExcessBlobGas != nil {
+			resp.BlobBaseFee = eip4844.CalcBlobFee(*head.ExcessBlobGas).String()
 		}
 
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
 	})
 }
@@ -0,0 +1,213 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	envSecretPrefix  = "env:"  // secret read from the named environment variable
+	fileSecretPrefix = "file:" // secret read from the named file
+
+	redacted          = "xxxxx" // as in url.URL.Redacted
+	minRedactedLength = 16      // URL path segments at least this long are redacted
+)
+
+// resolveSecret returns the secret referred to by value: the contents of an environment variable for
+// env:NAME, the trimmed contents of a file for file:PATH, or otherwise the value itself.
+func resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envSecretPrefix):
+		name := value[len(envSecretPrefix):]
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, fileSecretPrefix):
+		b, err := os.ReadFile(filepath.Clean(value[len(fileSecretPrefix):]))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	default:
+		return value, nil
+	}
+}
+
+// expandURL replaces ${NAME} references in url with the values of the named environment variables so
+// that provider keys need not be written in the config file.
+func expandURL(url string) (string, error) {
+	var missing []string
+	expanded := os.Expand(url, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variables %s are not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// clientOptions returns the rpc client options which authenticate requests as configured. Secrets are
+// resolved each time so that rotated secret files are picked up when a node is redialled.
+func (c AuthConfig) clientOptions() ([]rpc.ClientOption, error) {
+	headers, auth, err := c.requestAuth()
+	if err != nil {
+		return nil, err
+	}
+	var opts []rpc.ClientOption
+	if auth != nil {
+		opts = append(opts, rpc.WithHTTPAuth(auth))
+	}
+	if len(headers) > 0 {
+		opts = append(opts, rpc.WithHeaders(headers))
+	}
+	return opts, nil
+}
+
+// requestAuth returns the headers to be sent with every request and, if a JWT secret is configured,
+// the authenticator which adds a fresh token to each request.
+func (c AuthConfig) requestAuth() (http.Header, rpc.HTTPAuth, error) {
+	schemes := 0
+	for _, set := range []bool{c.Username != "" || c.Password != "", c.Bearer != "", c.JWTSecret != ""} {
+		if set {
+			schemes++
+		}
+	}
+	if schemes > 1 {
+		return nil, nil, errors.New("only one of basic auth, a bearer token and a jwt secret may be configured")
+	}
+
+	headers := make(http.Header)
+	for name, value := range c.Headers {
+		secret, err := resolveSecret(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("header %s: %v", name, err)
+		}
+		headers.Set(name, secret)
+	}
+	var auth rpc.HTTPAuth
+	switch {
+	case c.Username != "" || c.Password != "":
+		password, err := resolveSecret(c.Password)
+		if err != nil {
+			return nil, nil, fmt.Errorf("password: %v", err)
+		}
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.Username+":"+password)))
+	case c.Bearer != "":
+		token, err := resolveSecret(c.Bearer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bearer token: %v", err)
+		}
+		headers.Set("Authorization", "Bearer "+token)
+	case c.JWTSecret != "":
+		secret, err := resolveSecret(c.JWTSecret)
+		if err != nil {
+			return nil, nil, fmt.Errorf("jwt secret: %v", err)
+		}
+		b, err := hexSecret(secret)
+		if err != nil {
+			return nil, nil, fmt.Errorf("jwt secret: %v", err)
+		}
+		auth = node.NewJWTAuth(b)
+	}
+	return headers, auth, nil
+}
+
+// hexSecret decodes a hex-encoded 32 byte secret, with or without a 0x prefix.
+func hexSecret(secret string) ([32]byte, error) {
+	var b [32]byte
+	decoded := common.FromHex(secret)
+	if len(decoded) != len(b) {
+		return b, fmt.Errorf("expected %d hex-encoded bytes, got %d", len(b), len(decoded))
+	}
+	copy(b[:], decoded)
+	return b, nil
+}
+
+// RedactURL returns url with anything which may be a secret replaced: user credentials, path segments
+// as long as a provider API key and query values. IPC socket paths are returned unchanged, while URLs
+// with an unknown scheme are redacted like HTTP URLs.
+func RedactURL(url string) string {
+	if TransportOf(url) == TransportIPC {
+		return url
+	}
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return redacted
+	}
+	var b strings.Builder
+	if u.Scheme != "" {
+		b.WriteString(u.Scheme + "://")
+	}
+	if u.User != nil {
+		b.WriteString(redacted + "@")
+	}
+	b.WriteString(u.Host)
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if len(segment) >= minRedactedLength {
+			segments[i] = redacted
+		}
+	}
+	b.WriteString(strings.Join(segments, "/"))
+	sep := "?"
+	for _, name := range slices.Sorted(maps.Keys(u.Query())) {
+		b.WriteString(sep + name + "=" + redacted)
+		sep = "&"
+	}
+	return b.String()
+}
+
+// redactError returns err with every occurrence of url in its message redacted.
+func redactError(err error, url string) error {
+	if err == nil || url == "" || !strings.Contains(err.Error(), url) {
+		return err
+	}
+	return &redactedError{err: err, msg: strings.ReplaceAll(err.Error(), url, RedactURL(url))}
+}
+
+// redactedError is an error whose message has had secrets removed.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// urlTransport sends every request to url. An rpc client dialled with the redacted URL and an HTTP client
+// using this transport reaches the node while errors reported by the HTTP client, which quote the request
+// URL, cannot contain the secrets in the real URL.
+type urlTransport struct {
+	url  *neturl.URL
+	base http.RoundTripper
+}
+
+func (t *urlTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := *t.url
+	req = req.Clone(req.Context())
+	req.URL, req.Host = &u, u.Host
+	if u.User != nil && req.Header.Get("Authorization") == "" {
+		password, _ := u.User.Password()
+		req.SetBasicAuth(u.User.Username(), password)
+	}
+	return t.base.RoundTrip(req)
+}
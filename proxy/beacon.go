@@ -0,0 +1,382 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	beaconHealthPath  = "/eth/v1/node/health"  // 200 if the node is ready, 206 if it is syncing
+	beaconSyncingPath = "/eth/v1/node/syncing" // head slot and sync distance of the node
+
+	maxBeaconErrorSize = 64 << 10 // size of a failed beacon node response kept for the error message
+)
+
+// errNoBeaconNodes is returned when no beacon node is in rotation.
+var errNoBeaconNodes = errors.New("no beacon nodes available")
+
+var _ healthChecker = (*BeaconClient)(nil)
+
+// BeaconClient proxies the beacon node API of a set of consensus clients. Its nodes are held by a
+// multiNodeClient which never calls their (absent) execution client, so that beacon requests share
+// the selection strategies, retry policy, circuit breakers and request budgets of execution nodes.
+type BeaconClient struct {
+	nodes           *multiNodeClient
+	upstreams       map[string]*beaconUpstream // by node id, fixed once the client is created
+	maxSyncDistance uint64
+}
+
+// beaconUpstream is the HTTP endpoint of a beacon node.
+type beaconUpstream struct {
+	url    *neturl.URL // expanded, may hold credentials
+	client *http.Client
+	header http.Header  // authentication headers sent with every request
+	auth   rpc.HTTPAuth // adds a fresh JWT to each request, nil unless a JWT secret is configured
+	probe  atomic.Pointer[beaconProbe]
+}
+
+// beaconProbe is the outcome of a health check of a beacon node.
+type beaconProbe struct {
+	headSlot     uint64
+	syncDistance uint64
+	syncing      bool
+	elOffline    bool
+	latency      time.Duration
+	err          error
+}
+
+// BeaconNodeHealth contains the circuit breaker state and most recent health check result of a beacon node.
+type BeaconNodeHealth struct {
+	ID           string       `json:"id"`
+	State        BreakerState `json:"state"`
+	HeadSlot     uint64       `json:"head_slot,omitempty"`
+	SyncDistance uint64       `json:"sync_distance,omitempty"`
+	Syncing      bool         `json:"syncing,omitempty"`
+	LatencyMs    int64        `json:"latency_ms,omitempty"`
+	Error        string       `json:"error,omitempty"`
+}
+
+// NewBeaconClient returns a client for the configured beacon nodes, which are identified as beacon-0,
+// beacon-1 and so on in metrics and health reports. Nodes are not contacted until the first request
+// or health check, so an error is only returned for invalid node settings.
+func NewBeaconClient(cfg BeaconConfig) (*BeaconClient, error) {
+	if len(cfg.Nodes) == 0 {
+		return nil, errors.New("no beacon nodes configured")
+	}
+	sel, err := newSelector(cfg.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	b := &BeaconClient{upstreams: make(map[string]*beaconUpstream), maxSyncDistance: cfg.MaxSyncDistance}
+	var nodes []*item
+	for i, nodeCfg := range cfg.Nodes {
+		id := fmt.Sprintf("beacon-%d", i)
+		upstream, err := newBeaconUpstream(nodeCfg)
+		if err != nil {
+			return nil, fmt.Errorf("beacon node %s: %w", RedactURL(nodeCfg.URL), err)
+		}
+		b.upstreams[id] = upstream
+		nodes = append(nodes, newItem(id, nodeCfg, nil, HealthCheckConfig{}))
+	}
+	b.nodes = &multiNodeClient{}
+	b.nodes.state.Store(&routingState{nodes: nodes, selector: sel})
+	return b, nil
+}
+
+// newBeaconUpstream prepares the HTTP client for a beacon node. Node URLs may refer to environment
+// variables and carry basic auth credentials like execution node URLs.
+func newBeaconUpstream(cfg NodeConfig) (*beaconUpstream, error) {
+	url, err := expandURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if TransportOf(url) != TransportHTTP {
+		return nil, errors.New("beacon nodes must be reached over HTTP(S)")
+	}
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, redactError(err, url)
+	}
+	header, auth, err := cfg.Auth.requestAuth()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := cfg.TLS.httpTransport(u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	// Bound the wait for a node to answer without limiting how long a response (such as an event
+	// stream) may take to read.
+	transport.ResponseHeaderTimeout = timeout
+	return &beaconUpstream{url: u, client: &http.Client{Transport: transport}, header: header, auth: auth}, nil
+}
+
+// SetRetryConfig sets the retry policy applied to beacon requests.
+func (b *BeaconClient) SetRetryConfig(cfg RetryConfig) error {
+	return b.nodes.SetRetryConfig(cfg)
+}
+
+// do sends a request for the beacon API path and query to the node. Rate limiting and server error
+// responses are returned as an rpc.HTTPError, so that they are retried and counted against the node
+// like failed JSON-RPC requests, while other responses are returned with their body still to be read.
+// Transport errors name the node by its redacted URL.
+func (u *beaconUpstream) do(ctx context.Context, method, path, query string, body []byte, header http.Header) (*http.Response, error) {
+	target := *u.url
+	target.User = nil // credentials are added as a header
+	target.Path, target.RawPath = strings.TrimSuffix(target.Path, "/")+path, ""
+	if query != "" {
+		if target.RawQuery != "" {
+			query = target.RawQuery + "&" + query
+		}
+		target.RawQuery = query
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid beacon request: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	for name, values := range u.header {
+		req.Header[name] = values
+	}
+	if u.url.User != nil && req.Header.Get("Authorization") == "" {
+		password, _ := u.url.User.Password()
+		req.SetBasicAuth(u.url.User.Username(), password)
+	}
+	if u.auth != nil {
+		if err := u.auth(req.Header); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			return nil, &neturl.Error{Op: urlErr.Op, URL: RedactURL(u.url.String()) + path, Err: urlErr.Err}
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxBeaconErrorSize))
+		return nil, rpc.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: msg}
+	}
+	return resp, nil
+}
+
+// forward sends a request to the beacon nodes in rotation, moving on to the next node under the retry
+// policy if a node cannot be reached, is rate limiting or answers with a server error. The first other
+// response is returned with its body still to be read. Unlike callEach no per-attempt timeout is
+// applied, since it would cancel the request while the response body is being read.
+func (b *BeaconClient) forward(ctx context.Context, method, path, query string, body []byte, header http.Header) (*http.Response, error) {
+	return retryEach(ctx, b.nodes, b.nodes.rotation(nil), errNoBeaconNodes, false, func(ctx context.Context, node *item) (*http.Response, error) {
+		return b.upstreams[node.id].do(ctx, method, path, query, body, header)
+	})
+}
+
+// getData reads the data field of the JSON response to a beacon API GET request into out.
+func (b *BeaconClient) getData(ctx context.Context, path, query string, out any) error {
+	resp, err := b.forward(ctx, http.MethodGet, path, query, nil, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return err
+	}
+	return decodeBeaconData(resp, out)
+}
+
+// decodeBeaconData reads the data field of a JSON beacon API response into out, closing the response
+// body. Responses other than 200 OK are returned as an rpc.HTTPError.
+func decodeBeaconData(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxBeaconErrorSize))
+		return rpc.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: msg}
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("invalid beacon node response: %v", err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("invalid beacon node response: %v", err)
+	}
+	return nil
+}
+
+// StartHealthChecks applies the breaker thresholds in cfg and starts checking the health of every beacon
+// node at the configured interval. Checking is disabled if the interval is zero.
+func (b *BeaconClient) StartHealthChecks(cfg HealthCheckConfig, l *logrus.Entry) {
+	cfg = cfg.withDefaults()
+	m := b.nodes
+	for _, node := range m.snapshot() {
+		node.breaker.configure(cfg)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger, m.healthCfg = l, cfg
+	if m.prober != nil || cfg.Interval <= 0 || cfg.Disabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.prober = &prober{cancel: cancel}
+	m.prober.wg.Add(1)
+	go func(p *prober) {
+		defer p.wg.Done()
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for {
+			b.probeNodes(ctx, cfg)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}(m.prober)
+}
+
+// StopHealthChecks stops the health checks and waits for any check in progress to return.
+func (b *BeaconClient) StopHealthChecks() {
+	b.nodes.StopHealthChecks()
+}
+
+// probeNodes checks the health, sync distance and latency of every beacon node, updating the node
+// circuit breakers and latency averages.
+func (b *BeaconClient) probeNodes(parent context.Context, cfg HealthCheckConfig) {
+	ctx, cancelFunc := context.WithTimeout(parent, timeout)
+	defer cancelFunc()
+
+	nodes := b.nodes.snapshot()
+	results := b.checkNodes(ctx, nodes, cfg)
+	if parent.Err() != nil {
+		return // health checks were stopped while probing
+	}
+	for i, node := range nodes {
+		res := results[i]
+		b.upstreams[node.id].probe.Store(res)
+		if res.err == nil {
+			node.head.Store(res.headSlot)
+			nodeLatencyGauge.WithLabelValues(node.id).Set(node.stats.observe(res.latency).Seconds())
+		}
+		if res.err != nil && node.breaker.State() == BreakerOpen {
+			continue // wait for the cooldown before counting further failures
+		}
+		b.nodes.report(node, res.err)
+	}
+}
+
+// checkNodes checks the supplied nodes concurrently, returning the results in node order. Nodes which
+// are not ready, further than the maximum sync distance behind the chain head, whose execution client
+// is offline or which are slow to answer fail the check.
+func (b *BeaconClient) checkNodes(ctx context.Context, nodes []*item, cfg HealthCheckConfig) []*beaconProbe {
+	results := make([]*beaconProbe, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(index int, node *item) {
+			defer wg.Done()
+			node.budget.record(2)
+			res := b.upstreams[node.id].check(ctx)
+			switch {
+			case res.err != nil:
+			case res.syncDistance > b.maxSyncDistance:
+				res.err = fmt.Errorf("node is %d slots behind the chain head", res.syncDistance)
+			case res.elOffline:
+				res.err = errors.New("execution client of the node is offline")
+			case res.latency > cfg.MaxLatency:
+				res.err = fmt.Errorf("probe latency %v exceeds %v", res.latency.Round(time.Millisecond), cfg.MaxLatency)
+			}
+			results[index] = res
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
+
+// check reads the health and sync status of the node.
+func (u *beaconUpstream) check(ctx context.Context) *beaconProbe {
+	res := &beaconProbe{}
+	start := time.Now()
+	resp, err := u.do(ctx, http.MethodGet, beaconHealthPath, "", nil, nil)
+	res.latency = time.Since(start)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusPartialContent:
+		res.syncing = true
+	default:
+		res.err = fmt.Errorf("node health status %s", resp.Status)
+		return res
+	}
+
+	var status struct {
+		HeadSlot     uint64 `json:"head_slot,string"`
+		SyncDistance uint64 `json:"sync_distance,string"`
+		ELOffline    bool   `json:"el_offline"`
+	}
+	resp, err = u.do(ctx, http.MethodGet, beaconSyncingPath, "", nil, http.Header{"Accept": {"application/json"}})
+	if err == nil {
+		err = decodeBeaconData(resp, &status)
+	}
+	res.headSlot, res.syncDistance, res.elOffline, res.err = status.HeadSlot, status.SyncDistance, status.ELOffline, err
+	return res
+}
+
+// checkHealth checks every beacon node in rotation for the readiness probe. Failures are returned as a
+// single error with each failure terminated by '|'.
+func (b *BeaconClient) checkHealth(ctx context.Context) error {
+	m := b.nodes
+	m.mu.Lock()
+	cfg := m.healthCfg.withDefaults()
+	m.mu.Unlock()
+	nodes := m.rotation(nil)
+	if len(nodes) == 0 {
+		return errors.New(errNoBeaconNodes.Error() + "|")
+	}
+	var errStr string
+	for i, res := range b.checkNodes(ctx, nodes, cfg) {
+		if res.err != nil {
+			errStr += fmt.Sprintf("node %s err: %s|", nodes[i].id, res.err.Error())
+		}
+	}
+	if errStr != "" {
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// NodeHealth returns the breaker state and latest health check result of every beacon node.
+func (b *BeaconClient) NodeHealth() []BeaconNodeHealth {
+	nodes := b.nodes.snapshot()
+	health := make([]BeaconNodeHealth, len(nodes))
+	for i, node := range nodes {
+		health[i] = BeaconNodeHealth{ID: node.id, State: node.breaker.State()}
+		if res := b.upstreams[node.id].probe.Load(); res != nil {
+			health[i].HeadSlot, health[i].SyncDistance, health[i].Syncing = res.headSlot, res.syncDistance, res.syncing
+			health[i].LatencyMs = res.latency.Milliseconds()
+			if res.err != nil {
+				health[i].Error = res.err.Error()
+			}
+		}
+	}
+	return health
+}
@@ -0,0 +1,251 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	BeaconPrfx = "/beacon/" // beacon node API passthrough, e.g. /beacon/eth/v1/node/version
+
+	EthV0BeaconFinalityEndPnt = "/eth/v0/beacon/finality"  // finality checkpoints of the beacon chain
+	EthV0BeaconBalancesPrfx   = "/eth/v0/beacon/balances/" // balances of comma-separated validator indices or public keys
+
+	BeaconPathKey = "*path"
+	ValidatorsKey = ":validators"
+	StateKey      = "state" // query parameter selecting the beacon state (default head)
+
+	defaultBeaconState = "head"
+	maxBeaconBodySize  = 16 << 20 // large enough to carry a block with the maximum number of blobs
+	maxValidators      = 100      // validators per balance request
+)
+
+var (
+	beaconEndPnt              = BeaconPrfx + BeaconPathKey
+	ethV0BeaconBalancesEndPnt = EthV0BeaconBalancesPrfx + ValidatorsKey
+
+	stateIDPattern   = regexp.MustCompile(`^(head|genesis|finalized|justified|[0-9]+|0x[0-9a-fA-F]{64})$`)
+	validatorPattern = regexp.MustCompile(`^([0-9]+|0x[0-9a-fA-F]{96})$`)
+)
+
+// beaconEndpoints returns the beacon API passthrough and convenience endpoints.
+func beaconEndpoints(beacon *BeaconClient) []endPoint {
+	return []endPoint{
+		{
+			path:       beaconEndPnt,
+			handler:    BeaconProxy(beacon),
+			methodType: http.MethodGet,
+		},
+		{
+			path:       beaconEndPnt,
+			handler:    BeaconProxy(beacon),
+			methodType: http.MethodPost,
+		},
+		{
+			path:       EthV0BeaconFinalityEndPnt,
+			handler:    BeaconFinality(beacon),
+			methodType: http.MethodGet,
+		},
+		{
+			path:       ethV0BeaconBalancesEndPnt,
+			handler:    BeaconBalances(beacon),
+			methodType: http.MethodGet,
+		},
+	}
+}
+
+// beaconErrorCode returns the HTTP status code reported for a failed beacon request: the status of a
+// beacon node which answered with an error, or otherwise bad gateway.
+func beaconErrorCode(err error) int {
+	var httpErr rpc.HTTPError
+	switch {
+	case errors.Is(err, errNoBeaconNodes):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &httpErr) && httpErr.StatusCode < http.StatusInternalServerError:
+		return httpErr.StatusCode
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// BeaconProxy forwards requests under /beacon/ to the beacon nodes, e.g. /beacon/eth/v1/node/version to
+// /eth/v1/node/version. The response of the first node to answer is streamed back with its status code,
+// content type and Eth-* headers, so that SSZ responses and event streams are passed through. If every
+// node fails the last node error response is returned.
+func BeaconProxy(beacon *BeaconClient) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBeaconBodySize))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+		header := make(http.Header)
+		for name, values := range r.Header {
+			if forwardedHeader(name) {
+				header[name] = values
+			}
+		}
+
+		resp, err := beacon.forward(r.Context(), r.Method, p.ByName(BeaconPathKey[1:]), r.URL.RawQuery, body, header)
+		var httpErr rpc.HTTPError
+		if errors.As(err, &httpErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(httpErr.StatusCode)
+			_, _ = w.Write(httpErr.Body)
+			return
+		}
+		if err != nil {
+			respondWithError(w, beaconErrorCode(err), fmt.Errorf("beacon client error: %v", err))
+			return
+		}
+		defer resp.Body.Close()
+
+		for name, values := range resp.Header {
+			if name == "Content-Length" || forwardedHeader(name) {
+				w.Header()[name] = values
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		rc := http.NewResponseController(w)
+		buf := make([]byte, 32<<10)
+		for {
+			n, err := resp.Body.Read(buf)
+			if n > 0 {
+				if _, err := w.Write(buf[:n]); err != nil {
+					return
+				}
+				_ = rc.Flush() // deliver events as they arrive
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+}
+
+// forwardedHeader reports whether a header is passed between the caller and the beacon node: the
+// content negotiation headers and the Eth-* headers of the beacon API (such as Eth-Consensus-Version).
+func forwardedHeader(name string) bool {
+	switch name = http.CanonicalHeaderKey(name); name {
+	case "Accept", "Content-Type", "Cache-Control":
+		return true
+	default:
+		return strings.HasPrefix(name, "Eth-")
+	}
+}
+
+// beaconState returns the state id selected by the state query parameter.
+func beaconState(r *http.Request) (string, error) {
+	state := r.URL.Query().Get(StateKey)
+	if state == "" {
+		return defaultBeaconState, nil
+	}
+	if !stateIDPattern.MatchString(state) {
+		return "", fmt.Errorf("invalid state id '%s'", state)
+	}
+	return state, nil
+}
+
+// BeaconCheckpoint is a beacon chain checkpoint.
+type BeaconCheckpoint struct {
+	Epoch uint64      `json:"epoch,string"`
+	Root  common.Hash `json:"root"`
+}
+
+// BeaconFinalityResponse contains the finality checkpoints of a beacon state.
+type BeaconFinalityResponse struct {
+	State             string           `json:"state"`
+	PreviousJustified BeaconCheckpoint `json:"previous_justified"`
+	CurrentJustified  BeaconCheckpoint `json:"current_justified"`
+	Finalized         BeaconCheckpoint `json:"finalized"`
+}
+
+// BeaconFinality handles the finality checkpoints endpoint. The state is selected by the state query
+// parameter (head by default).
+func BeaconFinality(beacon *BeaconClient) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+		state, err := beaconState(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+
+		resp := &BeaconFinalityResponse{State: state}
+		if err := beacon.getData(ctx, "/eth/v1/beacon/states/"+state+"/finality_checkpoints", "", resp); err != nil {
+			respondWithError(w, beaconErrorCode(err), fmt.Errorf("beacon client error: %v", err))
+			return
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
+
+// ValidatorBalance is the balance of a validator in Gwei.
+type ValidatorBalance struct {
+	Index   uint64 `json:"index,string"`
+	Balance uint64 `json:"balance,string"`
+}
+
+// BeaconBalancesResponse contains validator balances at a beacon state.
+type BeaconBalancesResponse struct {
+	State    string             `json:"state"`
+	Balances []ValidatorBalance `json:"balances"`
+}
+
+// BeaconBalances handles the validator balances endpoint for a comma-separated list of validator
+// indices or public keys. The state is selected by the state query parameter (head by default).
+// Unknown validators are left out of the response.
+func BeaconBalances(beacon *BeaconClient) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+
+		validators := strings.Split(p.ByName(ValidatorsKey[1:]), ",")
+		if len(validators) > maxValidators {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("at most %d validators may be requested", maxValidators))
+			return
+		}
+		for _, validator := range validators {
+			if !validatorPattern.MatchString(validator) {
+				respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid validator '%s'", validator))
+				return
+			}
+		}
+		state, err := beaconState(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+
+		resp := &BeaconBalancesResponse{State: state}
+		query := "id=" + strings.Join(validators, "&id=")
+		if err := beacon.getData(ctx, "/eth/v1/beacon/states/"+state+"/validator_balances", query, &resp.Balances); err != nil {
+			respondWithError(w, beaconErrorCode(err), fmt.Errorf("beacon client error: %v", err))
+			return
+		}
+		if resp.Balances == nil {
+			resp.Balances = []ValidatorBalance{}
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
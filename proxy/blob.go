@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// maxBlobsPerTx is the maximum number of blobs that can be included in a single
+// block and therefore in a single transaction (EIP-4844).
+const maxBlobsPerTx = params.MaxBlobGasPerBlock / params.BlobTxBlobGasPerBlob
+
+var errMissingSidecar = errors.New("blob transaction must be submitted in network form with a sidecar")
+
+// validateBlobTx checks that a type-3 transaction carries a well formed sidecar before it is
+// broadcast upstream: the blob count must be within protocol limits, every commitment must
+// hash to the matching versioned hash and every KZG proof must verify against its blob.
+// Transactions of any other type are ignored.
+func validateBlobTx(tx *types.Transaction) error {
+	if tx.Type() != types.BlobTxType {
+		return nil
+	}
+	hashes := tx.BlobHashes()
+	if len(hashes) == 0 {
+		return errors.New("blob transaction must reference at least one blob")
+	}
+	if len(hashes) > maxBlobsPerTx {
+		return fmt.Errorf("too many blobs in transaction: have %d, max %d", len(hashes), maxBlobsPerTx)
+	}
+	sidecar := tx.BlobTxSidecar()
+	if sidecar == nil {
+		return errMissingSidecar
+	}
+	if len(sidecar.Blobs) != len(hashes) {
+		return fmt.Errorf("invalid number of blobs: have %d, want %d", len(sidecar.Blobs), len(hashes))
+	}
+	if len(sidecar.Commitments) != len(hashes) {
+		return fmt.Errorf("invalid number of blob commitments: have %d, want %d", len(sidecar.Commitments), len(hashes))
+	}
+	if len(sidecar.Proofs) != len(hashes) {
+		return fmt.Errorf("invalid number of blob proofs: have %d, want %d", len(sidecar.Proofs), len(hashes))
+	}
+	hasher := sha256.New()
+	for i, want := range hashes {
+		if have := common.Hash(kzg4844.CalcBlobHashV1(hasher, &sidecar.Commitments[i])); have != want {
+			return fmt.Errorf("blob %d: commitment hash %x does not match versioned hash %x", i, have, want)
+		}
+	}
+	for i := range sidecar.Blobs {
+		if err := kzg4844.VerifyBlobProof(&sidecar.Blobs[i], sidecar.Commitments[i], sidecar.Proofs[i]); err != nil {
+			return fmt.Errorf("blob %d: invalid kzg proof: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BreakerState is the state of the circuit breaker guarding an upstream node.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // node is in rotation
+	BreakerOpen     BreakerState = "open"      // node has been taken out of rotation
+	BreakerHalfOpen BreakerState = "half-open" // node is receiving trial requests after the cooldown period
+)
+
+// circuitBreaker tracks consecutive failures of a single upstream node. Closed breakers open after
+// failureThreshold consecutive failures. Once the cooldown has elapsed an open breaker becomes
+// half-open: a single failure re-opens it and successThreshold consecutive successes close it. The
+// open and healthy flags mirror the state so that routing and successful requests do not take the lock.
+type circuitBreaker struct {
+	open    atomic.Bool // the breaker is open (it may be due to become half-open)
+	healthy atomic.Bool // the breaker is closed and no failure has been recorded since it closed
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	successes int
+	openedAt  time.Time
+
+	failureThreshold int
+	successThreshold int
+	cooldown         time.Duration
+
+	now func() time.Time
+}
+
+func newCircuitBreaker(cfg HealthCheckConfig) *circuitBreaker {
+	b := &circuitBreaker{state: BreakerClosed, now: time.Now}
+	b.healthy.Store(true)
+	b.configure(cfg)
+	return b
+}
+
+// configure applies the breaker thresholds contained in cfg (unset values use the defaults).
+func (b *circuitBreaker) configure(cfg HealthCheckConfig) {
+	cfg = cfg.withDefaults()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failureThreshold, b.successThreshold, b.cooldown = cfg.FailureThreshold, cfg.SuccessThreshold, cfg.Cooldown
+}
+
+// State returns the current breaker state, moving an open breaker to half-open once the cooldown
+// period has elapsed.
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.refresh()
+}
+
+func (b *circuitBreaker) refresh() BreakerState {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.state, b.successes = BreakerHalfOpen, 0
+		b.publish()
+	}
+	return b.state
+}
+
+// publish updates the flags mirroring the breaker state. It must be called with the lock held.
+func (b *circuitBreaker) publish() {
+	b.open.Store(b.state == BreakerOpen)
+	b.healthy.Store(b.state == BreakerClosed && b.failures == 0)
+}
+
+// available reports whether the node may receive requests.
+func (b *circuitBreaker) available() bool {
+	return !b.open.Load() || b.State() != BreakerOpen
+}
+
+// success records a successful probe or request and returns the resulting state.
+func (b *circuitBreaker) success() BreakerState {
+	if b.healthy.Load() {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.publish()
+	switch b.refresh() {
+	case BreakerClosed:
+		b.failures = 0
+	case BreakerHalfOpen:
+		if b.successes++; b.successes >= b.successThreshold {
+			b.state, b.failures, b.successes = BreakerClosed, 0, 0
+		}
+	}
+	return b.state
+}
+
+// failure records a failed probe or request and returns the resulting state.
+func (b *circuitBreaker) failure() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.publish()
+	switch b.refresh() {
+	case BreakerClosed:
+		if b.failures++; b.failures >= b.failureThreshold {
+			b.state, b.openedAt = BreakerOpen, b.now()
+		}
+	case BreakerHalfOpen:
+		b.state, b.openedAt, b.successes = BreakerOpen, b.now(), 0
+	}
+	return b.state
+}
+
+// isNodeFailure reports whether a request error indicates a problem with the upstream node itself.
+// JSON-RPC errors (e.g. reverts), missing results and cancelled requests are not node failures.
+func isNodeFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	return !errors.Is(err, ethereum.NotFound) && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,237 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// txBroadcaster is implemented by clients which can report the outcome of sending a transaction to
+// each of their upstream nodes.
+type txBroadcaster interface {
+	BroadcastTransaction(ctx context.Context, tx *types.Transaction) ([]BroadcastResult, error)
+	StopRebroadcasts()
+}
+
+// BroadcastResult is the outcome of sending a transaction to a single upstream node. Nodes which
+// already knew the transaction are counted as having accepted it.
+type BroadcastResult struct {
+	Node     string `json:"node"`
+	Accepted bool   `json:"accepted"`
+	Known    bool   `json:"known,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// rebroadcaster re-broadcasts the pending transactions of a multiNodeClient from a single goroutine.
+type rebroadcaster struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	pending map[common.Hash]*pendingTx // at most BroadcastConfig.MaxPending transactions
+}
+
+// pendingTx is a transaction waiting to be mined.
+type pendingTx struct {
+	tx       *types.Transaction
+	from     common.Address
+	deadline time.Time // time after which the transaction is no longer re-broadcast
+}
+
+// isAlreadyKnown reports whether err is a node rejecting a transaction which is already in its pool.
+func isAlreadyKnown(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
+
+// sendTx sends tx to node. An "already known" rejection is reported as a success with known set.
+func sendTx(ctx context.Context, node *item, tx *types.Transaction) (known bool, err error) {
+	if err := node.client.SendTransaction(ctx, tx); err != nil {
+		if isAlreadyKnown(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// SetBroadcastConfig sets how sent transactions are propagated to the upstream nodes.
+func (m *multiNodeClient) SetBroadcastConfig(cfg BroadcastConfig) error {
+	if cfg.RebroadcastInterval < 0 || cfg.RebroadcastTimeout < 0 || cfg.MaxPending < 0 {
+		return fmt.Errorf("invalid rebroadcast interval %v, timeout %v or pending limit %d", cfg.RebroadcastInterval, cfg.RebroadcastTimeout, cfg.MaxPending)
+	}
+	m.update(func(st *routingState) { st.broadcast = cfg.withDefaults() })
+	return nil
+}
+
+// BroadcastTransaction sends tx to every send-only node and every node in rotation in parallel if
+// broadcast mode is enabled, returning the outcome for each node. The transaction is
+// accepted if any node accepts it and is re-broadcast in the background (see trackRebroadcast) if a
+// rebroadcast interval is configured. Without broadcast mode tx is sent to the first node to accept it (preferring
+// send-only nodes) and no per-node outcomes are returned.
+func (m *multiNodeClient) BroadcastTransaction(ctx context.Context, tx *types.Transaction) ([]BroadcastResult, error) {
+	cfg := m.routing().broadcast
+	if !cfg.Enabled {
+		_, err := callEach(ctx, m, m.sendRotation(), errNoNodes, func(ctx context.Context, node *item) (bool, error) {
+			return sendTx(ctx, node, tx)
+		})
+		return nil, err
+	}
+
+	results := m.broadcastTo(ctx, m.broadcastRotation(), tx)
+	var errs []string
+	accepted := false
+	for _, res := range results {
+		if res.Accepted {
+			accepted = true
+		} else {
+			errs = append(errs, fmt.Sprintf("node %s: %s", res.Node, res.Error))
+		}
+	}
+	if !accepted {
+		return results, fmt.Errorf("transaction rejected by every node [%s]", strings.Join(errs, ", "))
+	}
+	if cfg.RebroadcastInterval > 0 {
+		m.trackRebroadcast(tx, cfg)
+	}
+	return results, nil
+}
+
+// broadcastTo sends tx to each of the supplied nodes concurrently and returns the outcomes in node order.
+func (m *multiNodeClient) broadcastTo(ctx context.Context, nodes []*item, tx *types.Transaction) []BroadcastResult {
+	results := make([]BroadcastResult, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			known, err := callNode(ctx, m, node, func(ctx context.Context, node *item) (bool, error) {
+				return sendTx(ctx, node, tx)
+			})
+			results[i] = BroadcastResult{Node: node.id, Accepted: err == nil, Known: known}
+			result := "accepted"
+			switch {
+			case err != nil:
+				results[i].Error = err.Error()
+				result = "rejected"
+			case known:
+				result = "known"
+			}
+			txBroadcastCounter.WithLabelValues(node.id, result).Inc()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// trackRebroadcast adds tx to the transactions re-broadcast every cfg.RebroadcastInterval until the
+// sender's confirmed nonce passes the nonce of tx (the transaction, or another with the same nonce, has
+// been mined), the rebroadcast timeout expires or StopRebroadcasts is called. The re-broadcasts are
+// run by a single goroutine started with the first tracked transaction. Transactions whose sender
+// cannot be recovered, or which arrive while cfg.MaxPending transactions are pending, are not tracked.
+func (m *multiNodeClient) trackRebroadcast(tx *types.Transaction, cfg BroadcastConfig) {
+	m.mu.Lock()
+	if m.rebroadcaster == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.rebroadcaster = &rebroadcaster{ctx: ctx, cancel: cancel, pending: make(map[common.Hash]*pendingTx)}
+		m.rebroadcaster.wg.Add(1)
+		go m.runRebroadcasts(m.rebroadcaster, cfg.RebroadcastInterval)
+	}
+	r, l := m.rebroadcaster, m.logger
+	m.mu.Unlock()
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[tx.Hash()]; ok {
+		return
+	}
+	if len(r.pending) >= cfg.MaxPending {
+		if l != nil {
+			l.WithFields(logrus.Fields{"txid": tx.Hash().Hex(), "pending": len(r.pending)}).Warn("rebroadcastQueueFull")
+		}
+		return
+	}
+	r.pending[tx.Hash()] = &pendingTx{tx: tx, from: from, deadline: time.Now().Add(cfg.RebroadcastTimeout)}
+}
+
+// runRebroadcasts re-broadcasts the pending transactions of r at the given interval until r is stopped.
+func (m *multiNodeClient) runRebroadcasts(r *rebroadcaster, interval time.Duration) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.rebroadcastPending(r)
+	}
+}
+
+// rebroadcastPending re-broadcasts each pending transaction of r, first dropping those which have been
+// mined or replaced (the confirmed nonce of each sender is read once per round) or have timed out.
+func (m *multiNodeClient) rebroadcastPending(r *rebroadcaster) {
+	m.mu.Lock()
+	l := m.logger
+	m.mu.Unlock()
+	r.mu.Lock()
+	pending := slices.Collect(maps.Values(r.pending))
+	r.mu.Unlock()
+
+	nonces := make(map[common.Address]uint64)
+	now := time.Now()
+	for _, p := range pending {
+		if r.ctx.Err() != nil {
+			return
+		}
+		ctx, cancelFunc := context.WithTimeout(r.ctx, timeout)
+		done := now.After(p.deadline)
+		if done && l != nil {
+			l.WithFields(logrus.Fields{"txid": p.tx.Hash().Hex()}).Warn("rebroadcastTimeout")
+		}
+		if !done {
+			nonce, ok := nonces[p.from]
+			if !ok {
+				var err error
+				if nonce, err = m.NonceAt(ctx, p.from, nil); err == nil {
+					nonces[p.from], ok = nonce, true
+				}
+			}
+			done = ok && nonce > p.tx.Nonce()
+		}
+		if done {
+			r.mu.Lock()
+			delete(r.pending, p.tx.Hash())
+			r.mu.Unlock()
+		} else {
+			m.broadcastTo(ctx, m.broadcastRotation(), p.tx)
+		}
+		cancelFunc()
+	}
+}
+
+// StopRebroadcasts stops background re-broadcasts and waits for any broadcast in progress to return.
+func (m *multiNodeClient) StopRebroadcasts() {
+	m.mu.Lock()
+	r := m.rebroadcaster
+	m.rebroadcaster = nil
+	m.mu.Unlock()
+	if r == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+)
+
+const (
+	quotaWindowHours   = 24               // the daily quota is counted over a rolling window of hourly buckets
+	quotaHeadroom      = 0.9              // share of the daily quota after which a node is considered near its limit
+	saturationCooldown = 10 * time.Second // time a node which answered 429 is treated as saturated
+)
+
+// nodeBudget tracks the request rate limit and rolling daily quota of an upstream node. Nodes which are
+// near either limit, or which have recently rejected requests with a 429, are tried after other nodes.
+// Limits are not enforced: a node over its budget is still used if no other node is available.
+type nodeBudget struct {
+	id      string
+	limiter *rate.Limiter // nil if no rate limit is configured
+	quota   int64         // requests per rolling day, unlimited if zero
+
+	saturated atomic.Int64 // time (unix nanoseconds) until which the node is treated as saturated
+
+	mu     sync.Mutex
+	counts [quotaWindowHours]int64
+	hours  [quotaWindowHours]int64 // hour (since the unix epoch) counted by each bucket
+	now    func() time.Time
+}
+
+func newNodeBudget(id string, cfg NodeConfig) *nodeBudget {
+	b := &nodeBudget{id: id, quota: cfg.DailyQuota, now: time.Now}
+	if cfg.RateLimit > 0 {
+		b.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), max(1, int(cfg.RateLimit)))
+	}
+	if b.quota > 0 {
+		nodeQuotaLimitGauge.WithLabelValues(id).Set(float64(b.quota))
+		nodeQuotaUsedGauge.WithLabelValues(id).Set(0)
+	}
+	return b
+}
+
+// record counts n requests made to the node against its rate limit and quota.
+func (b *nodeBudget) record(n int) {
+	now := b.now()
+	if b.limiter != nil {
+		b.limiter.ReserveN(now, n) // reservations beyond the burst drive the bucket negative
+	}
+	if b.quota <= 0 {
+		return
+	}
+	b.mu.Lock()
+	hour := now.Unix() / 3600
+	i := hour % quotaWindowHours
+	if b.hours[i] != hour {
+		b.hours[i], b.counts[i] = hour, 0
+	}
+	b.counts[i] += int64(n)
+	used := b.usedLocked(hour)
+	b.mu.Unlock()
+	nodeQuotaUsedGauge.WithLabelValues(b.id).Set(float64(used))
+}
+
+// usedLocked returns the number of requests counted in the rolling window ending at hour.
+func (b *nodeBudget) usedLocked(hour int64) int64 {
+	var used int64
+	for i, h := range b.hours {
+		if h > hour-quotaWindowHours {
+			used += b.counts[i]
+		}
+	}
+	return used
+}
+
+// used returns the number of requests made to the node in the last day.
+func (b *nodeBudget) used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.usedLocked(b.now().Unix() / 3600)
+}
+
+// saturate marks the node as saturated for the saturation cooldown.
+func (b *nodeBudget) saturate() {
+	b.saturated.Store(b.now().Add(saturationCooldown).UnixNano())
+	nodeRateLimitedCounter.WithLabelValues(b.id).Inc()
+}
+
+// nearLimit reports whether the node is saturated, has used its request rate allowance or is close to
+// exhausting its daily quota.
+func (b *nodeBudget) nearLimit() bool {
+	now := b.now()
+	switch {
+	case now.UnixNano() < b.saturated.Load():
+		return true
+	case b.limiter != nil && b.limiter.TokensAt(now) < 1:
+		return true
+	case b.quota > 0 && float64(b.used()) >= quotaHeadroom*float64(b.quota):
+		return true
+	default:
+		return false
+	}
+}
+
+// isRateLimited reports whether err is an upstream node rejecting a request because it is over its rate limit.
+func isRateLimited(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		msg := strings.ToLower(rpcErr.Error())
+		return rpcErr.ErrorCode() == rpcLimitExceededCode || strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const cacheEntryOverhead = 128 // approximate bytes used by a cache entry in addition to its key and value
+
+// cacheScope is the lifetime of a cached response.
+type cacheScope int
+
+const (
+	scopeNone  cacheScope = iota // the response is not cached
+	scopeHead                    // the response is valid until the next head
+	scopeFinal                   // the response concerns finalized blocks and is valid until evicted
+)
+
+// cacheEntry is a cached response.
+type cacheEntry struct {
+	key   string
+	val   any
+	size  int64
+	scope cacheScope
+	gen   uint64 // head generation at which a head-scoped response was read
+}
+
+// responseCache is a least recently used cache of upstream responses bounded by an approximate byte
+// size. Head-scoped responses are dropped when the head tracker reports a new head or a reorg.
+type responseCache struct {
+	maxBytes   int64
+	bytesGauge prometheus.Gauge // size of the cache, labelled with its chain
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List // most recently used first
+	bytes     int64
+	head      uint64 // latest block number reported by the head tracker, zero until the first report
+	finalized uint64 // latest finalized block number, zero if unknown
+	gen       uint64 // incremented whenever head-scoped responses are invalidated
+}
+
+func newResponseCache(cfg CacheConfig, chain string) *responseCache {
+	c := &responseCache{maxBytes: cfg.MaxBytes, bytesGauge: cacheBytesGauge.WithLabelValues(chain), entries: make(map[string]*list.Element), lru: list.New()}
+	c.bytesGauge.Set(0)
+	return c
+}
+
+// SetCacheConfig enables the response cache. Caching of head-scoped responses starts once the head
+// tracker, run with the health checks, has reported the chain head.
+func (m *multiNodeClient) SetCacheConfig(cfg CacheConfig) {
+	var c *responseCache
+	if cfg.Enabled {
+		c = newResponseCache(cfg.withDefaults(), m.chain)
+	}
+	m.update(func(st *routingState) { st.cache, st.cacheCfg = c, cfg.withDefaults() })
+}
+
+// get returns the cached response for key. Head-scoped responses are only returned if they were read
+// since the last invalidation.
+func (c *responseCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if entry.scope == scopeHead && entry.gen != c.gen {
+		c.removeLocked(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return entry.val, true
+}
+
+// put caches a response read at head generation gen, evicting the least recently used entries to
+// stay within the size limit. Head-scoped responses are discarded if the head has moved on.
+func (c *responseCache) put(key string, val any, scope cacheScope, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if scope == scopeNone || scope == scopeHead && (c.head == 0 || gen != c.gen) {
+		return
+	}
+	entry := &cacheEntry{key: key, val: val, size: int64(len(key)) + responseSize(val) + cacheEntryOverhead, scope: scope, gen: gen}
+	if entry.size > c.maxBytes {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.removeLocked(el)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.bytes += entry.size
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.lru.Back())
+	}
+	c.bytesGauge.Set(float64(c.bytes))
+}
+
+func (c *responseCache) removeLocked(el *list.Element) {
+	entry := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+	c.bytesGauge.Set(float64(c.bytes))
+}
+
+// isFinal reports whether a response concerning finalized blocks is cached for key.
+func (c *responseCache) isFinal(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	return ok && el.Value.(*cacheEntry).scope == scopeFinal
+}
+
+// current returns the latest head and finalized block numbers.
+func (c *responseCache) current() (head, finalized uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, c.finalized
+}
+
+// position returns the latest head and the current head generation.
+func (c *responseCache) position() (head, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, c.gen
+}
+
+// setHead records a new head, dropping every head-scoped response. It reports whether the head changed.
+func (c *responseCache) setHead(head, finalized uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finalized = max(c.finalized, finalized)
+	if head == c.head {
+		return false
+	}
+	c.head = head
+	c.dropLocked(scopeHead)
+	return true
+}
+
+// invalidate drops the responses which may concern blocks orphaned by a reorg from the fork height
+// onwards. Every head-scoped response is dropped and, if the reorg reached finalized blocks, so is
+// every other response.
+func (c *responseCache) invalidate(fork uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scope := scopeHead
+	if c.finalized > 0 && fork <= c.finalized {
+		c.finalized, scope = fork-1, scopeFinal
+	}
+	c.dropLocked(scope)
+}
+
+// dropLocked removes every response with a scope up to and including scope and starts a new head
+// generation.
+func (c *responseCache) dropLocked(scope cacheScope) {
+	c.gen++
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*cacheEntry).scope <= scope {
+			c.removeLocked(el)
+		}
+		el = next
+	}
+}
+
+// blockScope returns the lifetime of a response read at the given block: responses at finalized
+// blocks are kept until evicted and latest or unfinalized responses until the next head. Responses
+// read at pending, safe or finalized tags are not cached.
+func (c *responseCache) blockScope(number *big.Int) cacheScope {
+	switch {
+	case number == nil:
+		return scopeHead
+	case number.Sign() < 0 || !number.IsUint64():
+		return scopeNone
+	}
+	if _, finalized := c.current(); finalized > 0 && number.Uint64() <= finalized {
+		return scopeFinal
+	}
+	return scopeHead
+}
+
+// responseSize returns the approximate number of bytes used by a cached response.
+func responseSize(val any) int64 {
+	switch v := val.(type) {
+	case *big.Int:
+		return int64(len(v.Bits())) * 8
+	case []byte:
+		return int64(len(v))
+	case *types.Header:
+		return int64(v.Size())
+	case *types.Receipt:
+		size := int64(types.BloomByteLength + 256)
+		for _, log := range v.Logs {
+			size += int64(len(log.Data)+len(log.Topics)*32) + 128
+		}
+		return size
+	case txByHashResult:
+		if v.tx == nil {
+			return 0
+		}
+		return int64(v.tx.Size())
+	default:
+		return 8
+	}
+}
+
+// cachedRead answers a read from the cache if possible. Otherwise the read is made with fn (coalesced
+// with identical reads in flight) and cached with the lifetime returned by scope. Reads which require
+// a minimum block beyond the tracked head bypass the cache.
+func cachedRead[T any](ctx context.Context, m *multiNodeClient, method, params string, scope func(c *responseCache, val T) cacheScope, fn func(context.Context) (T, error)) (T, error) {
+	c := m.routing().cache
+	if c == nil {
+		return coalesce(ctx, m, method, params, fn)
+	}
+	key := method + "(" + params + ")"
+	head, gen := c.position()
+	if minBlockFrom(ctx) <= head {
+		if val, ok := c.get(key); ok {
+			cacheHitsCounter.WithLabelValues(m.chain, method).Inc()
+			return val.(T), nil
+		}
+	}
+	cacheMissesCounter.WithLabelValues(m.chain, method).Inc()
+	val, err := coalesce(ctx, m, method, params, fn)
+	if err == nil {
+		c.put(key, val, scope(c, val), gen)
+	}
+	return val, err
+}
+
+// atBlock returns a cache scope func for reads at the given block.
+func atBlock[T any](number *big.Int) func(c *responseCache, _ T) cacheScope {
+	return func(c *responseCache, _ T) cacheScope { return c.blockScope(number) }
+}
+
+// atHash is the cache scope func for reads pinned to a block hash, whose state never changes.
+func atHash[T any](_ *responseCache, _ T) cacheScope { return scopeFinal }
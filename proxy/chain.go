@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	ChainHeader = "X-Chain" // selects the chain serving a request by name
+
+	ethV0Prfx = "/eth/v0/"
+)
+
+var chainNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Chain is a chain served alongside the default chain.
+type Chain struct {
+	Config ChainConfig
+	Client SimpleEthClient
+}
+
+// NewChainClient connects to the nodes of a chain and applies its routing settings. The ids of the nodes
+// of a named chain start with its name (e.g. sepolia-0) so that their metrics and health reports can be
+// told apart from those of other chains. If a chain ID is configured every connected node must report it.
+func NewChainClient(cfg ChainConfig, dial func(cfg NodeConfig) (SimpleEthClient, error)) (*multiNodeClient, error) {
+	m, err := newMultiNodeClient(cfg.Name, cfg.NodeConfigs(), dial)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.SetSelectionStrategy(cfg.Strategy); err != nil {
+		return nil, err
+	}
+	if err := m.SetHedgeConfig(cfg.Hedge); err != nil {
+		return nil, err
+	}
+	if err := m.SetBroadcastConfig(cfg.Broadcast); err != nil {
+		return nil, err
+	}
+	m.SetHeadConfig(cfg.Head)
+	m.SetCoalesceConfig(cfg.Coalesce)
+	m.SetCacheConfig(cfg.Cache)
+	m.SetReorgConfig(cfg.Reorg)
+	if err := m.SetRetryConfig(cfg.Retry); err != nil {
+		return nil, err
+	}
+	if cfg.ChainID != 0 {
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+		if err := m.SetChainID(ctx, cfg.ChainID); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// NewChains connects to the nodes of each chain configured alongside the default chain of cfg. Chain
+// names (including the name of the default chain) must be unique and cannot be the first segment of an
+// /eth/v0/ endpoint path.
+func NewChains(cfg *Config, dial func(cfg NodeConfig) (SimpleEthClient, error)) ([]Chain, error) {
+	names := make(map[string]bool)
+	if cfg.Chain != "" {
+		if err := validateChainName(cfg.Chain); err != nil {
+			return nil, err
+		}
+		names[cfg.Chain] = true
+	}
+	var chains []Chain
+	for _, chainCfg := range cfg.Chains {
+		if err := validateChainName(chainCfg.Name); err != nil {
+			return nil, err
+		}
+		if names[chainCfg.Name] {
+			return nil, fmt.Errorf("duplicate chain name '%s'", chainCfg.Name)
+		}
+		names[chainCfg.Name] = true
+		client, err := NewChainClient(chainCfg, dial)
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: %v", chainCfg.Name, err)
+		}
+		chains = append(chains, Chain{Config: chainCfg, Client: client})
+	}
+	return chains, nil
+}
+
+// validateChainName checks that name can be used in endpoint paths without shadowing an endpoint.
+func validateChainName(name string) error {
+	if !chainNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid chain name '%s': lower-case letters, digits and dashes expected", name)
+	}
+	for _, path := range []string{EthV0BalancePrfx, EthV0NoncePrfx, EthV0StoragePrfx, EthV0TxPrfx, EthV0TxPoolPrfx, EthV0FeeEndPnt, EthV0CallEndPnt, EthV0BeaconFinalityEndPnt} {
+		if segment, _, _ := strings.Cut(strings.TrimPrefix(path, ethV0Prfx), "/"); segment == name {
+			return fmt.Errorf("invalid chain name '%s': reserved by the %s endpoints", name, ethV0Prfx+segment)
+		}
+	}
+	return nil
+}
+
+// SetChainID requires every node to report the given chain ID: the connected nodes are checked now, and
+// nodes which are redialled or added later are rejected unless they report it.
+func (m *multiNodeClient) SetChainID(ctx context.Context, id uint64) error {
+	want := new(big.Int).SetUint64(id)
+	var errs []string
+	for _, node := range m.snapshot() {
+		reader, ok := node.client.(chainIDReader)
+		if !ok {
+			continue
+		}
+		got, err := reader.ChainID(ctx)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Sprintf("node %s chain id error: %v", node.id, err))
+		case got.Cmp(want) != 0:
+			errs = append(errs, fmt.Sprintf("node %s chain id %v does not match configured chain id %v", node.id, got, want))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	m.mu.Lock()
+	m.chainID = want
+	m.mu.Unlock()
+	return nil
+}
+
+// chainRouter passes each request to the handler of the chain it is addressed to: the chain named by
+// the path of an /eth/v0/{chain}/... request (served as /eth/v0/...), or otherwise the chain named by
+// the X-Chain header. Requests addressed to no chain are served by the default chain.
+type chainRouter struct {
+	defaultName    string // name of the default chain, if any
+	defaultHandler http.Handler
+	chains         map[string]http.Handler
+}
+
+// handler returns the handler of the named chain.
+func (c *chainRouter) handler(name string) (http.Handler, bool) {
+	if name != "" && name == c.defaultName {
+		return c.defaultHandler, true
+	}
+	handler, ok := c.chains[name]
+	return handler, ok
+}
+
+func (c *chainRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rest, ok := strings.CutPrefix(r.URL.Path, ethV0Prfx); ok {
+		name, path, _ := strings.Cut(rest, "/")
+		if handler, ok := c.handler(name); ok {
+			u := *r.URL
+			u.Path, u.RawPath = ethV0Prfx+path, ""
+			req := *r
+			req.URL = &u
+			handler.ServeHTTP(w, &req)
+			return
+		}
+	}
+	if name := r.Header.Get(ChainHeader); name != "" {
+		handler, ok := c.handler(name)
+		if !ok {
+			respondWithError(w, http.StatusNotFound, fmt.Errorf("unknown chain '%s'", name))
+			return
+		}
+		handler.ServeHTTP(w, r)
+		return
+	}
+	c.defaultHandler.ServeHTTP(w, r)
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// SetCoalesceConfig enables or disables the coalescing of identical concurrent reads.
+func (m *multiNodeClient) SetCoalesceConfig(cfg CoalesceConfig) {
+	m.update(func(st *routingState) { st.coalesce = cfg })
+}
+
+// blockKey formats a block number for use in a coalescing key.
+func blockKey(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return number.String()
+}
+
+// coalesce calls fn unless an identical request (the same method, params and minimum block) is
+// already in flight, in which case the caller waits for and shares its result. The shared call is
+// not cancelled if the caller that started it gives up, but is bounded by that caller's deadline.
+// Results are shared between callers and must not be modified.
+func coalesce[T any](ctx context.Context, m *multiNodeClient, method, params string, fn func(context.Context) (T, error)) (T, error) {
+	if !m.routing().coalesce.Enabled {
+		return fn(ctx)
+	}
+	key := fmt.Sprintf("%s(%s)@%d", method, params, minBlockFrom(ctx))
+	led := false
+	ch := m.flight.DoChan(key, func() (any, error) {
+		led = true
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(timeout)
+		}
+		callCtx, cancelFunc := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+		defer cancelFunc()
+		return fn(callCtx)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if !led {
+			coalescedRequestsCounter.WithLabelValues(m.chain, method).Inc()
+		}
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
@@ -1,23 +1,411 @@
 package proxy
 
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
 const (
 	defaultPort      = 8080
 	defaultLogLevel  = "info"
 	defaultLogFormat = "plain"
+
+	defaultHealthCheckInterval = 15 * time.Second
+	defaultFailureThreshold    = 3
+	defaultSuccessThreshold    = 2
+	defaultBreakerCooldown     = 30 * time.Second
+	defaultMaxProbeLatency     = 2 * time.Second
+	defaultMaxBlockLag         = blockDiff
+	defaultMinHedgeDelay       = 10 * time.Millisecond
+	defaultMaxHedgeDelay       = time.Second
+	defaultRebroadcastTimeout  = 10 * time.Minute
+	defaultMaxRebroadcasts     = 1000
+	defaultCheckpointDistance  = 128
+	defaultMaxRetryBackoff     = time.Second
+	defaultRedialBackoff       = time.Second
+	defaultCacheMaxBytes       = 64 << 20
+	defaultHeadInterval        = time.Second
+	defaultReorgDepth          = 64
+	defaultKeepalive           = 15 * time.Second
+	defaultMaxRedialBackoff    = time.Minute
+	defaultMaxSyncDistance     = 2
 )
 
 var (
 	emptyConfig   = Config{}
-	defaultConfig = Config{Port: defaultPort, LogLevel: defaultLogLevel, LogFormat: defaultLogFormat}
+	defaultConfig = Config{
+		Port:      defaultPort,
+		LogLevel:  defaultLogLevel,
+		LogFormat: defaultLogFormat,
+		Strategy:  StrategyEWMA,
+		HealthCheck: HealthCheckConfig{
+			Interval:         defaultHealthCheckInterval,
+			FailureThreshold: defaultFailureThreshold,
+			SuccessThreshold: defaultSuccessThreshold,
+			Cooldown:         defaultBreakerCooldown,
+			MaxLatency:       defaultMaxProbeLatency,
+			MaxLag:           defaultMaxBlockLag,
+			RedialBackoff:    defaultRedialBackoff,
+			MaxRedialBackoff: defaultMaxRedialBackoff,
+			Keepalive:        defaultKeepalive,
+		},
+	}
 )
 
 // Config represents the service configuration
 // struct.
 type Config struct {
-	Port      int    `yaml:"port"`
-	LogLevel  string `yaml:"loglevel"`
-	LogFormat string `yaml:"logformat"`
-	URLs      string `yaml:"urls"` // must be supplied by user
+	Port      int          `yaml:"port"`
+	LogLevel  string       `yaml:"loglevel"`
+	LogFormat string       `yaml:"logformat"`
+	URLs      string       `yaml:"urls"`     // must be supplied by user (unless nodes are configured)
+	Nodes     []NodeConfig `yaml:"nodes"`    // optional per-node configuration
+	Strategy  string       `yaml:"strategy"` // upstream node selection strategy: ewma, round-robin, least-outstanding or priority
+
+	Chain   string `yaml:"chain"`   // optional name under which the default chain is also served, e.g. mainnet
+	ChainID uint64 `yaml:"chainid"` // chain ID every node must report (not checked if zero)
+
+	SimulateTxs bool `yaml:"simulatetxs"` // simulate every transaction before broadcast, rejecting those that would revert
+
+	Proof ProofConfig `yaml:"proof"` // proof-verified state reads
+
+	HealthCheck HealthCheckConfig `yaml:"healthcheck"` // background node probing and circuit breakers
+
+	Hedge HedgeConfig `yaml:"hedge"` // hedged idempotent reads
+
+	QuorumReads QuorumReadsConfig `yaml:"quorumreads"` // per-endpoint consistency mode
+
+	Broadcast BroadcastConfig `yaml:"broadcast"` // transaction propagation to the upstream nodes
+
+	Head HeadConfig `yaml:"head"` // head-aware read routing
+
+	Retry RetryConfig `yaml:"retry"` // retry policy for upstream requests
+
+	Admin AdminConfig `yaml:"admin"` // runtime node administration API
+
+	Coalesce CoalesceConfig `yaml:"coalesce"` // sharing of identical concurrent upstream reads
+
+	Cache CacheConfig `yaml:"cache"` // block-aware response cache
+
+	Reorg ReorgConfig `yaml:"reorg"` // chain reorganization detection
+
+	Beacon BeaconConfig `yaml:"beacon"` // consensus layer beacon API proxying
+
+	Chains []ChainConfig `yaml:"chains"` // further chains served alongside the default chain
+}
+
+// ChainConfig is the config block of a chain served alongside the default chain, which is configured
+// by the top-level fields. Each chain has its own nodes and routing settings, and is served under
+// /eth/v0/{name}/ or selected for any endpoint with the X-Chain header. The health check, admin and
+// beacon settings are shared with the default chain.
+type ChainConfig struct {
+	Name     string       `yaml:"name"`    // lower-case letters, digits and dashes, e.g. sepolia
+	ChainID  uint64       `yaml:"chainid"` // chain ID every node must report (not checked if zero)
+	URLs     string       `yaml:"urls"`
+	Nodes    []NodeConfig `yaml:"nodes"`
+	Strategy string       `yaml:"strategy"`
+
+	SimulateTxs bool              `yaml:"simulatetxs"`
+	Proof       ProofConfig       `yaml:"proof"`
+	Hedge       HedgeConfig       `yaml:"hedge"`
+	QuorumReads QuorumReadsConfig `yaml:"quorumreads"`
+	Broadcast   BroadcastConfig   `yaml:"broadcast"`
+	Head        HeadConfig        `yaml:"head"`
+	Retry       RetryConfig       `yaml:"retry"`
+	Coalesce    CoalesceConfig    `yaml:"coalesce"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Reorg       ReorgConfig       `yaml:"reorg"`
+}
+
+// NodeConfigs returns the full list of upstream nodes of the chain. Untagged nodes from the
+// comma-separated urls field are listed first followed by the nodes block.
+func (c ChainConfig) NodeConfigs() []NodeConfig {
+	var nodes []NodeConfig
+	if c.URLs != "" {
+		for _, url := range strings.Split(c.URLs, ",") {
+			nodes = append(nodes, NodeConfig{URL: url})
+		}
+	}
+	return append(nodes, c.Nodes...)
+}
+
+// serviceConfig returns the config of the endpoints serving the chain: the endpoint settings of the
+// chain with the shared settings (such as the admin API) of cfg.
+func (c ChainConfig) serviceConfig(cfg *Config) *Config {
+	chainCfg := *cfg
+	chainCfg.SimulateTxs, chainCfg.Proof, chainCfg.QuorumReads = c.SimulateTxs, c.Proof, c.QuorumReads
+	return &chainCfg
+}
+
+// BeaconConfig enables proxying of the beacon node API of consensus clients under /beacon/. Beacon
+// nodes are routed, retried and taken out of rotation by their circuit breakers like execution nodes,
+// using the retry policy and health check settings of the execution nodes. A beacon node fails its
+// health check unless /eth/v1/node/health reports it ready (or syncing) and it is at most
+// MaxSyncDistance slots behind the chain head.
+type BeaconConfig struct {
+	Nodes           []NodeConfig `yaml:"nodes"`           // beacon API endpoints, beacon proxying is disabled if empty
+	Strategy        string       `yaml:"strategy"`        // beacon node selection strategy (default ewma)
+	MaxSyncDistance uint64       `yaml:"maxsyncdistance"` // slots a node may be behind the chain head (default 2)
+}
+
+// withDefaults returns a copy of the config with unset values replaced by default values.
+func (c BeaconConfig) withDefaults() BeaconConfig {
+	if c.MaxSyncDistance == 0 {
+		c.MaxSyncDistance = defaultMaxSyncDistance
+	}
+	return c
+}
+
+// ReorgConfig enables chain reorganization detection. The head tracker records the hashes of the
+// last Depth blocks and reports a reorg when a block's parent hash or the hash at a known height
+// changes. Receipts for transactions in orphaned blocks are flagged as reorged.
+type ReorgConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Depth   int  `yaml:"depth"` // number of recent block hashes tracked (default 64)
+}
+
+// withDefaults returns a copy of the config with unset values replaced by default values.
+func (c ReorgConfig) withDefaults() ReorgConfig {
+	if c.Depth <= 0 {
+		c.Depth = defaultReorgDepth
+	}
+	return c
+}
+
+// CacheConfig enables the in-memory response cache. Responses concerning finalized blocks are kept until
+// evicted, while latest and unfinalized responses are kept until the head tracker, which polls the
+// latest header every HeadInterval, reports a new head.
+type CacheConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	MaxBytes     int64         `yaml:"maxbytes"`     // approximate size limit of the cache (default 64 MiB)
+	HeadInterval time.Duration `yaml:"headinterval"` // time between polls of the latest header (default 1s)
+}
+
+// withDefaults returns a copy of the config with unset values replaced by default values.
+func (c CacheConfig) withDefaults() CacheConfig {
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = defaultCacheMaxBytes
+	}
+	if c.HeadInterval <= 0 {
+		c.HeadInterval = defaultHeadInterval
+	}
+	return c
+}
+
+// CoalesceConfig enables request coalescing: identical reads (the same method and parameters) made
+// while one is already in flight wait for and share its result instead of calling an upstream node.
+type CoalesceConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// AdminConfig enables the node administration endpoints. Requests must carry the token as a bearer
+// token in the Authorization header; the admin API cannot be enabled without a token.
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token"`
+}
+
+// RetryConfig controls how failed upstream requests are retried. Retryable errors (timeouts, rate
+// limiting, 5xx responses and connection failures) are retried on the next node in rotation after an
+// exponential backoff with jitter, while terminal errors (reverts, nonce errors, not found) are
+// returned immediately. Each attempt may be given its own timeout within the request deadline.
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"maxattempts"`    // attempts per request, one per node in rotation if zero
+	InitialBackoff time.Duration `yaml:"initialbackoff"` // wait before the first retry, doubled for each further retry (no backoff if zero)
+	MaxBackoff     time.Duration `yaml:"maxbackoff"`     // upper bound on the wait between attempts
+	AttemptTimeout time.Duration `yaml:"attempttimeout"` // timeout of a single attempt, which also gets no more than its share of the time left before the request deadline (none if zero)
+}
+
+// withDefaults returns a copy of the config with an unset maximum backoff replaced by the default value.
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxRetryBackoff
+	}
+	c.MaxBackoff = max(c.MaxBackoff, c.InitialBackoff)
+	return c
+}
+
+// HeadConfig controls head-aware read routing. The proxy tracks the latest block reported by each
+// node and never routes reads to nodes more than MaxLag blocks behind the best-known head (the
+// highest block reached by a majority of the healthy nodes in rotation); reads fail if no other node
+// is available.
+type HeadConfig struct {
+	MaxLag uint64 `yaml:"maxlag"` // blocks a node may lag the best-known head, head-aware routing is disabled if zero
+}
+
+// BroadcastConfig controls how sent transactions are propagated. In broadcast mode every healthy
+// node is sent the transaction in parallel rather than only the first node to accept it, and the
+// transaction may be re-broadcast at an interval until it is seen in a block.
+type BroadcastConfig struct {
+	Enabled             bool          `yaml:"enabled"`             // send transactions to every healthy node
+	RebroadcastInterval time.Duration `yaml:"rebroadcastinterval"` // time between re-broadcasts of unmined transactions, disabled if zero
+	RebroadcastTimeout  time.Duration `yaml:"rebroadcasttimeout"`  // time after which unmined transactions are no longer re-broadcast
+	MaxPending          int           `yaml:"maxpending"`          // transactions re-broadcast at once, further transactions are not re-broadcast (default 1000)
+}
+
+// withDefaults returns a copy of the config with an unset rebroadcast timeout and pending limit replaced
+// by the default values.
+func (c BroadcastConfig) withDefaults() BroadcastConfig {
+	if c.RebroadcastTimeout <= 0 {
+		c.RebroadcastTimeout = defaultRebroadcastTimeout
+	}
+	if c.MaxPending <= 0 {
+		c.MaxPending = defaultMaxRebroadcasts
+	}
+	return c
+}
+
+// QuorumReadsConfig enables quorum reads for individual state read endpoints.
+type QuorumReadsConfig struct {
+	Balance QuorumConfig `yaml:"balance"`
+	Nonce   QuorumConfig `yaml:"nonce"`
+	Storage QuorumConfig `yaml:"storage"`
+}
+
+// QuorumConfig describes a quorum read: the value is read from Nodes upstream nodes in parallel
+// at the same block and returned only if at least Agree of them report the same answer.
+type QuorumConfig struct {
+	Nodes int `yaml:"nodes"` // number of nodes to query, every node in rotation if zero
+	Agree int `yaml:"agree"` // number of nodes which must agree, quorum reads are disabled if zero
+}
+
+// enabled reports whether quorum reads have been configured.
+func (c QuorumConfig) enabled() bool {
+	return c.Agree > 0
+}
+
+// HedgeConfig controls hedged reads. If the node serving an idempotent read has not answered
+// within the Percentile latency of its recent requests (clamped to [MinDelay, MaxDelay]) a
+// duplicate request is sent to the next node in rotation and the first answer is used. MaxDelay
+// is used until enough latency samples have been collected.
+type HedgeConfig struct {
+	Percentile float64       `yaml:"percentile"` // latency percentile after which a duplicate request is started, hedging is disabled if zero
+	MinDelay   time.Duration `yaml:"mindelay"`   // lower bound on the hedge delay
+	MaxDelay   time.Duration `yaml:"maxdelay"`   // upper bound on the hedge delay
+}
+
+// withDefaults returns a copy of the config with unset delay bounds replaced by default values.
+func (c HedgeConfig) withDefaults() HedgeConfig {
+	if c.MinDelay <= 0 {
+		c.MinDelay = defaultMinHedgeDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaultMaxHedgeDelay
+	}
+	c.MaxDelay = max(c.MaxDelay, c.MinDelay)
+	return c
+}
+
+// HealthCheckConfig controls the background prober and the per-node circuit breakers. A node is
+// taken out of rotation once FailureThreshold consecutive probes or requests fail. After Cooldown
+// it is half-open and is returned to rotation after SuccessThreshold consecutive successes.
+type HealthCheckConfig struct {
+	Interval         time.Duration `yaml:"interval"`         // time between probes (default 15s)
+	Disabled         bool          `yaml:"disabled"`         // switches background probing off
+	FailureThreshold int           `yaml:"failurethreshold"` // consecutive failures which open the breaker
+	SuccessThreshold int           `yaml:"successthreshold"` // consecutive half-open successes which close the breaker
+	Cooldown         time.Duration `yaml:"cooldown"`         // time an open breaker waits before allowing trial requests
+	MaxLatency       time.Duration `yaml:"maxlatency"`       // probes slower than this count as failures
+	MaxLag           uint64        `yaml:"maxlag"`           // nodes further than this many blocks behind the head agreed by most nodes fail probes
+	RedialBackoff    time.Duration `yaml:"redialbackoff"`    // initial wait before redialling a node which could not be connected
+	MaxRedialBackoff time.Duration `yaml:"maxredialbackoff"` // longest wait between redials of a node
+	Keepalive        time.Duration `yaml:"keepalive"`        // time between pings of WebSocket and IPC connections, disabled if negative (default 15s)
+}
+
+// withDefaults returns a copy of the config with unset thresholds replaced by default values.
+func (c HealthCheckConfig) withDefaults() HealthCheckConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = defaultFailureThreshold
+	}
+	if c.SuccessThreshold <= 0 {
+		c.SuccessThreshold = defaultSuccessThreshold
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = defaultBreakerCooldown
+	}
+	if c.MaxLatency <= 0 {
+		c.MaxLatency = defaultMaxProbeLatency
+	}
+	if c.MaxLag == 0 {
+		c.MaxLag = defaultMaxBlockLag
+	}
+	if c.RedialBackoff <= 0 {
+		c.RedialBackoff = defaultRedialBackoff
+	}
+	if c.MaxRedialBackoff < c.RedialBackoff {
+		c.MaxRedialBackoff = max(defaultMaxRedialBackoff, c.RedialBackoff)
+	}
+	if c.Keepalive == 0 {
+		c.Keepalive = defaultKeepalive
+	}
+	return c
+}
+
+// ProofConfig controls how the block hash used to verify eth_getProof responses is
+// established. By default a simple majority of upstream nodes, and at least two of them, must
+// agree on the hash. Blocks within CheckpointDistance blocks of a configured checkpoint are instead
+// verified by following parent hashes to the checkpoint.
+type ProofConfig struct {
+	Quorum             int    `yaml:"quorum"`             // number of upstream nodes which must agree on the block hash
+	CheckpointNumber   uint64 `yaml:"checkpointnumber"`   // block number of a trusted checkpoint
+	CheckpointHash     string `yaml:"checkpointhash"`     // block hash of a trusted checkpoint
+	CheckpointDistance uint64 `yaml:"checkpointdistance"` // blocks from the checkpoint verified by their parent hashes (default 128)
+
+	AllowSingleNode bool `yaml:"allowsinglenode"` // accept a block hash reported by a single node, which then vouches for its own state
+}
+
+// checkpointDistance returns the largest number of blocks between a read and the checkpoint for the
+// read to be verified against the checkpoint.
+func (c ProofConfig) checkpointDistance() uint64 {
+	if c.CheckpointDistance == 0 {
+		return defaultCheckpointDistance
+	}
+	return c.CheckpointDistance
+}
+
+// minQuorum returns the number of nodes which must at least agree on a block hash.
+func (c ProofConfig) minQuorum() int {
+	if c.AllowSingleNode {
+		return 1
+	}
+	return 2
+}
+
+// NodeConfig describes a single upstream execution client and the
+// capabilities it has been tagged with.
+type NodeConfig struct {
+	URL    string   `yaml:"url"` // may refer to environment variables as ${NAME}, e.g. for provider keys
+	Tags   []string `yaml:"tags"`
+	Weight int      `yaml:"weight"` // relative share of requests under the round-robin strategy (default 1)
+
+	RateLimit  float64 `yaml:"ratelimit"`  // requests per second, unlimited if zero
+	DailyQuota int64   `yaml:"dailyquota"` // requests per rolling 24 hours, unlimited if zero
+
+	Auth AuthConfig `yaml:"auth"` // request authentication, not used for IPC nodes
+	TLS  TLSConfig  `yaml:"tls"`  // TLS settings for https:// and wss:// nodes
+}
+
+// TLSConfig configures the TLS connections made to an upstream node: the CA bundle used to verify the
+// node (the system roots by default), a client certificate and key for mTLS gateways, the server name
+// expected by the node and the minimum TLS version. Certificate files are reloaded when they change.
+type TLSConfig struct {
+	CAFile     string `yaml:"cafile"`
+	CertFile   string `yaml:"certfile"`
+	KeyFile    string `yaml:"keyfile"`
+	ServerName string `yaml:"servername"`
+	MinVersion string `yaml:"minversion"` // 1.0, 1.1, 1.2 or 1.3 (default 1.2)
+}
+
+// AuthConfig configures how requests to an upstream node are authenticated. At most one of basic auth,
+// a bearer token and a JWT secret may be set. Header values, the password, the bearer token and the JWT
+// secret may be given inline or read from an environment variable or file as env:NAME or file:PATH.
+type AuthConfig struct {
+	Headers   map[string]string `yaml:"headers"`   // extra request headers
+	Username  string            `yaml:"username"`  // HTTP basic auth user
+	Password  string            `yaml:"password"`  // HTTP basic auth password
+	Bearer    string            `yaml:"bearer"`    // bearer token
+	JWTSecret string            `yaml:"jwtsecret"` // hex-encoded 32 byte secret signing engine API style HS256 tokens
 }
 
 // Sanitize will support a lazy user by ensuring that empty config file
@@ -32,4 +420,60 @@ func (c *Config) Sanitize() {
 	if c.LogFormat == "" {
 		c.LogFormat = defaultLogFormat
 	}
+	if c.Strategy == "" {
+		c.Strategy = StrategyEWMA
+	}
+	switch {
+	case c.HealthCheck.Disabled:
+		c.HealthCheck.Interval = 0
+	case c.HealthCheck.Interval == 0:
+		c.HealthCheck.Interval = defaultHealthCheckInterval
+	}
+	c.HealthCheck = c.HealthCheck.withDefaults()
+}
+
+// Validate reports settings which would leave the service unsafe to start.
+func (c *Config) Validate() error {
+	if c.Admin.Enabled && c.Admin.Token == "" {
+		return errors.New("admin api enabled without a token")
+	}
+	for _, chain := range append([]ChainConfig{c.DefaultChain()}, c.Chains...) {
+		for _, node := range chain.NodeConfigs() {
+			url, err := expandURL(node.URL)
+			if err != nil {
+				continue // reported when the node is dialled
+			}
+			if err := validateNodeURL(url); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// NodeConfigs returns the full list of upstream nodes. Untagged nodes from the
+// comma-separated urls field are listed first followed by the nodes block.
+func (c *Config) NodeConfigs() []NodeConfig {
+	return c.DefaultChain().NodeConfigs()
+}
+
+// DefaultChain returns the config block of the default chain, made of the top-level fields. It has no
+// name so that the ids of its nodes are not prefixed.
+func (c *Config) DefaultChain() ChainConfig {
+	return ChainConfig{
+		ChainID:     c.ChainID,
+		URLs:        c.URLs,
+		Nodes:       c.Nodes,
+		Strategy:    c.Strategy,
+		SimulateTxs: c.SimulateTxs,
+		Proof:       c.Proof,
+		Hedge:       c.Hedge,
+		QuorumReads: c.QuorumReads,
+		Broadcast:   c.Broadcast,
+		Head:        c.Head,
+		Retry:       c.Retry,
+		Coalesce:    c.Coalesce,
+		Cache:       c.Cache,
+		Reorg:       c.Reorg,
+	}
 }
@@ -2,16 +2,27 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"net/http"
+	neturl "net/url"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 )
 
 // SimpleEthClient exposes the eth_getBalance wrapper from the go-ethereum library
@@ -19,180 +30,772 @@ type SimpleEthClient interface {
 	ethereum.BlockNumberReader
 	ethereum.TransactionReader
 	ethereum.TransactionSender
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.GasEstimator
+	ethereum.PendingContractCaller
 	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) // queries eth balance at the specified block. If nil blockNumber is supplied the node will return the latest confirmed balance.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)                    // returns the block header at the specified height. If nil number is supplied the latest header is returned.
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
 }
 
-// NewEthClient wraps the connector to the given URL
+// DebugEthClient exposes the debug namespace tracing calls. Only nodes that
+// have been tagged with TagDebug are expected to serve them.
+type DebugEthClient interface {
+	TraceTransaction(ctx context.Context, txHash common.Hash, tracer string) (json.RawMessage, error) // replays the transaction using the named built-in tracer.
+}
+
+// Node tags recognised when routing requests. Nodes may carry other tags, which are reported by the
+// admin API but do not affect routing.
+const (
+	TagDebug    = "debug"     // the node exposes the debug namespace
+	TagArchive  = "archive"   // the node serves historical state
+	TagFull     = "full"      // the node serves only recent state
+	TagSendOnly = "send-only" // the node is used only for transaction submission
+	TagPrimary  = "primary"   // the node is preferred over fallback nodes (the default)
+	TagFallback = "fallback"  // the node is used only when no primary node is healthy
+)
+
+var (
+	// ErrDebugNotSupported is returned when no upstream node can serve debug namespace calls.
+	ErrDebugNotSupported = errors.New("no upstream node supports the debug namespace")
+
+	errNoNodes = errors.New("no upstream nodes available")
+)
+
+// NewEthClient wraps the connector to the given URL. HTTP(S) URLs are called a request at a time
+// while ws(s):// URLs, ipc:// URLs and absolute IPC socket paths hold a persistent connection which is
+// redialled if it drops. URLs with any other scheme are rejected.
 func NewEthClient(url string) (SimpleEthClient, error) {
-	return ethclient.Dial(url)
+	return NewEthClientFromConfig(NodeConfig{URL: url})
+}
+
+// NewEthClientFromConfig connects to the node described by cfg (see NewEthClient), expanding
+// environment variables in its URL and authenticating requests and setting up TLS as configured.
+// Dial errors have the URL redacted.
+func NewEthClientFromConfig(cfg NodeConfig) (SimpleEthClient, error) {
+	url, err := expandURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNodeURL(url); err != nil {
+		return nil, err
+	}
+	target, transport := dialURL(url), TransportOf(url)
+	var opts []rpc.ClientOption
+	var u *neturl.URL
+	if transport != TransportIPC {
+		if u, err = neturl.Parse(url); err != nil {
+			return nil, redactError(err, url)
+		}
+		if opts, err = cfg.Auth.clientOptions(); err != nil {
+			return nil, err
+		}
+	}
+	switch transport {
+	case TransportHTTP:
+		base, err := cfg.TLS.httpTransport(u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		anonymous := *u
+		anonymous.User = nil // credentials are added by the transport
+		target = RedactURL(anonymous.String())
+		opts = append(opts, rpc.WithHTTPClient(&http.Client{Transport: &urlTransport{url: u, base: base}}))
+	case TransportWS:
+		dialer, ok, err := cfg.TLS.websocketDialer(u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			opts = append(opts, rpc.WithWebsocketDialer(dialer))
+		}
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+	c, err := rpc.DialOptions(ctx, target, opts...)
+	if err != nil {
+		return nil, redactError(err, url)
+	}
+	return NewEthClientFromRPC(c), nil
+}
+
+// NewEthClientFromRPC wraps an existing RPC connection.
+func NewEthClientFromRPC(c *rpc.Client) SimpleEthClient {
+	return &ethClient{Client: ethclient.NewClient(c), geth: gethclient.New(c), rpc: c}
+}
+
+var (
+	_ SimpleEthClient = (*ethClient)(nil)
+	_ DebugEthClient  = (*ethClient)(nil)
+	_ ProofEthClient  = (*ethClient)(nil)
+	_ TxPoolEthClient = (*ethClient)(nil)
+)
+
+// ethClient extends the go-ethereum client with calls that are not covered
+// by the ethclient library.
+type ethClient struct {
+	*ethclient.Client
+	geth *gethclient.Client
+	rpc  *rpc.Client
+}
+
+// GetProof returns the account and storage values of the specified account including the Merkle-proof.
+func (e *ethClient) GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	res, err := e.geth.GetProof(ctx, account, keys, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// TraceTransaction executes debug_traceTransaction with the supplied tracer.
+func (e *ethClient) TraceTransaction(ctx context.Context, txHash common.Hash, tracer string) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := e.rpc.CallContext(ctx, &result, "debug_traceTransaction", txHash, map[string]any{"tracer": tracer}); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-var _ SimpleEthClient = (*multiNodeClient)(nil)
+// TxPoolContentFrom executes txpool_contentFrom for the given sender.
+func (e *ethClient) TxPoolContentFrom(ctx context.Context, account common.Address) (*TxPoolContent, error) {
+	var result map[string]map[string]*types.Transaction
+	if err := e.rpc.CallContext(ctx, &result, "txpool_contentFrom", account); err != nil {
+		return nil, err
+	}
+	return newTxPoolContent(result)
+}
+
+var (
+	_ SimpleEthClient    = (*multiNodeClient)(nil)
+	_ DebugEthClient     = (*multiNodeClient)(nil)
+	_ ProofEthClient     = (*multiNodeClient)(nil)
+	_ TxPoolEthClient    = (*multiNodeClient)(nil)
+	_ headerQuorumReader = (*multiNodeClient)(nil)
+	_ chainTipChecker    = (*multiNodeClient)(nil)
+	_ quorumNodeSet      = (*multiNodeClient)(nil)
+	_ txBroadcaster      = (*multiNodeClient)(nil)
+	_ NodeAdmin          = (*multiNodeClient)(nil)
+)
 
 // Multi nodes
 
+// routingState is an immutable snapshot of the nodes and routing settings of a multiNodeClient. Each
+// change of node or setting publishes a new snapshot so that requests are routed without taking a lock.
+type routingState struct {
+	nodes     []*item // in config order, never modified once published
+	selector  selector
+	hedge     HedgeConfig
+	head      HeadConfig
+	retry     RetryConfig
+	broadcast BroadcastConfig
+	coalesce  CoalesceConfig
+	cache     *responseCache // nil if caching is disabled
+	cacheCfg  CacheConfig
+	reorg     *reorgTracker // nil if reorg detection is disabled
+}
+
 type multiNodeClient struct {
-	nodes []*item
-	mu    sync.RWMutex
+	state  atomic.Pointer[routingState]
+	flight singleflight.Group // identical reads in flight
+
+	mu            sync.Mutex // serialises routing state updates and guards the fields below
+	logger        *logrus.Entry
+	prober        *prober
+	rebroadcaster *rebroadcaster
+	disconnected  []*disconnected // nodes waiting to be redialled
+
+	dial      func(cfg NodeConfig) (SimpleEthClient, error) // connects disconnected nodes and nodes added at runtime
+	nextID    int                                           // id of the next node to be added
+	healthCfg HealthCheckConfig                             // breaker thresholds applied to added nodes
+	chainID   *big.Int                                      // chain ID every node must report, if set
+
+	chain    string // name of the chain, reported as the chain label of the chain's metrics
+	idPrefix string // prefix of the node ids, set for the nodes of named chains
 }
 
 // item is used to track the ordering of multiple eth RPC clients.
 type item struct {
-	id     string // id is the position on the config url string
-	client SimpleEthClient
+	id       string // id is the position in the node config (or the order in which the node was added)
+	url      string
+	client   SimpleEthClient
+	tags     map[string]bool
+	weight   int // relative share of requests under the round-robin strategy
+	stats    *nodeStats
+	breaker  *circuitBreaker
+	probe    atomic.Pointer[probeResult] // most recent background probe result
+	head     atomic.Uint64               // latest block number reported by the node
+	budget   *nodeBudget                 // request rate limit and daily quota
+	draining atomic.Bool                 // set once the node should receive no new requests
+	connLost atomic.Bool                 // set while keepalive pings of a persistent connection fail
+	noTxPool atomic.Int64                // time (in unix nanoseconds) until which the node is not asked for txpool_contentFrom after reporting it unsupported
+}
+
+// hasTag reports whether the node was configured with the given tag.
+func (i *item) hasTag(tag string) bool {
+	return i.tags[tag]
 }
 
 // NewMultiNodeClient connects to a comma-separated list of ethereum clients and stores them in an ordered
-// list where they can be prioritized bad on the logic implemented in multiNodeCall.
+// list. Requests are routed to the nodes by the EWMA latency selection strategy unless another strategy
+// is set with SetSelectionStrategy.
 func NewMultiNodeClient(possibleUrls string, constructor func(url string) (SimpleEthClient, error)) (*multiNodeClient, error) {
-	urls := strings.Split(possibleUrls, ",")
+	var nodes []NodeConfig
+	for _, url := range strings.Split(possibleUrls, ",") {
+		nodes = append(nodes, NodeConfig{URL: url})
+	}
+	return NewMultiNodeClientFromNodes(nodes, constructor)
+}
+
+// NewMultiNodeClientFromNodes connects to each configured node and stores them in an ordered list. Node
+// tags are retained so that calls requiring a particular capability are only routed to suitable nodes.
+// Nodes which cannot be connected are kept in a disconnected state and redialled in the background once
+// health checks are started. An error is returned if no node can be connected. The constructor is
+// retained to redial nodes and to dial nodes added at runtime with AddNode.
+func NewMultiNodeClientFromNodes(cfgs []NodeConfig, constructor func(url string) (SimpleEthClient, error)) (*multiNodeClient, error) {
+	return NewMultiNodeClientWithDialer(cfgs, func(cfg NodeConfig) (SimpleEthClient, error) { return constructor(cfg.URL) })
+}
+
+// NewMultiNodeClientWithDialer is NewMultiNodeClientFromNodes for a constructor which is given the whole
+// node config, so that settings such as authentication are applied when a node is dialled.
+func NewMultiNodeClientWithDialer(cfgs []NodeConfig, dial func(cfg NodeConfig) (SimpleEthClient, error)) (*multiNodeClient, error) {
+	return newMultiNodeClient("", cfgs, dial)
+}
+
+// newMultiNodeClient is NewMultiNodeClientWithDialer for the nodes of the named chain. The ids of the
+// nodes of a named chain start with its name.
+func newMultiNodeClient(chain string, cfgs []NodeConfig, dial func(cfg NodeConfig) (SimpleEthClient, error)) (*multiNodeClient, error) {
+	idPrefix := ""
+	if chain != "" {
+		idPrefix = chain + "-"
+	}
 	var nodes []*item
-	errs := make(map[string]error)
-	for i := 0; i < len(urls); i++ {
-		url := urls[i]
-		var node SimpleEthClient
-		n, err := constructor(url)
-		node = n
+	var pending []*disconnected
+	var errs []string
+	for i := 0; i < len(cfgs); i++ {
+		id := fmt.Sprintf("%s%d", idPrefix, i)
+		node, err := dial(cfgs[i])
 		if err != nil {
-			errs[url] = err
+			err = redactError(err, cfgs[i].URL)
+			errs = append(errs, fmt.Sprintf("url='%s' err='%s'", RedactURL(cfgs[i].URL), err.Error()))
+			pending = append(pending, &disconnected{id: id, cfg: cfgs[i], err: err})
 			continue
 		}
-		nodes = append(nodes, &item{
-			id:     fmt.Sprintf("%d", len(nodes)),
-			client: node,
-		})
+		nodes = append(nodes, newItem(id, cfgs[i], node, HealthCheckConfig{}))
 	}
 	if len(nodes) == 0 {
-		message := "cannot connect to any nodes"
-		for url, err := range errs {
-			message = fmt.Sprintf("%s url='%s' err='%s'", message, url, err.Error())
-		}
-		return nil, errors.New(message)
+		return nil, errors.New(strings.Join(append([]string{"cannot connect to any nodes"}, errs...), " "))
+	}
+	m := &multiNodeClient{disconnected: pending, dial: dial, nextID: len(cfgs), chain: chain, idPrefix: idPrefix}
+	m.state.Store(&routingState{nodes: nodes})
+	if err := m.SetSelectionStrategy(StrategyEWMA); err != nil {
+		return nil, err
 	}
-	return &multiNodeClient{
-		nodes: nodes,
-	}, nil
+	return m, nil
 }
 
-// increaseNodePriority bumps a client up one place in the slice.
-func (m *multiNodeClient) increaseNodePriority(position int, id string) {
-	if position == 0 {
-		return
+// newItem returns the routing entry for a connected node.
+func newItem(id string, cfg NodeConfig, client SimpleEthClient, healthCfg HealthCheckConfig) *item {
+	tags := make(map[string]bool)
+	for _, tag := range cfg.Tags {
+		tags[tag] = true
 	}
+	return &item{
+		id:      id,
+		url:     cfg.URL,
+		client:  client,
+		tags:    tags,
+		weight:  max(cfg.Weight, 1),
+		stats:   &nodeStats{},
+		breaker: newCircuitBreaker(healthCfg),
+		budget:  newNodeBudget(id, cfg),
+	}
+}
+
+// SetSelectionStrategy sets the strategy used to choose the node serving each request (one of
+// StrategyEWMA, StrategyRoundRobin, StrategyLeastOutstanding or StrategyPriority). The selection
+// strategy gauge reports the strategy of each chain.
+func (m *multiNodeClient) SetSelectionStrategy(strategy string) error {
+	sel, err := newSelector(strategy)
+	if err != nil {
+		return err
+	}
+	m.update(func(st *routingState) { st.selector = sel })
+	selectionStrategyGauge.DeletePartialMatch(prometheus.Labels{"chain": m.chain})
+	selectionStrategyGauge.WithLabelValues(m.chain, sel.name()).Set(1)
+	return nil
+}
+
+// routing returns the current routing state.
+func (m *multiNodeClient) routing() *routingState {
+	return m.state.Load()
+}
+
+// update publishes a copy of the routing state modified by fn. Updates are serialised so that
+// concurrent updates are not lost. The node list must be replaced, not modified, by fn.
+func (m *multiNodeClient) update(fn func(st *routingState)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.nodes[position].id != id {
-		return
-	}
-	m.nodes[position-1], m.nodes[position] = m.nodes[position], m.nodes[position-1]
-}
-
-// BalanceAt prepares a balance query to all nodes in the multiNodeClient set.
-func (m *multiNodeClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (bal *big.Int, err error) {
-	for i := 0; i < len(m.nodes); i++ {
-		index := i
-		m.mu.RLock()
-		node := m.nodes[index]
-		bal, err = node.client.BalanceAt(ctx, account, blockNumber)
-		m.mu.RUnlock()
-		if err == nil {
-			m.increaseNodePriority(i, node.id)
+	m.updateLocked(fn)
+}
+
+// updateLocked is update for callers which already hold m.mu. Selector state kept for nodes which are
+// no longer in the node list is dropped.
+func (m *multiNodeClient) updateLocked(fn func(st *routingState)) {
+	st := *m.state.Load()
+	fn(&st)
+	if sel, ok := st.selector.(nodeStateSelector); ok {
+		sel.retain(st.nodes)
+	}
+	m.state.Store(&st)
+}
+
+// snapshot returns the node list in config order. The list is shared and must not be modified.
+func (m *multiNodeClient) snapshot() []*item {
+	return m.routing().nodes
+}
+
+// nodeResult holds the outcome of a call made to a single upstream node.
+type nodeResult[T any] struct {
+	node *item
+	val  T
+	err  error
+}
+
+// fanOut calls fn on every supplied node concurrently and returns the results in node order. Each call
+// counts as one request against the node budget and is tracked as in flight, so that a node being
+// removed is not closed while it is answering.
+func fanOut[T any](ctx context.Context, nodes []*item, fn func(context.Context, SimpleEthClient) (T, error)) []nodeResult[T] {
+	results := make([]nodeResult[T], len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(index int, node *item) {
+			defer wg.Done()
+			node.stats.outstanding.Add(1)
+			defer node.stats.outstanding.Add(-1)
+			node.budget.record(1)
+			val, err := fn(ctx, node.client)
+			results[index] = nodeResult[T]{node: node, val: val, err: err}
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
+
+// rotation returns the capable nodes in the order chosen by the selection strategy. Nodes near their
+// rate limit or quota are tried after the other nodes, and nodes whose circuit breaker is open are left
+// out of rotation unless no other capable node is available. Fallback nodes are used only if no primary
+// node is available. Draining and send-only nodes are never returned. A nil capable func accepts every
+// node.
+func (m *multiNodeClient) rotation(capable func(*item) bool) []*item {
+	return m.order(m.snapshot(), func(node *item) bool {
+		return !node.hasTag(TagSendOnly) && (capable == nil || capable(node))
+	})
+}
+
+// order returns the nodes accepted by include in rotation order (see rotation).
+func (m *multiNodeClient) order(nodes []*item, include func(*item) bool) []*item {
+	sel := m.routing().selector
+	var primary, fallback []*item
+	for _, node := range nodes {
+		switch {
+		case node.draining.Load() || !include(node):
+		case node.hasTag(TagFallback):
+			fallback = append(fallback, node)
+		default:
+			primary = append(primary, node)
+		}
+	}
+	if nodes := tier(sel, primary); len(nodes) > 0 {
+		return nodes
+	}
+	if nodes := tier(sel, fallback); len(nodes) > 0 {
+		return nodes
+	}
+	return sel.order(append(primary, fallback...))
+}
+
+// picked records that a request has been routed to nodes[0], chosen from nodes, with a selector that
+// keeps per-node state (see nodeStateSelector).
+func (m *multiNodeClient) picked(nodes []*item) {
+	if sel, ok := m.routing().selector.(nodeStateSelector); ok {
+		sel.picked(nodes)
+	}
+}
+
+// tier returns the available nodes in rotation order, those near their budget last. Nil is returned
+// if the circuit breaker of every node is open.
+func tier(sel selector, nodes []*item) []*item {
+	var available, limited []*item
+	for _, node := range nodes {
+		switch {
+		case !node.breaker.available():
+		case node.budget.nearLimit():
+			limited = append(limited, node)
+		default:
+			available = append(available, node)
+		}
+	}
+	if len(available)+len(limited) == 0 {
+		return nil
+	}
+	if len(limited) == 0 {
+		return sel.order(available)
+	}
+	return append(sel.order(available), sel.order(limited)...)
+}
+
+// callNodes calls fn on the capable nodes in read rotation until one succeeds, recording the outcome
+// in the node statistics and circuit breakers. Attempts follow the retry policy: retryable errors move
+// on to the next node (wrapping round if more attempts than nodes are allowed) after a backoff, while
+// terminal errors are returned immediately. If no node is capable of serving the request unsupported
+// is returned.
+func callNodes[T any](ctx context.Context, m *multiNodeClient, capable func(*item) bool, unsupported error, fn func(context.Context, *item) (T, error)) (val T, err error) {
+	nodes, err := m.readRotation(ctx, capable)
+	if err != nil {
+		return val, err
+	}
+	return callEach(ctx, m, nodes, unsupported, fn)
+}
+
+// callEach calls fn on the supplied nodes in order under the retry policy (see callNodes), bounding
+// each attempt as described by RetryConfig.attemptContext.
+func callEach[T any](ctx context.Context, m *multiNodeClient, nodes []*item, unsupported error, fn func(context.Context, *item) (T, error)) (T, error) {
+	return retryEach(ctx, m, nodes, unsupported, true, fn)
+}
+
+// retryEach is callEach with the per-attempt timeout applied only if attemptTimeout is set. Calls
+// whose result is still being read once fn returns, such as streamed HTTP responses, must not be
+// bounded by it.
+func retryEach[T any](ctx context.Context, m *multiNodeClient, nodes []*item, unsupported error, attemptTimeout bool, fn func(context.Context, *item) (T, error)) (val T, err error) {
+	if len(nodes) == 0 {
+		return val, unsupported
+	}
+	m.picked(nodes)
+	policy := m.routing().retry
+	attempts := policy.attempts(len(nodes))
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := policy.wait(ctx, attempt); waitErr != nil {
+				return val, err
+			}
+		}
+		node := nodes[attempt%len(nodes)]
+		if attemptTimeout {
+			val, err = callAttempt(ctx, m, policy, attempts-attempt, node, fn)
+		} else {
+			val, err = callNode(ctx, m, node, fn)
+		}
+		if !isRetryable(err) || ctx.Err() != nil {
 			break
 		}
 	}
 	return
 }
 
-const blockDiff = 3 // criteria for reporting failure based on two connected clients reporting different block numbers
+// callNode makes a single request to node, tracking in-flight requests, latency and the node budget.
+func callNode[T any](ctx context.Context, m *multiNodeClient, node *item, fn func(context.Context, *item) (T, error)) (T, error) {
+	node.stats.outstanding.Add(1)
+	node.budget.record(1)
+	start := time.Now()
+	val, err := fn(ctx, node)
+	latency := time.Since(start)
+	node.stats.outstanding.Add(-1)
+	if isRateLimited(err) {
+		node.budget.saturate()
+	}
 
-func absDiff(a, b uint64) uint64 {
-	if a > b {
-		return a - b
+	failed := isNodeFailure(err)
+	result := "success"
+	switch {
+	case errors.Is(err, context.Canceled):
+		result = "cancelled" // abandoned by the caller (e.g. a hedged request that lost the race)
+	case failed:
+		result = "error"
+		nodeLatencyGauge.WithLabelValues(node.id).Set(node.stats.penalize().Seconds())
+	default:
+		if err != nil {
+			result = "error"
+		}
+		nodeLatencyGauge.WithLabelValues(node.id).Set(node.stats.observe(latency).Seconds())
 	}
-	return b - a
+
+	nodeRequestsCounter.WithLabelValues(node.id, m.routing().selector.name(), result).Inc()
+
+	if err == nil || failed {
+		m.report(node, err)
+	}
+	return val, err
+}
+
+// BalanceAt prepares a balance query to all nodes in the multiNodeClient set. Historical reads are
+// routed to archive nodes.
+func (m *multiNodeClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return cachedRead(ctx, m, "eth_getBalance", account.Hex()+","+blockKey(blockNumber), atBlock[*big.Int](blockNumber), func(ctx context.Context) (*big.Int, error) {
+		return hedgedCall(ctx, m, m.stateCapable(blockNumber), ErrArchiveNotSupported, func(ctx context.Context, node *item) (*big.Int, error) {
+			return node.client.BalanceAt(ctx, account, blockNumber)
+		})
+	})
 }
 
-// BlockNumber is used as part of the liveness probe for multiNodeClient and will return
-// and error if the connected Ethereum nodes report block heights with disparity grater
-// then the blockDiff limit.
+const blockDiff = 3 // default number of blocks a node may lag the chain head before the readiness probe fails
+
+// BlockNumber returns the latest block number reported by the first node to answer.
 func (m *multiNodeClient) BlockNumber(ctx context.Context) (uint64, error) {
-	var blockheights []uint64
+	return coalesce(ctx, m, "eth_blockNumber", "", func(ctx context.Context) (uint64, error) {
+		return hedgedCall(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (uint64, error) {
+			n, err := node.client.BlockNumber(ctx)
+			if err == nil {
+				node.head.Store(n)
+			}
+			return n, err
+		})
+	})
+}
+
+// checkChainTips is used as part of the readiness probe for multiNodeClient and will return
+// an error for each connected Ethereum node reporting a block height more than the configured
+// head lag (blockDiff by default) behind the highest reported height. Nodes whose circuit
+// breaker is open are not checked.
+func (m *multiNodeClient) checkChainTips(ctx context.Context) error {
+	maxLag := m.routing().head.MaxLag
+	if maxLag == 0 {
+		maxLag = blockDiff
+	}
+
+	var checked []*item
+	var best uint64
 	var errStr string
-	for i := 0; i < len(m.nodes); i++ {
-		index := i
-		m.mu.RLock()
-		node := m.nodes[index]
-		b, err := node.client.BlockNumber(ctx)
+	for _, node := range m.rotation(nil) {
+		b, err := callNode(ctx, m, node, func(ctx context.Context, node *item) (uint64, error) {
+			return node.client.BlockNumber(ctx)
+		})
 		if err != nil {
-			errStr += fmt.Sprintf("node %d err: %s|", index, err.Error())
-			m.mu.RUnlock()
+			errStr += fmt.Sprintf("node %s err: %s|", node.id, err.Error())
 			continue
 		}
-		blockheights = append(blockheights, b)
-		if len(blockheights) > 1 {
-			if f, s := blockheights[len(blockheights)-1], blockheights[len(blockheights)-2]; absDiff(f, s) > blockDiff {
-				errStr += fmt.Sprintf("nodes %d (height=%d) and %d (height=%d) are reporting different chain tips|", index, f, index-1, s)
-			}
+		node.head.Store(b)
+		checked, best = append(checked, node), max(best, b)
+	}
+	for _, node := range checked {
+		if h := node.head.Load(); best-h > maxLag {
+			errStr += fmt.Sprintf("node %s (height=%d) is %d blocks behind the chain head (height=%d)|", node.id, h, best-h, best)
 		}
-		m.mu.RUnlock()
 	}
 	if errStr != "" {
-		return 0, errors.New(errStr)
+		return errors.New(errStr)
 	}
-	return blockheights[0], nil
+	return nil
+}
+
+// txByHashResult holds the results of a TransactionByHash call.
+type txByHashResult struct {
+	tx        *types.Transaction
+	isPending bool
 }
 
 // TransactionByHash checks the pool of pending transactions in addition to the
 // blockchain. The isPending return value indicates whether the transaction has been
 // mined yet. Note that the transaction may not be part of the canonical chain even if
 // it's not pending.
-func (m *multiNodeClient) TransactionByHash(ctx context.Context, txHash common.Hash) (tx *types.Transaction, isPending bool, err error) {
-	for i := 0; i < len(m.nodes); i++ {
-		index := i
-		m.mu.RLock()
-		node := m.nodes[index]
-		tx, isPending, err = node.client.TransactionByHash(ctx, txHash)
-		m.mu.RUnlock()
-		if err == nil {
-			m.increaseNodePriority(i, node.id)
-			break
+func (m *multiNodeClient) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	scope := func(c *responseCache, res txByHashResult) cacheScope {
+		if !res.isPending && c.isFinal("eth_getTransactionReceipt("+txHash.Hex()+")") {
+			return scopeFinal
 		}
+		return scopeHead
 	}
-	return
+	res, err := cachedRead(ctx, m, "eth_getTransactionByHash", txHash.Hex(), scope, func(ctx context.Context) (txByHashResult, error) {
+		return hedgedCall(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (txByHashResult, error) {
+			tx, isPending, err := node.client.TransactionByHash(ctx, txHash)
+			return txByHashResult{tx: tx, isPending: isPending}, err
+		})
+	})
+	return res.tx, res.isPending, err
 }
 
 // TransactionReceipt returns the receipt of a mined transaction. Note that the
 // transaction may not be included in the current canonical chain even if a receipt
 // exists.
-func (m *multiNodeClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
-	for i := 0; i < len(m.nodes); i++ {
-		index := i
-		m.mu.RLock()
-		node := m.nodes[index]
-		receipt, err = node.client.TransactionReceipt(ctx, txHash)
-		m.mu.RUnlock()
-		if err == nil {
-			m.increaseNodePriority(i, node.id)
-			break
+func (m *multiNodeClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	scope := func(c *responseCache, receipt *types.Receipt) cacheScope {
+		if receipt == nil {
+			return scopeNone
 		}
+		return c.blockScope(receipt.BlockNumber)
 	}
-	return
+	return cachedRead(ctx, m, "eth_getTransactionReceipt", txHash.Hex(), scope, func(ctx context.Context) (*types.Receipt, error) {
+		return hedgedCall(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (*types.Receipt, error) {
+			return node.client.TransactionReceipt(ctx, txHash)
+		})
+	})
 }
 
 // SendTransaction method injects a signed transaction into the pending transaction pool for execution. If the transaction
 // was a contract creation, the TransactionReceipt method can be used to retrieve the
 // contract address after the transaction has been mined.
-func (m *multiNodeClient) SendTransaction(ctx context.Context, tx *types.Transaction) (err error) {
-	for i := 0; i < len(m.nodes); i++ {
-		index := i
-		m.mu.RLock()
-		node := m.nodes[index]
-		err = node.client.SendTransaction(ctx, tx)
-		m.mu.RUnlock()
-		if err == nil {
-			m.increaseNodePriority(i, node.id)
-			break
+func (m *multiNodeClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := m.BroadcastTransaction(ctx, tx)
+	return err
+}
+
+// TraceTransaction replays a transaction on the first debug-tagged node able to serve the request.
+func (m *multiNodeClient) TraceTransaction(ctx context.Context, txHash common.Hash, tracer string) (json.RawMessage, error) {
+	isDebugNode := func(node *item) bool {
+		_, ok := node.client.(DebugEthClient)
+		return ok && node.hasTag(TagDebug)
+	}
+	return callNodes(ctx, m, isDebugNode, ErrDebugNotSupported, func(ctx context.Context, node *item) (json.RawMessage, error) {
+		return node.client.(DebugEthClient).TraceTransaction(ctx, txHash, tracer)
+	})
+}
+
+// HeaderByNumber returns a block header from the first node able to serve the request.
+func (m *multiNodeClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return cachedRead(ctx, m, "eth_getBlockByNumber", blockKey(number), atBlock[*types.Header](number), func(ctx context.Context) (*types.Header, error) {
+		return callNodes(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (*types.Header, error) {
+			return node.client.HeaderByNumber(ctx, number)
+		})
+	})
+}
+
+// SuggestGasPrice retrieves the currently suggested legacy gas price.
+func (m *multiNodeClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return cachedRead(ctx, m, "eth_gasPrice", "", atBlock[*big.Int](nil), func(ctx context.Context) (*big.Int, error) {
+		return callNodes(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (*big.Int, error) {
+			return node.client.SuggestGasPrice(ctx)
+		})
+	})
+}
+
+// SuggestGasTipCap retrieves the currently suggested EIP-1559 priority fee.
+func (m *multiNodeClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return cachedRead(ctx, m, "eth_maxPriorityFeePerGas", "", atBlock[*big.Int](nil), func(ctx context.Context) (*big.Int, error) {
+		return callNodes(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (*big.Int, error) {
+			return node.client.SuggestGasTipCap(ctx)
+		})
+	})
+}
+
+// PendingCallContract executes a message call against the pending state. Execution errors such as
+// reverts are returned by the first node that responds.
+func (m *multiNodeClient) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	return callNodes(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) ([]byte, error) {
+		return node.client.PendingCallContract(ctx, msg)
+	})
+}
+
+// EstimateGas estimates the gas needed to execute the supplied message call.
+func (m *multiNodeClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return callNodes(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (uint64, error) {
+		return node.client.EstimateGas(ctx, msg)
+	})
+}
+
+// NonceAt returns the account nonce from the first node able to serve the request. Historical reads
+// are routed to archive nodes.
+func (m *multiNodeClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return cachedRead(ctx, m, "eth_getTransactionCount", account.Hex()+","+blockKey(blockNumber), atBlock[uint64](blockNumber), func(ctx context.Context) (uint64, error) {
+		return callNodes(ctx, m, m.stateCapable(blockNumber), ErrArchiveNotSupported, func(ctx context.Context, node *item) (uint64, error) {
+			return node.client.NonceAt(ctx, account, blockNumber)
+		})
+	})
+}
+
+// StorageAt returns the value of an account storage slot from the first node able to serve the request.
+// Historical reads are routed to archive nodes.
+func (m *multiNodeClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return cachedRead(ctx, m, "eth_getStorageAt", account.Hex()+","+key.Hex()+","+blockKey(blockNumber), atBlock[[]byte](blockNumber), func(ctx context.Context) ([]byte, error) {
+		return callNodes(ctx, m, m.stateCapable(blockNumber), ErrArchiveNotSupported, func(ctx context.Context, node *item) ([]byte, error) {
+			return node.client.StorageAt(ctx, account, key, blockNumber)
+		})
+	})
+}
+
+// GetProof requests an account proof from the first node that supports eth_getProof. The proof
+// is untrusted and must be verified by the caller.
+func (m *multiNodeClient) GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	stateNode := m.stateCapable(blockNumber)
+	isProofNode := func(node *item) bool {
+		_, ok := node.client.(ProofEthClient)
+		return ok && (stateNode == nil || stateNode(node))
+	}
+	return callNodes(ctx, m, isProofNode, ErrProofNotSupported, func(ctx context.Context, node *item) (*gethclient.AccountResult, error) {
+		return node.client.(ProofEthClient).GetProof(ctx, account, keys, blockNumber)
+	})
+}
+
+// TxPoolContentFrom returns the pool contents for a sender from the first node that serves the
+// txpool namespace. A node answering that it does not serve txpool_contentFrom is skipped for
+// txPoolRetryInterval, or until its persistent connection is restored, and the request moves on to
+// the remaining nodes.
+func (m *multiNodeClient) TxPoolContentFrom(ctx context.Context, account common.Address) (*TxPoolContent, error) {
+	isTxPoolNode := func(node *item) bool {
+		_, ok := node.client.(TxPoolEthClient)
+		return ok && time.Now().UnixNano() >= node.noTxPool.Load()
+	}
+	for {
+		content, err := callNodes(ctx, m, isTxPoolNode, ErrTxPoolNotSupported, func(ctx context.Context, node *item) (*TxPoolContent, error) {
+			content, err := node.client.(TxPoolEthClient).TxPoolContentFrom(ctx, account)
+			if isMethodUnsupported(err) {
+				node.noTxPool.Store(time.Now().Add(txPoolRetryInterval).UnixNano())
+			}
+			return content, err
+		})
+		if !isMethodUnsupported(err) || ctx.Err() != nil {
+			return content, err
 		}
 	}
-	return
+}
+
+// headerQuorum queries every node in read rotation (see readRotation) for the header at the given
+// height and returns it only if at least quorum nodes report the same block hash. If no height is supplied the highest block
+// known to at least quorum nodes is used, which must not be below the minimum block carried by ctx.
+// A non-positive quorum requires a simple majority of at least minQuorum nodes.
+func (m *multiNodeClient) headerQuorum(ctx context.Context, number *big.Int, quorum, minQuorum int) (*types.Header, error) {
+	nodes, err := m.readRotation(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if quorum <= 0 {
+		quorum = max(len(nodes)/2+1, minQuorum)
+	}
+	if quorum > len(nodes) {
+		return nil, fmt.Errorf("%w: quorum %d exceeds node count %d", errNoQuorum, quorum, len(nodes))
+	}
+	if number == nil {
+		var heights []uint64
+		for _, res := range fanOut(ctx, nodes, func(ctx context.Context, c SimpleEthClient) (uint64, error) { return c.BlockNumber(ctx) }) {
+			if res.err == nil {
+				heights = append(heights, res.val)
+			}
+		}
+		if len(heights) < quorum {
+			return nil, fmt.Errorf("%w: %d of %d nodes reported a block height", errNoQuorum, len(heights), quorum)
+		}
+		sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+		if minBlock := minBlockFrom(ctx); heights[quorum-1] < minBlock {
+			return nil, fmt.Errorf("%w %d: quorum of %d nodes only reached block %d", ErrMinBlockNotReached, minBlock, quorum, heights[quorum-1])
+		}
+		number = new(big.Int).SetUint64(heights[quorum-1])
+	}
+	votes := make(map[common.Hash]int)
+	headers := make(map[common.Hash]*types.Header)
+	for _, res := range fanOut(ctx, nodes, func(ctx context.Context, c SimpleEthClient) (*types.Header, error) {
+		return c.HeaderByNumber(ctx, number)
+	}) {
+		if res.err != nil || res.val == nil {
+			continue
+		}
+		hash := res.val.Hash()
+		votes[hash]++
+		headers[hash] = res.val
+		if votes[hash] >= quorum {
+			return headers[hash], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: block %d, %d distinct hashes reported, quorum %d", errNoQuorum, number, len(votes), quorum)
 }
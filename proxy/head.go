@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// MinBlockKey is the optional query parameter restricting a read to upstream nodes which have seen
+// at least the given block (read-your-writes).
+const MinBlockKey = "minblock"
+
+// ErrMinBlockNotReached is returned when no upstream node has reached the minimum block requested
+// by the caller.
+var ErrMinBlockNotReached = errors.New("no upstream node has reached the minimum block")
+
+// ErrNodesLagging is returned when every upstream node able to serve a read lags the best-known head
+// by more than the configured limit.
+var ErrNodesLagging = errors.New("every upstream node lags the best-known head")
+
+type minBlockCtxKey struct{}
+
+// WithMinBlock returns a copy of ctx which restricts reads made by a multiNodeClient to nodes whose
+// head is at or above number.
+func WithMinBlock(ctx context.Context, number uint64) context.Context {
+	return context.WithValue(ctx, minBlockCtxKey{}, number)
+}
+
+// minBlockFrom returns the minimum block set with WithMinBlock, or zero if none was set.
+func minBlockFrom(ctx context.Context) uint64 {
+	number, _ := ctx.Value(minBlockCtxKey{}).(uint64)
+	return number
+}
+
+// withMinBlockParam applies the minblock query parameter of r (if any) to ctx.
+func withMinBlockParam(ctx context.Context, r *http.Request) (context.Context, error) {
+	b := r.URL.Query().Get(MinBlockKey)
+	if b == "" {
+		return ctx, nil
+	}
+	n, err := strconv.ParseUint(b, 10, 64)
+	if err != nil {
+		return ctx, fmt.Errorf("invalid minimum block number '%s'", b)
+	}
+	return WithMinBlock(ctx, n), nil
+}
+
+// ethClientErrorCode maps errors returned by reads routed through the eth client to an HTTP response code.
+func ethClientErrorCode(err error) int {
+	if errors.Is(err, ErrMinBlockNotReached) || errors.Is(err, ErrNodesLagging) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// SetHeadConfig sets the number of blocks a node may lag the best-known head before it is excluded
+// from read routing. Head-aware routing is disabled if cfg.MaxLag is zero.
+func (m *multiNodeClient) SetHeadConfig(cfg HeadConfig) {
+	m.update(func(st *routingState) { st.head = cfg })
+}
+
+// bestHead returns the head agreed by the nodes in rotation which have reported one (see agreedHead).
+// Draining nodes and nodes whose circuit breaker is open are not counted.
+func (m *multiNodeClient) bestHead() uint64 {
+	var heads []uint64
+	for _, node := range m.rotation(nil) {
+		if head := node.head.Load(); head > 0 {
+			heads = append(heads, head)
+		}
+	}
+	return agreedHead(heads)
+}
+
+// refreshHeads asks each of the supplied nodes for its latest block number.
+func (m *multiNodeClient) refreshHeads(ctx context.Context, nodes []*item) {
+	for _, res := range fanOut(ctx, nodes, func(ctx context.Context, c SimpleEthClient) (uint64, error) { return c.BlockNumber(ctx) }) {
+		if res.err == nil {
+			res.node.head.Store(res.val)
+		}
+	}
+}
+
+// readRotation returns the capable nodes in rotation order for a read. Nodes which have not reached
+// the minimum block carried by ctx are left out, refreshing the node heads first if no node appears to
+// have reached it. Nodes lagging the best-known head by more than the configured limit are left out as
+// well, and ErrNodesLagging is returned if no other node is left. Nodes which have not reported a head
+// are not treated as lagging.
+func (m *multiNodeClient) readRotation(ctx context.Context, capable func(*item) bool) ([]*item, error) {
+	nodes := m.rotation(capable)
+	maxLag := m.routing().head.MaxLag
+	minBlock := minBlockFrom(ctx)
+	if maxLag == 0 && minBlock == 0 {
+		return nodes, nil
+	}
+
+	reached := func(node *item) bool { return node.head.Load() >= minBlock }
+	if minBlock > 0 && !anyNode(nodes, reached) {
+		m.refreshHeads(ctx, nodes)
+	}
+
+	best := m.bestHead()
+	var current []*item
+	lagging := 0
+	for _, node := range nodes {
+		head := node.head.Load()
+		switch {
+		case !reached(node):
+		case maxLag > 0 && head > 0 && head+maxLag < best:
+			lagging++
+		default:
+			current = append(current, node)
+		}
+	}
+	switch {
+	case len(current) > 0 || len(nodes) == 0:
+		return current, nil
+	case lagging > 0:
+		return nil, fmt.Errorf("%w by more than %d blocks (best head %d)", ErrNodesLagging, maxLag, best)
+	default:
+		return nil, fmt.Errorf("%w %d (best head %d)", ErrMinBlockNotReached, minBlock, best)
+	}
+}
+
+// anyNode reports whether fn returns true for any of the supplied nodes.
+func anyNode(nodes []*item, fn func(*item) bool) bool {
+	for _, node := range nodes {
+		if fn(node) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackHead polls the latest header at the cache head interval so that head-scoped cache entries are
+// dropped when a new head arrives and reorgs are detected. If a node with a persistent connection is
+// available the latest header is also polled whenever it announces a new head, so that new heads are
+// seen without waiting for the next interval. It returns immediately if neither the response cache
+// nor reorg detection is enabled.
+func (m *multiNodeClient) trackHead(ctx context.Context) {
+	st := m.routing()
+	if st.cache == nil && st.reorg == nil {
+		return
+	}
+	ticker := time.NewTicker(st.cacheCfg.withDefaults().HeadInterval)
+	defer ticker.Stop()
+	heads := make(chan *types.Header, 1)
+	sub := m.subscribeHeads(ctx, heads)
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	for {
+		m.pollHead(ctx)
+		var subErr <-chan error
+		if sub != nil {
+			subErr = sub.Err()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-heads:
+		case <-subErr:
+			sub.Unsubscribe()
+			sub = nil
+		case <-ticker.C:
+			if sub == nil {
+				sub = m.subscribeHeads(ctx, heads)
+			}
+		}
+	}
+}
+
+// pollHead fetches the latest header, checks it for a reorg and reports it, along with the finalized
+// block number when the head changes, to the cache.
+func (m *multiNodeClient) pollHead(parent context.Context) {
+	ctx, cancelFunc := context.WithTimeout(parent, timeout)
+	defer cancelFunc()
+	st := m.routing()
+	latest, err := m.headerAt(ctx, nil)
+	if err != nil {
+		if parent.Err() == nil {
+			m.logHeadError(err)
+		}
+		return
+	}
+	var reorg *reorg
+	if st.reorg != nil {
+		if reorg, err = m.detectReorg(ctx, st.reorg, latest); err != nil {
+			m.logHeadError(err)
+		} else if reorg != nil {
+			m.reportReorg(reorg)
+		}
+	}
+	c := st.cache
+	if c == nil {
+		return
+	}
+	if reorg != nil {
+		c.invalidate(reorg.fork)
+	}
+	head, finalized := latest.Number.Uint64(), uint64(0)
+	if current, _ := c.current(); head != current {
+		if final, err := m.headerAt(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber))); err == nil {
+			finalized = final.Number.Uint64()
+		}
+	}
+	c.setHead(head, finalized)
+}
+
+// headerAt fetches the header at number from the nodes in rotation, bypassing the response cache.
+func (m *multiNodeClient) headerAt(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := callNodes(ctx, m, nil, errNoNodes, func(ctx context.Context, node *item) (*types.Header, error) {
+		return node.client.HeaderByNumber(ctx, number)
+	})
+	if err == nil && header == nil {
+		return nil, fmt.Errorf("header %v not found", number)
+	}
+	return header, err
+}
+
+// logHeadError logs a failure to track the chain head.
+func (m *multiNodeClient) logHeadError(err error) {
+	m.mu.Lock()
+	l := m.logger
+	m.mu.Unlock()
+	if l != nil {
+		l.WithFields(logrus.Fields{"error": err.Error()}).Warn("headTrackerError")
+	}
+}
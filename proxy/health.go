@@ -0,0 +1,258 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/sirupsen/logrus"
+)
+
+// healthChecker is implemented by clients which probe their upstream nodes in the background.
+type healthChecker interface {
+	StartHealthChecks(cfg HealthCheckConfig, l *logrus.Entry)
+	StopHealthChecks()
+}
+
+// chainTipChecker is implemented by clients which can check that their upstream nodes agree on the
+// chain tip. Failures are returned as a single error with each failure terminated by '|'.
+type chainTipChecker interface {
+	checkChainTips(ctx context.Context) error
+}
+
+// nodeHealthReporter is implemented by clients which track the health of individual upstream nodes.
+type nodeHealthReporter interface {
+	NodeHealth() []NodeHealth
+}
+
+// NodeHealth contains the circuit breaker state and most recent probe result for an upstream node.
+// Disconnected is set for nodes which could not be connected and are waiting to be redialled.
+type NodeHealth struct {
+	ID           string       `json:"id"`
+	State        BreakerState `json:"state"`
+	BlockNumber  uint64       `json:"block_number,omitempty"`
+	Syncing      bool         `json:"syncing,omitempty"`
+	Disconnected bool         `json:"disconnected,omitempty"`
+	LatencyMs    int64        `json:"latency_ms,omitempty"`
+	Error        string       `json:"error,omitempty"`
+}
+
+// probeResult is the outcome of a single background probe of an upstream node.
+type probeResult struct {
+	blockNumber uint64
+	syncing     bool
+	syncChecked bool // the probe made a second request for the node sync status
+	latency     time.Duration
+	err         error
+}
+
+// prober runs the background health checks of a multiNodeClient.
+type prober struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// StartHealthChecks applies the breaker thresholds in cfg and starts probing every node at the
+// configured interval. Probing is disabled if the interval is zero. WebSocket and IPC connections are
+// pinged at the keepalive interval and redialled when dropped. Nodes which could not be connected
+// are redialled in the background with backoff until they connect, and the chain head is tracked for
+// the response cache and reorg detection if either is enabled.
+func (m *multiNodeClient) StartHealthChecks(cfg HealthCheckConfig, l *logrus.Entry) {
+	cfg = cfg.withDefaults()
+	for _, node := range m.snapshot() {
+		node.breaker.configure(cfg)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger, m.healthCfg = l, cfg
+	if m.prober != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.prober = &prober{cancel: cancel}
+	if cfg.Interval > 0 && !cfg.Disabled {
+		m.prober.wg.Add(1)
+		go func(p *prober) {
+			defer p.wg.Done()
+			ticker := time.NewTicker(cfg.Interval)
+			defer ticker.Stop()
+			for {
+				m.probeNodes(ctx, cfg)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(m.prober)
+	}
+	if cfg.Keepalive > 0 {
+		m.prober.wg.Add(1)
+		go func(p *prober) {
+			defer p.wg.Done()
+			m.startKeepalive(ctx, cfg.Keepalive)
+		}(m.prober)
+	}
+	if st := m.routing(); st.cache != nil || st.reorg != nil {
+		m.prober.wg.Add(1)
+		go func(p *prober) {
+			defer p.wg.Done()
+			m.trackHead(ctx)
+		}(m.prober)
+	}
+	if len(m.disconnected) > 0 {
+		m.prober.wg.Add(1)
+		go func(p *prober) {
+			defer p.wg.Done()
+			for {
+				wait, ok := m.redialNodes(ctx, cfg)
+				if !ok {
+					return
+				}
+				t := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return
+				case <-t.C:
+				}
+			}
+		}(m.prober)
+	}
+}
+
+// StopHealthChecks stops background probing and waits for any probe in progress to return.
+func (m *multiNodeClient) StopHealthChecks() {
+	m.mu.Lock()
+	p := m.prober
+	m.prober = nil
+	m.mu.Unlock()
+	if p == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+}
+
+// probeNodes checks the block height, sync status and latency of every node and updates the node
+// circuit breakers and latency averages. Nodes which are syncing, slow to respond or lagging the agreed
+// head (see agreedHead) by more than the configured limit fail the probe.
+func (m *multiNodeClient) probeNodes(parent context.Context, cfg HealthCheckConfig) {
+	ctx, cancelFunc := context.WithTimeout(parent, timeout)
+	defer cancelFunc()
+
+	nodes := m.snapshot()
+	results := fanOut(ctx, nodes, func(ctx context.Context, c SimpleEthClient) (*probeResult, error) {
+		start := time.Now()
+		res := &probeResult{}
+		res.blockNumber, res.err = c.BlockNumber(ctx)
+		res.latency = time.Since(start)
+		if syncReader, ok := c.(ethereum.ChainSyncReader); ok && res.err == nil {
+			res.syncChecked = true
+			var progress *ethereum.SyncProgress
+			progress, res.err = syncReader.SyncProgress(ctx)
+			res.syncing = progress != nil
+		}
+		return res, nil
+	})
+	if parent.Err() != nil {
+		return // health checks were stopped while probing
+	}
+
+	var heads []uint64
+	for _, r := range results {
+		if r.val.err == nil {
+			r.node.head.Store(r.val.blockNumber)
+			if !r.val.syncing {
+				heads = append(heads, r.val.blockNumber)
+			}
+		}
+	}
+	reference := agreedHead(heads)
+	for _, r := range results {
+		res := r.val
+		if res.syncChecked {
+			r.node.budget.record(1)
+		}
+		switch {
+		case res.err != nil:
+		case res.syncing:
+			res.err = fmt.Errorf("node is syncing")
+		case res.latency > cfg.MaxLatency:
+			res.err = fmt.Errorf("probe latency %v exceeds %v", res.latency.Round(time.Millisecond), cfg.MaxLatency)
+		case res.blockNumber+cfg.MaxLag < reference:
+			res.err = fmt.Errorf("node is %d blocks behind the agreed head", reference-res.blockNumber)
+		}
+		r.node.probe.Store(res)
+		if res.err == nil {
+			nodeLatencyGauge.WithLabelValues(r.node.id).Set(r.node.stats.observe(res.latency).Seconds())
+		}
+		if res.err != nil && r.node.breaker.State() == BreakerOpen {
+			continue // wait for the cooldown before counting further failures
+		}
+		m.report(r.node, res.err)
+	}
+}
+
+// agreedHead returns the highest block reached by a majority of the supplied heads, or zero if there
+// are none. Unlike the highest head it cannot be raised by a single node reporting a bogus height
+// (e.g. a node of another chain), which would otherwise make every correct node appear to lag.
+func agreedHead(heads []uint64) uint64 {
+	if len(heads) == 0 {
+		return 0
+	}
+	sorted := slices.Sorted(slices.Values(heads))
+	return sorted[(len(sorted)-1)/2]
+}
+
+// report records the outcome of a probe or request in the node circuit breaker (a nil error is a
+// success), logging any change of state.
+func (m *multiNodeClient) report(node *item, err error) {
+	if err == nil && node.breaker.healthy.Load() {
+		return // nothing to record
+	}
+	before := node.breaker.State()
+	var after BreakerState
+	if err != nil {
+		after = node.breaker.failure()
+	} else {
+		after = node.breaker.success()
+	}
+	if after == before {
+		return
+	}
+	m.mu.Lock()
+	l := m.logger
+	m.mu.Unlock()
+	if l == nil {
+		return
+	}
+	entry := l.WithFields(logrus.Fields{"node": node.id, "from": before, "to": after})
+	if err != nil {
+		entry = entry.WithField("error", err.Error())
+	}
+	entry.Warn("circuitBreakerStateChange")
+}
+
+// NodeHealth returns the breaker state and latest probe result of every node. Nodes waiting to be
+// redialled are reported as disconnected with their most recent connection error.
+func (m *multiNodeClient) NodeHealth() []NodeHealth {
+	nodes := m.snapshot()
+	health := make([]NodeHealth, len(nodes))
+	for i, node := range nodes {
+		health[i] = NodeHealth{ID: node.id, State: node.breaker.State()}
+		if res := node.probe.Load(); res != nil {
+			health[i].BlockNumber, health[i].Syncing, health[i].LatencyMs = res.blockNumber, res.syncing, res.latency.Milliseconds()
+			if res.err != nil {
+				health[i].Error = res.err.Error()
+			}
+		}
+	}
+	for _, h := range m.disconnectedHealth() {
+		health = slices.Insert(health, configPosition(health, func(h NodeHealth) string { return h.ID }, h.ID), h)
+	}
+	return health
+}
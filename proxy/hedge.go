@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetHedgeConfig enables hedged reads for BalanceAt, TransactionByHash, TransactionReceipt and
+// BlockNumber. A zero percentile disables hedging.
+func (m *multiNodeClient) SetHedgeConfig(cfg HedgeConfig) error {
+	if cfg.Percentile < 0 || cfg.Percentile > 100 {
+		return fmt.Errorf("invalid hedge percentile %v", cfg.Percentile)
+	}
+	m.update(func(st *routingState) { st.hedge = cfg.withDefaults() })
+	return nil
+}
+
+// hedgeDelay returns the time to wait for node to answer before a duplicate request is started.
+func (cfg HedgeConfig) hedgeDelay(node *item) time.Duration {
+	delay, ok := node.stats.percentile(cfg.Percentile)
+	if !ok {
+		return cfg.MaxDelay
+	}
+	return min(max(delay, cfg.MinDelay), cfg.MaxDelay)
+}
+
+// hedgedCall calls fn on the first capable node in rotation. If that node has not answered within the hedge
+// delay a duplicate request is started on the next node; the first successful answer is returned and
+// the other request is cancelled. Attempts follow the retry policy, as with callNodes: retryable errors
+// move on to the next node after the backoff once no request is in flight, terminal errors are
+// returned, and hedged requests count as attempts.
+func hedgedCall[T any](ctx context.Context, m *multiNodeClient, capable func(*item) bool, unsupported error, fn func(context.Context, *item) (T, error)) (T, error) {
+	st := m.routing()
+	cfg, policy := st.hedge, st.retry
+	if cfg.Percentile <= 0 {
+		return callNodes(ctx, m, capable, unsupported, fn)
+	}
+	var zero T
+	nodes, err := m.readRotation(ctx, capable)
+	if err != nil {
+		return zero, err
+	}
+	if len(nodes) == 0 {
+		return zero, unsupported
+	}
+	m.picked(nodes)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	attempts := policy.attempts(len(nodes))
+	results := make(chan nodeResult[T], attempts)
+	next, inflight := 0, 0
+	launch := func() {
+		node, remaining := nodes[next%len(nodes)], attempts-next
+		next, inflight = next+1, inflight+1
+		go func() {
+			val, err := callAttempt(ctx, m, policy, remaining, node, fn)
+			results <- nodeResult[T]{node: node, val: val, err: err}
+		}()
+	}
+
+	launch()
+	hedge := time.NewTimer(cfg.hedgeDelay(nodes[0]))
+	defer hedge.Stop()
+	hedged := false
+
+	err = errNoNodes
+	for inflight > 0 {
+		select {
+		case res := <-results:
+			inflight--
+			if res.err == nil {
+				if hedged {
+					winner := "primary"
+					if res.node != nodes[0] {
+						winner = "hedge"
+					}
+					hedgedRequestsCounter.WithLabelValues(m.chain, winner).Inc()
+				}
+				return res.val, nil
+			}
+			err = res.err
+			if !isRetryable(err) {
+				return zero, err
+			}
+			if next < attempts && inflight == 0 {
+				if waitErr := policy.wait(ctx, next); waitErr != nil {
+					return zero, err
+				}
+				launch()
+			}
+		case <-hedge.C:
+			if !hedged && next < attempts && next < len(nodes) {
+				hedged = true
+				launch()
+			}
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+	return zero, err
+}
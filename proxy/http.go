@@ -32,8 +32,9 @@ func makeAPI(endpoints []endPoint) *api {
 	return r
 }
 
-func makeProxyAPIs(ethCli SimpleEthClient) *api {
-	return makeAPI([]endPoint{
+func makeProxyAPIs(ethCli SimpleEthClient, beacon *BeaconClient, cfg *Config) *api {
+	journal := NewTxJournal()
+	endpoints := []endPoint{
 		{
 			path:       StatusEndPnt,
 			handler:    Status(),
@@ -41,12 +42,22 @@ func makeProxyAPIs(ethCli SimpleEthClient) *api {
 		},
 		{
 			path:       HeathEndPnt,
-			handler:    Health(ethCli),
+			handler:    Health(ethCli, beacon),
 			methodType: http.MethodGet,
 		},
 		{
 			path:       ethV0BalanceEndPnt,
-			handler:    Balance(ethCli),
+			handler:    Balance(ethCli, cfg.Proof, cfg.QuorumReads.Balance),
+			methodType: http.MethodGet,
+		},
+		{
+			path:       ethV0NonceEndPnt,
+			handler:    Nonce(ethCli, cfg.Proof, cfg.QuorumReads.Nonce),
+			methodType: http.MethodGet,
+		},
+		{
+			path:       ethV0StorageEndPnt,
+			handler:    Storage(ethCli, cfg.Proof, cfg.QuorumReads.Storage),
 			methodType: http.MethodGet,
 		},
 		{
@@ -61,11 +72,47 @@ func makeProxyAPIs(ethCli SimpleEthClient) *api {
 		},
 		{
 			path:       ethV0SendTxEndPnt,
-			handler:    SendTx(ethCli),
+			handler:    SendTx(ethCli, cfg.SimulateTxs, journal),
+			methodType: http.MethodPost,
+		},
+		{
+			path:       EthV0SendTxBodyEndPnt,
+			handler:    SendTxBody(ethCli, cfg.SimulateTxs, journal),
+			methodType: http.MethodPost,
+		},
+		{
+			path:       EthV0SimulateEndPnt,
+			handler:    SimulateTx(ethCli),
 			methodType: http.MethodPost,
 		},
-	},
-	)
+		{
+			path:       EthV0CallEndPnt,
+			handler:    Call(ethCli),
+			methodType: http.MethodPost,
+		},
+		{
+			path:       EthV0FeeEndPnt,
+			handler:    Fee(ethCli),
+			methodType: http.MethodGet,
+		},
+		{
+			path:       ethV0TxTraceEndPnt,
+			handler:    TxTrace(ethCli),
+			methodType: http.MethodGet,
+		},
+		{
+			path:       ethV0TxPoolEndPnt,
+			handler:    TxPool(ethCli, journal),
+			methodType: http.MethodGet,
+		},
+	}
+	if cfg.Admin.Enabled {
+		endpoints = append(endpoints, adminEndpoints(ethCli, cfg.Admin)...)
+	}
+	if beacon != nil {
+		endpoints = append(endpoints, beaconEndpoints(beacon)...)
+	}
+	return makeAPI(endpoints)
 }
 
 func (a *api) addEndpoint(e endPoint) {
@@ -96,9 +143,11 @@ type hTTPService struct {
 // NewHTTPService returns a HTTP server with httprouter Router
 // handling requests.
 func NewHTTPService(port int, api *api, l *logrus.Entry) *hTTPService {
+	return newHTTPService(port, api.routes(l), l)
+}
 
-	handler := api.routes(l)
-
+// newHTTPService returns a HTTP server with the supplied handler handling requests.
+func newHTTPService(port int, handler http.Handler, l *logrus.Entry) *hTTPService {
 	return &hTTPService{
 		server: &http.Server{
 			Addr:              fmt.Sprintf(":%d", port),
@@ -181,6 +230,11 @@ func (w *responseRecorder) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap returns the wrapped writer so that http.ResponseController can flush streamed responses.
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload any) error {
 	response, err := json.Marshal(payload)
 	if err != nil {
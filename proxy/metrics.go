@@ -0,0 +1,110 @@
+package proxy
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const metricsNamespace = "eth_proxy"
+
+var (
+	selectionStrategyGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "selection_strategy",
+		Help:      "Upstream node selection strategy in use (set to 1 for the active strategy).",
+	}, []string{"chain", "strategy"})
+
+	nodeRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_requests_total",
+		Help:      "Requests made to each upstream node by selection strategy and result.",
+	}, []string{"node", "strategy", "result"})
+
+	nodeLatencyGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_latency_ewma_seconds",
+		Help:      "Exponentially weighted moving average latency of each upstream node.",
+	}, []string{"node"})
+
+	hedgedRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "hedged_requests_total",
+		Help:      "Hedged reads by the request (primary or hedge) which answered first.",
+	}, []string{"chain", "winner"})
+
+	quorumDivergenceCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "quorum_divergence_total",
+		Help:      "Quorum reads which failed because upstream nodes did not agree, by endpoint.",
+	}, []string{"chain", "endpoint"})
+
+	txBroadcastCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "tx_broadcasts_total",
+		Help:      "Transactions broadcast to each upstream node by result (accepted, known or rejected).",
+	}, []string{"node", "result"})
+
+	coalescedRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "coalesced_requests_total",
+		Help:      "Requests answered with the result of an identical request already in flight, by method.",
+	}, []string{"chain", "method"})
+
+	cacheHitsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "cache_hits_total",
+		Help:      "Reads answered from the response cache, by method.",
+	}, []string{"chain", "method"})
+
+	cacheMissesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "cache_misses_total",
+		Help:      "Reads not found in the response cache, by method.",
+	}, []string{"chain", "method"})
+
+	cacheBytesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cache_bytes",
+		Help:      "Approximate size of the response cache in bytes.",
+	}, []string{"chain"})
+
+	nodeReconnectsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_reconnects_total",
+		Help:      "Persistent (WebSocket or IPC) connections to each upstream node restored after being lost.",
+	}, []string{"node"})
+
+	chainReorgsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_reorgs_total",
+		Help:      "Chain reorganizations seen by the head tracker.",
+	}, []string{"chain"})
+
+	chainReorgDepthHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_reorg_depth",
+		Help:      "Number of blocks orphaned by each chain reorganization.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 7),
+	}, []string{"chain"})
+
+	nodeQuotaUsedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_quota_used",
+		Help:      "Requests made to each upstream node with a daily quota over the last 24 hours.",
+	}, []string{"node"})
+
+	nodeQuotaLimitGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_quota_limit",
+		Help:      "Configured daily request quota of each upstream node.",
+	}, []string{"node"})
+
+	nodeRateLimitedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_rate_limited_total",
+		Help:      "Requests rejected by each upstream node for exceeding its rate limit (HTTP 429).",
+	}, []string{"node"})
+)
+
+func init() {
+	prometheus.MustRegister(selectionStrategyGauge, nodeRequestsCounter, nodeLatencyGauge, hedgedRequestsCounter, quorumDivergenceCounter, txBroadcastCounter,
+		coalescedRequestsCounter, cacheHitsCounter, cacheMissesCounter, cacheBytesGauge, nodeReconnectsCounter, chainReorgsCounter,
+		chainReorgDepthHistogram, nodeQuotaUsedGauge, nodeQuotaLimitGauge, nodeRateLimitedCounter)
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	BlockHashKey = "blockhash" // optional query parameter pinning a read to the block with the given hash
+
+	EthV0CallEndPnt = "/eth/v0/call" // eth_call proxy endpoint (call supplied in request body)
+
+	maxCallBodySize = 1 << 20
+)
+
+// ErrBlockHashNotSupported is returned when the upstream client cannot serve reads pinned to a block hash.
+var ErrBlockHashNotSupported = errors.New("upstream client does not support reads at a block hash")
+
+// BlockHashEthClient exposes state reads pinned to a block hash (EIP-1898). The state of a block never
+// changes, so these reads can be cached for as long as the block is known.
+type BlockHashEthClient interface {
+	BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error)
+	CallContractAtHash(ctx context.Context, msg ethereum.CallMsg, blockHash common.Hash) ([]byte, error)
+}
+
+var (
+	_ BlockHashEthClient = (*ethClient)(nil)
+	_ BlockHashEthClient = (*multiNodeClient)(nil)
+)
+
+// isBlockHashNode reports whether the node can serve reads pinned to a block hash.
+func isBlockHashNode(node *item) bool {
+	_, ok := node.client.(BlockHashEthClient)
+	return ok
+}
+
+// BalanceAtHash returns the balance of an account at the block with the given hash from the first node
+// able to serve the request. The balance is cached until evicted.
+func (m *multiNodeClient) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	return cachedRead(ctx, m, "eth_getBalance", account.Hex()+","+blockHash.Hex(), atHash[*big.Int], func(ctx context.Context) (*big.Int, error) {
+		return hedgedCall(ctx, m, isBlockHashNode, ErrBlockHashNotSupported, func(ctx context.Context, node *item) (*big.Int, error) {
+			return node.client.(BlockHashEthClient).BalanceAtHash(ctx, account, blockHash)
+		})
+	})
+}
+
+// CallContractAtHash executes a message call against the state of the block with the given hash on the
+// first node able to serve the request. The result is cached until evicted; execution errors such as
+// reverts are not cached.
+func (m *multiNodeClient) CallContractAtHash(ctx context.Context, msg ethereum.CallMsg, blockHash common.Hash) ([]byte, error) {
+	call := func(ctx context.Context) ([]byte, error) {
+		return callNodes(ctx, m, isBlockHashNode, ErrBlockHashNotSupported, func(ctx context.Context, node *item) ([]byte, error) {
+			return node.client.(BlockHashEthClient).CallContractAtHash(ctx, msg, blockHash)
+		})
+	}
+	params, err := json.Marshal(msg)
+	if err != nil {
+		return call(ctx)
+	}
+	return cachedRead(ctx, m, "eth_call", string(params)+","+blockHash.Hex(), atHash[[]byte], call)
+}
+
+// readBlockHash parses the blockhash query parameter. It reports whether the parameter was given.
+func readBlockHash(r *http.Request) (common.Hash, bool, error) {
+	h := r.URL.Query().Get(BlockHashKey)
+	if h == "" {
+		return common.Hash{}, false, nil
+	}
+	if !isHexHash(h) {
+		return common.Hash{}, false, fmt.Errorf("invalid block hash '%s'", h)
+	}
+	return common.HexToHash(h), true, nil
+}
+
+// blockHashClient returns the client used for reads pinned to a block hash.
+func blockHashClient(ethClient SimpleEthClient) (BlockHashEthClient, error) {
+	c, ok := ethClient.(BlockHashEthClient)
+	if !ok {
+		return nil, ErrBlockHashNotSupported
+	}
+	return c, nil
+}
+
+// pinnedReadErrorCode maps the error of a read pinned to a block hash to a HTTP status code.
+func pinnedReadErrorCode(err error) int {
+	if errors.Is(err, ErrBlockHashNotSupported) {
+		return http.StatusNotImplemented
+	}
+	return ethClientErrorCode(err)
+}
+
+// CallRequest describes a message call. Gas and value are optional.
+type CallRequest struct {
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Gas   hexutil.Uint64  `json:"gas,omitempty"`
+	Value *hexutil.Big    `json:"value,omitempty"`
+	Data  hexutil.Bytes   `json:"data,omitempty"`
+}
+
+// CallResponse contains the return data of a message call and, for calls pinned to a block, the hash of
+// that block.
+type CallResponse struct {
+	Result    string `json:"result"`
+	BlockHash string `json:"block_hash,omitempty"`
+}
+
+// Call returns a handler which executes a message call without creating a transaction. Calls are made
+// against the pending state unless the blockhash query parameter pins them to a block. A call which
+// reverts is reported with the revert reason.
+func Call(ethClient SimpleEthClient) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+		var req CallRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCallBodySize)).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid call data: %v", err))
+			return
+		}
+		msg := ethereum.CallMsg{From: req.From, To: req.To, Gas: uint64(req.Gas), Value: req.Value.ToInt(), Data: req.Data}
+
+		hash, pinned, err := readBlockHash(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+		ctx, err = withMinBlockParam(ctx, r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		resp := &CallResponse{}
+		var result []byte
+		if pinned {
+			var c BlockHashEthClient
+			if c, err = blockHashClient(ethClient); err == nil {
+				result, err = c.CallContractAtHash(ctx, msg, hash)
+			}
+			resp.BlockHash = hash.Hex()
+		} else {
+			result, err = ethClient.PendingCallContract(ctx, msg)
+		}
+		switch {
+		case isExecutionRevert(err):
+			respondWithError(w, http.StatusUnprocessableEntity, fmt.Errorf("call reverted: %s", revertReason(err)))
+			return
+		case err != nil:
+			respondWithError(w, pinnedReadErrorCode(err), fmt.Errorf("eth client error: %v", err))
+			return
+		}
+		resp.Result = hexutil.Encode(result)
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
@@ -0,0 +1,286 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	VerifiedKey = "verified" // optional query parameter requesting a proof-verified read
+	BlockKey    = "block"    // optional query parameter selecting the block number of a read
+)
+
+var (
+	// ErrProofNotSupported is returned when the upstream client cannot serve eth_getProof requests.
+	ErrProofNotSupported = errors.New("upstream client does not support eth_getProof")
+
+	errInvalidProof      = errors.New("invalid state proof")
+	errNoQuorum          = errors.New("upstream nodes did not reach quorum on block hash")
+	errCheckpointMissing = errors.New("block hash does not match configured checkpoint")
+)
+
+// ProofEthClient exposes the eth_getProof call used to serve proof-verified state reads.
+type ProofEthClient interface {
+	GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error)
+}
+
+// headerQuorumReader is implemented by clients backed by several upstream nodes. It returns the
+// header at the given height (or the highest height served by at least quorum nodes if number
+// is nil) provided that quorum nodes agree on its hash. A non-positive quorum requires a simple
+// majority of at least minQuorum nodes.
+type headerQuorumReader interface {
+	headerQuorum(ctx context.Context, number *big.Int, quorum, minQuorum int) (*types.Header, error)
+}
+
+// verifiedAccount contains account state that has been checked against a trusted state root.
+type verifiedAccount struct {
+	block   uint64
+	balance *big.Int
+	nonce   uint64
+	storage []common.Hash
+}
+
+// readVerifiedAccount fetches the account (and optional storage slots) with eth_getProof and
+// verifies the Merkle-Patricia proofs against the state root of a block whose hash is either
+// agreed by a quorum of upstream nodes or linked to the configured checkpoint.
+func readVerifiedAccount(ctx context.Context, ethClient SimpleEthClient, cfg ProofConfig, account common.Address, slots []common.Hash, number *big.Int) (*verifiedAccount, error) {
+	proofClient, ok := ethClient.(ProofEthClient)
+	if !ok {
+		return nil, ErrProofNotSupported
+	}
+	header, err := trustedHeader(ctx, ethClient, cfg, number)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(slots))
+	for i, slot := range slots {
+		keys[i] = slot.Hex()
+	}
+	res, err := proofClient.GetProof(ctx, account, keys, header.Number)
+	if err != nil {
+		return nil, err
+	}
+	if res.Address != account {
+		return nil, fmt.Errorf("%w: proof for account %s, want %s", errInvalidProof, res.Address.Hex(), account.Hex())
+	}
+	if err := verifyAccountProof(header.Root, res); err != nil {
+		return nil, err
+	}
+	storage, err := verifyStorageProofs(res, slots)
+	if err != nil {
+		return nil, err
+	}
+	return &verifiedAccount{
+		block:   header.Number.Uint64(),
+		balance: res.Balance,
+		nonce:   res.Nonce,
+		storage: storage,
+	}, nil
+}
+
+// trustedHeader returns a block header whose hash has been established independently of any
+// single upstream node (unless single node reads are allowed): a header linked to the configured
+// checkpoint (see checkpointHeader), or otherwise the hash agreed by a quorum of at least two nodes.
+func trustedHeader(ctx context.Context, ethClient SimpleEthClient, cfg ProofConfig, number *big.Int) (*types.Header, error) {
+	if cfg.CheckpointHash != "" {
+		header, ok, err := checkpointHeader(ctx, ethClient, cfg, number)
+		if err != nil || ok {
+			return header, err
+		}
+	}
+	if cfg.Quorum > 0 && cfg.Quorum < cfg.minQuorum() {
+		return nil, fmt.Errorf("%w: quorum %d is below the minimum of %d nodes", errNoQuorum, cfg.Quorum, cfg.minQuorum())
+	}
+	if quorumReader, ok := ethClient.(headerQuorumReader); ok {
+		return quorumReader.headerQuorum(ctx, number, cfg.Quorum, cfg.minQuorum())
+	}
+	if max(cfg.Quorum, cfg.minQuorum()) > 1 {
+		return nil, fmt.Errorf("%w: single upstream client cannot satisfy quorum %d", errNoQuorum, max(cfg.Quorum, cfg.minQuorum()))
+	}
+	return ethClient.HeaderByNumber(ctx, number)
+}
+
+// checkpointHeader returns the header at number (the latest header if number is nil) provided that the
+// parent hashes of the headers between it and the configured checkpoint link the two blocks, and the
+// checkpoint block has the configured hash. ok is false, and the header must be established by other
+// means, if the block is further than the checkpoint distance from the checkpoint.
+func checkpointHeader(ctx context.Context, ethClient SimpleEthClient, cfg ProofConfig, number *big.Int) (header *types.Header, ok bool, err error) {
+	headerAt := func(n uint64) (*types.Header, error) {
+		header, err := ethClient.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || !header.Number.IsUint64() || header.Number.Uint64() != n {
+			return nil, fmt.Errorf("header %d not found", n)
+		}
+		return header, nil
+	}
+	if number == nil {
+		if header, err = ethClient.HeaderByNumber(ctx, nil); err != nil {
+			return nil, false, err
+		}
+		if header == nil {
+			return nil, false, errors.New("latest header not found")
+		}
+		number = header.Number
+	}
+	if !number.IsUint64() {
+		return nil, false, nil
+	}
+	target, checkpoint := number.Uint64(), cfg.CheckpointNumber
+	low, high := min(target, checkpoint), max(target, checkpoint)
+	if high-low > cfg.checkpointDistance() {
+		return nil, false, nil
+	}
+
+	var top *types.Header
+	if header != nil && target == high {
+		top = header
+	} else if top, err = headerAt(high); err != nil {
+		return nil, false, err
+	}
+	header, child := top, top
+	for n := high; n > low; n-- {
+		parent, err := headerAt(n - 1)
+		if err != nil {
+			return nil, false, err
+		}
+		if parent.Hash() != child.ParentHash {
+			return nil, false, fmt.Errorf("%w: block %d is not the parent of block %d", errCheckpointMissing, n-1, n)
+		}
+		if n-1 == target {
+			header = parent
+		}
+		child = parent
+	}
+	anchor := top
+	if checkpoint == low {
+		anchor = child
+	}
+	if anchor.Hash() != common.HexToHash(cfg.CheckpointHash) {
+		return nil, false, fmt.Errorf("%w: block %d hash %s", errCheckpointMissing, checkpoint, anchor.Hash().Hex())
+	}
+	return header, true, nil
+}
+
+// proofDB loads a list of hex encoded trie nodes into a key-value store keyed by node hash.
+func proofDB(proof []string) (*memorydb.Database, error) {
+	db := memorydb.New()
+	for _, node := range proof {
+		b, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidProof, err)
+		}
+		if err := db.Put(crypto.Keccak256(b), b); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// verifyAccountProof checks the account fields returned by eth_getProof against the state root.
+func verifyAccountProof(root common.Hash, res *gethclient.AccountResult) error {
+	db, err := proofDB(res.AccountProof)
+	if err != nil {
+		return err
+	}
+	value, err := trie.VerifyProof(root, crypto.Keccak256(res.Address.Bytes()), db)
+	if err != nil {
+		return fmt.Errorf("%w: account proof: %v", errInvalidProof, err)
+	}
+	account := types.NewEmptyStateAccount()
+	if value != nil {
+		if err := rlp.DecodeBytes(value, account); err != nil {
+			return fmt.Errorf("%w: account rlp: %v", errInvalidProof, err)
+		}
+	}
+	switch {
+	case res.Balance == nil || account.Balance.ToBig().Cmp(res.Balance) != 0:
+		return fmt.Errorf("%w: balance mismatch", errInvalidProof)
+	case account.Nonce != res.Nonce:
+		return fmt.Errorf("%w: nonce mismatch", errInvalidProof)
+	case account.Root != res.StorageHash:
+		return fmt.Errorf("%w: storage root mismatch", errInvalidProof)
+	case !bytes.Equal(account.CodeHash, res.CodeHash.Bytes()):
+		return fmt.Errorf("%w: code hash mismatch", errInvalidProof)
+	}
+	return nil
+}
+
+// verifyStorageProofs checks each requested storage slot against the (verified) account storage
+// root and returns the slot values in request order.
+func verifyStorageProofs(res *gethclient.AccountResult, slots []common.Hash) ([]common.Hash, error) {
+	if len(res.StorageProof) != len(slots) {
+		return nil, fmt.Errorf("%w: have %d storage proofs, want %d", errInvalidProof, len(res.StorageProof), len(slots))
+	}
+	values := make([]common.Hash, len(slots))
+	for i, slot := range slots {
+		sp := res.StorageProof[i]
+		if common.HexToHash(sp.Key) != slot {
+			return nil, fmt.Errorf("%w: storage proof %d for key %s, want %s", errInvalidProof, i, sp.Key, slot.Hex())
+		}
+		db, err := proofDB(sp.Proof)
+		if err != nil {
+			return nil, err
+		}
+		value, err := trie.VerifyProof(res.StorageHash, crypto.Keccak256(slot.Bytes()), db)
+		if err != nil {
+			return nil, fmt.Errorf("%w: storage proof: %v", errInvalidProof, err)
+		}
+		proven := new(big.Int)
+		if value != nil {
+			_, content, _, err := rlp.Split(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: storage rlp: %v", errInvalidProof, err)
+			}
+			proven.SetBytes(content)
+		}
+		if sp.Value == nil || proven.Cmp(sp.Value) != 0 {
+			return nil, fmt.Errorf("%w: storage value mismatch for key %s", errInvalidProof, slot.Hex())
+		}
+		values[i] = common.BigToHash(proven)
+	}
+	return values, nil
+}
+
+// readErrorCode maps verified and quorum read errors to an HTTP response code.
+func readErrorCode(err error) int {
+	switch {
+	case errors.Is(err, ErrProofNotSupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, ErrMinBlockNotReached), errors.Is(err, ErrNodesLagging):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, errInvalidProof), errors.Is(err, errNoQuorum), errors.Is(err, errCheckpointMissing), errors.Is(err, errReadDivergence), errors.Is(err, errQuorumUnavailable):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// readOptions parses the verified and block query parameters shared by the state read endpoints.
+func readOptions(r *http.Request) (verified bool, number *big.Int, err error) {
+	query := r.URL.Query()
+	verified = query.Get(VerifiedKey) == "true"
+	if b := query.Get(BlockKey); b != "" {
+		n, err := strconv.ParseUint(b, 10, 64)
+		if err != nil {
+			return false, nil, fmt.Errorf("invalid block number '%s'", b)
+		}
+		number = new(big.Int).SetUint64(n)
+	}
+	return verified, number, nil
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errReadDivergence    = errors.New("upstream nodes disagree")              // nodes did not agree on the answer to a quorum read
+	errQuorumUnavailable = errors.New("not enough upstream nodes for quorum") // too few nodes could serve a quorum read
+)
+
+// quorumNodeSet is implemented by clients backed by several upstream nodes which can serve
+// quorum reads.
+type quorumNodeSet interface {
+	quorumNodes(ctx context.Context, n int, number *big.Int) ([]*item, error)
+	divergence(endpoint string, block uint64, answers []string)
+}
+
+// quorumNodes returns up to n nodes able to serve state at the given block in read rotation order (every
+// such node if n <= 0).
+func (m *multiNodeClient) quorumNodes(ctx context.Context, n int, number *big.Int) ([]*item, error) {
+	nodes, err := m.readRotation(ctx, m.stateCapable(number))
+	if n > 0 && n < len(nodes) {
+		nodes = nodes[:n]
+	}
+	return nodes, err
+}
+
+// divergence records a failed quorum read in metrics and logs the answer of each node.
+func (m *multiNodeClient) divergence(endpoint string, block uint64, answers []string) {
+	quorumDivergenceCounter.WithLabelValues(m.chain, endpoint).Inc()
+	m.mu.Lock()
+	l := m.logger
+	m.mu.Unlock()
+	if l == nil {
+		return
+	}
+	l.WithFields(logrus.Fields{"endpoint": endpoint, "block": block, "answers": answers}).Warn("quorumReadDivergence")
+}
+
+// quorumRead reads a value from cfg.Nodes nodes in parallel at the same block and returns it only if
+// at least cfg.Agree nodes report the same answer. If no block number is supplied the highest block
+// reported by at least cfg.Agree of the nodes is used. Answers are compared by their string form.
+func quorumRead[T any](ctx context.Context, ethClient SimpleEthClient, cfg QuorumConfig, endpoint string, number *big.Int, read func(context.Context, SimpleEthClient, *big.Int) (T, error)) (val T, block uint64, err error) {
+	nodeSet, ok := ethClient.(quorumNodeSet)
+	if !ok {
+		return val, 0, fmt.Errorf("%w: single upstream client cannot satisfy quorum %d", errQuorumUnavailable, cfg.Agree)
+	}
+	nodes, err := nodeSet.quorumNodes(ctx, cfg.Nodes, number)
+	if err != nil {
+		return val, 0, err
+	}
+	if cfg.Agree > len(nodes) {
+		return val, 0, fmt.Errorf("%w: quorum %d exceeds available node count %d", errQuorumUnavailable, cfg.Agree, len(nodes))
+	}
+
+	if number == nil {
+		var heights []uint64
+		for _, res := range fanOut(ctx, nodes, func(ctx context.Context, c SimpleEthClient) (uint64, error) { return c.BlockNumber(ctx) }) {
+			if res.err == nil {
+				heights = append(heights, res.val)
+			}
+		}
+		if len(heights) < cfg.Agree {
+			return val, 0, fmt.Errorf("%w: %d of %d nodes reported a block height", errQuorumUnavailable, len(heights), cfg.Agree)
+		}
+		sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+		number = new(big.Int).SetUint64(heights[cfg.Agree-1])
+	}
+	block = number.Uint64()
+
+	votes := make(map[string]int)
+	answers := make([]string, 0, len(nodes))
+	results := fanOut(ctx, nodes, func(ctx context.Context, c SimpleEthClient) (T, error) { return read(ctx, c, number) })
+	for _, res := range results {
+		if res.err != nil {
+			answers = append(answers, fmt.Sprintf("node %s err: %v", res.node.id, res.err))
+			continue
+		}
+		answer := fmt.Sprint(res.val)
+		answers = append(answers, fmt.Sprintf("node %s: %s", res.node.id, answer))
+		if votes[answer]++; votes[answer] >= cfg.Agree {
+			return res.val, block, nil
+		}
+	}
+	nodeSet.divergence(endpoint, block, answers)
+	return val, block, fmt.Errorf("%w: block %d, quorum %d of %d [%s]", errReadDivergence, block, cfg.Agree, len(nodes), strings.Join(answers, ", "))
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// disconnected is an upstream node which could not be connected. It is redialled in the background
+// until it passes the checks made by connect and joins the rotation.
+type disconnected struct {
+	id       string
+	cfg      NodeConfig
+	err      error
+	attempts int       // failed redials since the node was disconnected
+	next     time.Time // time of the next redial
+}
+
+// redialNodes redials the disconnected nodes which are due, moving those which connect into the node
+// list in config order. Nodes which fail are redialled after an exponential backoff. The time until the
+// next redial is returned, or false if no node remains disconnected.
+func (m *multiNodeClient) redialNodes(parent context.Context, cfg HealthCheckConfig) (time.Duration, bool) {
+	m.mu.Lock()
+	pending := slices.Clone(m.disconnected)
+	l := m.logger
+	m.mu.Unlock()
+
+	backoff := RetryConfig{InitialBackoff: cfg.RedialBackoff, MaxBackoff: cfg.MaxRedialBackoff}
+	for _, d := range pending {
+		if time.Now().Before(d.next) {
+			continue
+		}
+		ctx, cancelFunc := context.WithTimeout(parent, timeout)
+		client, head, err := m.connect(ctx, d.cfg)
+		cancelFunc()
+		if parent.Err() != nil {
+			break // health checks were stopped while redialling
+		}
+
+		m.mu.Lock()
+		if !slices.Contains(m.disconnected, d) {
+			m.mu.Unlock() // removed while being redialled
+			if closer, ok := client.(interface{ Close() }); ok && err == nil {
+				closer.Close()
+			}
+			continue
+		}
+		if err != nil {
+			d.attempts++
+			d.err, d.next = err, time.Now().Add(backoff.backoff(d.attempts))
+			m.mu.Unlock()
+			if l != nil {
+				l.WithFields(logrus.Fields{"node": d.id, "attempts": d.attempts, "error": err.Error()}).Debug("nodeRedialFailed")
+			}
+			continue
+		}
+		node := newItem(d.id, d.cfg, client, m.healthCfg)
+		node.head.Store(head)
+		m.disconnected = slices.DeleteFunc(m.disconnected, func(p *disconnected) bool { return p == d })
+		m.updateLocked(func(st *routingState) {
+			st.nodes = slices.Insert(slices.Clone(st.nodes), configPosition(st.nodes, func(node *item) string { return node.id }, d.id), node)
+		})
+		m.mu.Unlock()
+		if l != nil {
+			l.WithFields(logrus.Fields{"node": d.id, "attempts": d.attempts + 1}).Info("nodeConnected")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.disconnected) == 0 {
+		return 0, false
+	}
+	next := m.disconnected[0].next
+	for _, d := range m.disconnected[1:] {
+		if d.next.Before(next) {
+			next = d.next
+		}
+	}
+	return max(time.Until(next), 0), true
+}
+
+// configPosition returns the index at which the entry with the given node id belongs in entries, which
+// are ordered by node id.
+func configPosition[T any](entries []T, nodeID func(T) string, id string) int {
+	pos := nodeIndex(id)
+	i := slices.IndexFunc(entries, func(entry T) bool {
+		return nodeIndex(nodeID(entry)) > pos
+	})
+	if i < 0 {
+		return len(entries)
+	}
+	return i
+}
+
+// nodeIndex returns the position of a node in its config (or the order in which it was added) from its
+// id, which may start with the name of the node's chain, e.g. 1 for both "1" and "sepolia-1".
+func nodeIndex(id string) int {
+	n, _ := strconv.Atoi(id[strings.LastIndex(id, "-")+1:])
+	return n
+}
+
+// disconnectedHealth returns the health of each node which is waiting to be redialled.
+func (m *multiNodeClient) disconnectedHealth() []NodeHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	health := make([]NodeHealth, len(m.disconnected))
+	for i, d := range m.disconnected {
+		health[i] = NodeHealth{ID: d.id, State: BreakerOpen, Disconnected: true, Error: d.err.Error()}
+	}
+	return health
+}
@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// reorgReporter is implemented by clients which track reorgs and can report whether a receipt is for
+// a transaction in an orphaned block.
+type reorgReporter interface {
+	Reorged(receipt *types.Receipt) bool
+}
+
+// reorgTracker records the hashes of the recent canonical blocks seen by the head tracker and the
+// blocks orphaned by reorgs.
+type reorgTracker struct {
+	depth uint64
+
+	mu       sync.Mutex
+	hashes   map[uint64]common.Hash // canonical block hash by height
+	highest  uint64                 // highest tracked height, zero until the first head is recorded
+	orphaned map[common.Hash]uint64 // height of each block orphaned within the tracked window
+}
+
+// reorg is a chain reorganization seen by the head tracker.
+type reorg struct {
+	fork     uint64        // lowest height whose block was replaced
+	oldHead  uint64        // highest tracked height before the reorg
+	newHead  uint64        // height of the new head
+	orphaned []common.Hash // blocks no longer on the canonical chain
+}
+
+func newReorgTracker(depth int) *reorgTracker {
+	return &reorgTracker{depth: uint64(depth), hashes: make(map[uint64]common.Hash), orphaned: make(map[common.Hash]uint64)}
+}
+
+// SetReorgConfig enables reorg detection. Reorgs are detected by the head tracker, run with the health
+// checks.
+func (m *multiNodeClient) SetReorgConfig(cfg ReorgConfig) {
+	var t *reorgTracker
+	if cfg.Enabled {
+		t = newReorgTracker(cfg.withDefaults().Depth)
+	}
+	m.update(func(st *routingState) { st.reorg = t })
+}
+
+// lookup returns the tracked hash at height, if any, and whether height has yet to be tracked (no head
+// has been recorded or it lies above the highest tracked height).
+func (t *reorgTracker) lookup(height uint64) (hash common.Hash, known, untracked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hash, known = t.hashes[height]
+	return hash, known, t.highest == 0 || height > t.highest
+}
+
+// record records a chain segment ordered from its highest block down, each block being the parent of
+// the one before. Tracked blocks replaced by the segment and, if any were replaced, tracked blocks above
+// the segment are orphaned. The reorg is returned, or nil if no block was replaced.
+func (t *reorgTracker) record(segment []*types.Header) *reorg {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	head := segment[0].Number.Uint64()
+	r := &reorg{oldHead: t.highest, newHead: head}
+	for _, header := range segment {
+		height, hash := header.Number.Uint64(), header.Hash()
+		if old, ok := t.hashes[height]; ok && old != hash {
+			t.orphaned[old] = height
+			r.orphaned, r.fork = append(r.orphaned, old), height
+		}
+		t.hashes[height] = hash
+		delete(t.orphaned, hash) // back on the canonical chain
+	}
+	if len(r.orphaned) > 0 {
+		for height, hash := range t.hashes {
+			if height > head {
+				delete(t.hashes, height)
+				t.orphaned[hash] = height
+				r.orphaned = append(r.orphaned, hash)
+			}
+		}
+		t.highest = head
+	} else {
+		t.highest = max(t.highest, head)
+	}
+	for height := range t.hashes {
+		if height+t.depth <= t.highest {
+			delete(t.hashes, height)
+		}
+	}
+	for hash, height := range t.orphaned {
+		if height+t.depth <= t.highest {
+			delete(t.orphaned, hash)
+		}
+	}
+	if len(r.orphaned) == 0 {
+		return nil
+	}
+	return r
+}
+
+// isOrphaned reports whether the block with the given hash and height has been orphaned by a reorg,
+// either because it was seen to be replaced or because another block is tracked at its height.
+func (t *reorgTracker) isOrphaned(hash common.Hash, height uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.orphaned[hash]; ok {
+		return true
+	}
+	canonical, ok := t.hashes[height]
+	return ok && canonical != hash
+}
+
+// detectReorg records the latest header, fetching its ancestors until one matches the tracked chain so
+// that the tracked window is filled and replaced blocks are found. The reorg is returned, or nil
+// if the latest header extends the tracked chain.
+func (m *multiNodeClient) detectReorg(ctx context.Context, t *reorgTracker, latest *types.Header) (*reorg, error) {
+	segment := []*types.Header{latest}
+	for header := latest; uint64(len(segment)) < t.depth && header.Number.Sign() > 0; {
+		height := header.Number.Uint64() - 1
+		hash, known, untracked := t.lookup(height)
+		if known && hash == header.ParentHash || !known && !untracked {
+			break
+		}
+		parent, err := m.headerAt(ctx, new(big.Int).SetUint64(height))
+		if err != nil {
+			return nil, err
+		}
+		if parent.Hash() != header.ParentHash {
+			return nil, fmt.Errorf("upstream nodes disagree on the parent of block %d", header.Number)
+		}
+		segment = append(segment, parent)
+		header = parent
+	}
+	return t.record(segment), nil
+}
+
+// reportReorg counts and logs a reorg.
+func (m *multiNodeClient) reportReorg(r *reorg) {
+	chainReorgsCounter.WithLabelValues(m.chain).Inc()
+	chainReorgDepthHistogram.WithLabelValues(m.chain).Observe(float64(len(r.orphaned)))
+	m.mu.Lock()
+	l := m.logger
+	m.mu.Unlock()
+	if l == nil {
+		return
+	}
+	orphaned := make([]string, len(r.orphaned))
+	for i, hash := range r.orphaned {
+		orphaned[i] = hash.Hex()
+	}
+	l.WithFields(logrus.Fields{"forkBlock": r.fork, "depth": len(r.orphaned), "oldHead": r.oldHead, "newHead": r.newHead, "orphaned": orphaned}).Warn("chainReorg")
+}
+
+// Reorged reports whether receipt is for a transaction in a block orphaned by a reorg seen by the head
+// tracker. It is always false if reorg detection is disabled.
+func (m *multiNodeClient) Reorged(receipt *types.Receipt) bool {
+	t := m.routing().reorg
+	if t == nil || receipt == nil || receipt.BlockNumber == nil || !receipt.BlockNumber.IsUint64() {
+		return false
+	}
+	return t.isOrphaned(receipt.BlockHash, receipt.BlockNumber.Uint64())
+}
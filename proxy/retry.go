@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	rpcLimitExceededCode  = -32005 // JSON-RPC error code used by providers to signal rate limiting
+	rpcMethodNotFoundCode = -32601 // JSON-RPC error code of a method the node does not serve
+	rpcInvalidParamsCode  = -32602 // JSON-RPC error code also returned for unsupported variants of a method
+)
+
+// retryableRPCMessages are JSON-RPC error messages returned by a node which lacks the requested state
+// (e.g. a pruned node) rather than because the request itself is invalid.
+var retryableRPCMessages = []string{"missing trie node", "header not found", "pruned", "rate limit", "too many requests"}
+
+// SetRetryConfig sets the retry policy applied to requests routed through the node rotation.
+func (m *multiNodeClient) SetRetryConfig(cfg RetryConfig) error {
+	if cfg.MaxAttempts < 0 || cfg.InitialBackoff < 0 || cfg.MaxBackoff < 0 || cfg.AttemptTimeout < 0 {
+		return fmt.Errorf("invalid retry config %+v", cfg)
+	}
+	m.update(func(st *routingState) { st.retry = cfg.withDefaults() })
+	return nil
+}
+
+// attempts returns the number of attempts allowed for a request which can be served by n nodes.
+func (c RetryConfig) attempts(n int) int {
+	if c.MaxAttempts > 0 {
+		return c.MaxAttempts
+	}
+	return n
+}
+
+// backoff returns the time to wait before the given retry (numbered from one): the initial backoff
+// doubled for each further retry, capped at the maximum backoff, with up to half of it replaced by
+// random jitter.
+func (c RetryConfig) backoff(retry int) time.Duration {
+	if c.InitialBackoff <= 0 {
+		return 0
+	}
+	d := c.MaxBackoff
+	if shift := retry - 1; shift < 32 && c.InitialBackoff<<shift < c.MaxBackoff {
+		d = c.InitialBackoff << shift
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// wait sleeps for the backoff before the given retry, returning early with an error if ctx is done.
+func (c RetryConfig) wait(ctx context.Context, retry int) error {
+	d := c.backoff(retry)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// attemptContext returns the context of an attempt followed by remaining-1 further attempts. The attempt
+// gets an equal share of the time left before the request deadline, so that a node which does not answer
+// cannot use up the time of the attempts after it, and is also bounded by the per-attempt timeout if one
+// is configured.
+func (c RetryConfig) attemptContext(ctx context.Context, remaining int) (context.Context, context.CancelFunc) {
+	budget := c.AttemptTimeout
+	if deadline, ok := ctx.Deadline(); ok && remaining > 1 {
+		if share := time.Until(deadline) / time.Duration(remaining); budget <= 0 || share < budget {
+			budget = share
+		}
+	}
+	if budget <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, budget)
+}
+
+// callAttempt makes a single attempt on node followed by remaining-1 further attempts, bounded as
+// described by attemptContext.
+func callAttempt[T any](ctx context.Context, m *multiNodeClient, cfg RetryConfig, remaining int, node *item, fn func(context.Context, *item) (T, error)) (T, error) {
+	ctx, cancelFunc := cfg.attemptContext(ctx, remaining)
+	defer cancelFunc()
+	return callNode(ctx, m, node, fn)
+}
+
+// isMethodUnsupported reports whether err shows that the node does not serve the called method, which
+// another node may still serve.
+func isMethodUnsupported(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.ErrorCode() == rpcMethodNotFoundCode || rpcErr.ErrorCode() == rpcInvalidParamsCode
+}
+
+// isRetryable classifies an error returned by an upstream node. Timeouts, rate limiting, 5xx
+// responses and transport failures such as connection resets are retryable on another node.
+// JSON-RPC errors (reverts, nonce and other validation errors), other HTTP 4xx responses and
+// not found results are terminal since every node would give the same answer.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		if rpcErr.ErrorCode() == rpcLimitExceededCode {
+			return true
+		}
+		msg := strings.ToLower(rpcErr.Error())
+		for _, retryable := range retryableRPCMessages {
+			if strings.Contains(msg, retryable) {
+				return true
+			}
+		}
+		return false
+	}
+	return !errors.Is(err, ethereum.NotFound) && !errors.Is(err, context.Canceled)
+}
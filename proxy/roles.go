@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"errors"
+	"math/big"
+)
+
+// archiveDepth is the number of recent blocks whose state is assumed to be available on a full node
+// (the default state history retained by geth).
+const archiveDepth = 128
+
+// ErrArchiveNotSupported is returned when no upstream node can serve historical state.
+var ErrArchiveNotSupported = errors.New("no upstream node serves historical state")
+
+// stateCapable returns the nodes able to serve state at the given block. Reads more than archiveDepth
+// blocks behind the best-known head are routed to archive nodes or, if no node is tagged as an archive
+// node, to any node not tagged as a full node. A nil func (any node) is returned for recent reads.
+func (m *multiNodeClient) stateCapable(number *big.Int) func(*item) bool {
+	if number == nil || number.Sign() < 0 || !number.IsUint64() {
+		return nil // latest, pending, safe or finalized state
+	}
+	best := m.bestHead()
+	if best <= archiveDepth || number.Uint64() >= best-archiveDepth {
+		return nil
+	}
+	if anyNode(m.snapshot(), func(node *item) bool { return node.hasTag(TagArchive) }) {
+		return func(node *item) bool { return node.hasTag(TagArchive) }
+	}
+	return func(node *item) bool { return !node.hasTag(TagFull) }
+}
+
+// sendRotation returns the nodes used for transaction submission in rotation order: the send-only
+// nodes if any are configured, otherwise the nodes in read rotation.
+func (m *multiNodeClient) sendRotation() []*item {
+	if nodes := m.sendOnly(); len(nodes) > 0 {
+		return nodes
+	}
+	return m.rotation(nil)
+}
+
+// broadcastRotation returns the nodes a transaction is broadcast to: every send-only node followed by
+// the nodes in read rotation.
+func (m *multiNodeClient) broadcastRotation() []*item {
+	return append(m.sendOnly(), m.rotation(nil)...)
+}
+
+// sendOnly returns the send-only nodes in rotation order.
+func (m *multiNodeClient) sendOnly() []*item {
+	return m.order(m.snapshot(), func(node *item) bool { return node.hasTag(TagSendOnly) })
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StrategyEWMA             = "ewma"              // lowest exponentially weighted moving average latency first
+	StrategyRoundRobin       = "round-robin"       // smooth weighted round-robin using the configured node weights
+	StrategyLeastOutstanding = "least-outstanding" // fewest in-flight requests first
+	StrategyPriority         = "priority"          // strict config order
+
+	ewmaAlpha          = 0.3     // weight given to the most recent latency sample
+	ewmaFailurePenalty = timeout // latency sample recorded for failed requests
+
+	latencySamples       = 256 // size of the window of recent latencies used to compute percentiles
+	minPercentileSamples = 20  // number of samples required before percentiles are reported
+)
+
+// selector orders the nodes able to serve a request. The first node is tried first and the
+// remaining nodes are used as fallbacks in order.
+type selector interface {
+	name() string
+	order(nodes []*item) []*item
+}
+
+// nodeStateSelector is implemented by selectors which keep per-node state. order does not change the
+// state, which only advances when picked is called for a request routed to nodes[0] of an ordered
+// rotation, so that orderings made for other purposes (health checks, broadcasts etc.) do not skew
+// the selection. retain is called with the node list of each published routing snapshot and drops
+// the state of nodes no longer in it.
+type nodeStateSelector interface {
+	selector
+	picked(nodes []*item)
+	retain(nodes []*item)
+}
+
+// newSelector returns the selection strategy with the given name. The EWMA strategy is used if
+// no name is supplied.
+func newSelector(strategy string) (selector, error) {
+	switch strategy {
+	case "", StrategyEWMA:
+		return ewmaSelector{}, nil
+	case StrategyRoundRobin:
+		return &roundRobinSelector{current: make(map[string]int)}, nil
+	case StrategyLeastOutstanding:
+		return leastOutstandingSelector{}, nil
+	case StrategyPriority:
+		return prioritySelector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown selection strategy '%s'", strategy)
+	}
+}
+
+// nodeStats tracks the request load and latency of a single upstream node. Statistics are kept in
+// atomics so that recording a request never blocks routing.
+type nodeStats struct {
+	outstanding atomic.Int64
+	ewma        atomic.Int64 // moving average latency in nanoseconds, zero until the first sample has been observed
+	samples     [latencySamples]atomic.Int64
+	observed    atomic.Uint64 // number of samples observed, the next sample is stored at observed % latencySamples
+}
+
+// observe adds the latency of a successful request to the moving average and to the window of
+// recent samples used to compute latency percentiles.
+func (s *nodeStats) observe(latency time.Duration) time.Duration {
+	i := s.observed.Add(1) - 1
+	s.samples[i%latencySamples].Store(int64(latency))
+	return s.update(latency)
+}
+
+// penalize adds the failure penalty to the moving average.
+func (s *nodeStats) penalize() time.Duration {
+	return s.update(ewmaFailurePenalty)
+}
+
+func (s *nodeStats) update(latency time.Duration) time.Duration {
+	for {
+		old := s.ewma.Load()
+		next := int64(latency)
+		if old != 0 {
+			next = int64(ewmaAlpha*float64(latency) + (1-ewmaAlpha)*float64(old))
+		}
+		if s.ewma.CompareAndSwap(old, next) {
+			return time.Duration(next)
+		}
+	}
+}
+
+// latency returns the moving average latency, or zero if no sample has been observed.
+func (s *nodeStats) latency() time.Duration {
+	return time.Duration(s.ewma.Load())
+}
+
+// percentile returns the p-th percentile (0 < p <= 100) of the recent latency samples. The second
+// return value is false if fewer than minPercentileSamples samples have been observed.
+func (s *nodeStats) percentile(p float64) (time.Duration, bool) {
+	n := min(s.observed.Load(), latencySamples)
+	if n < minPercentileSamples {
+		return 0, false
+	}
+	samples := make([]time.Duration, n)
+	for i := range samples {
+		samples[i] = time.Duration(s.samples[i].Load())
+	}
+	slices.Sort(samples)
+	i := int(math.Ceil(p/100*float64(len(samples)))) - 1
+	return samples[max(0, min(i, len(samples)-1))], true
+}
+
+// prioritySelector always tries nodes in config order.
+type prioritySelector struct{}
+
+func (prioritySelector) name() string { return StrategyPriority }
+
+func (prioritySelector) order(nodes []*item) []*item { return nodes }
+
+// ewmaSelector tries the node with the lowest moving average latency first. Nodes without a latency
+// sample are tried before measured nodes, in config order, so that every node is measured rather than
+// the first node to answer keeping every request.
+type ewmaSelector struct{}
+
+func (ewmaSelector) name() string { return StrategyEWMA }
+
+func (ewmaSelector) order(nodes []*item) []*item {
+	latencies := make(map[*item]time.Duration, len(nodes))
+	for _, node := range nodes {
+		latencies[node] = node.stats.latency()
+	}
+	ordered := slices.Clone(nodes)
+	slices.SortStableFunc(ordered, func(a, b *item) int {
+		la, lb := latencies[a], latencies[b]
+		switch {
+		case la == lb:
+			return 0
+		case la == 0:
+			return -1
+		case lb == 0:
+			return 1
+		case la < lb:
+			return -1
+		default:
+			return 1
+		}
+	})
+	return ordered
+}
+
+// leastOutstandingSelector tries the node with the fewest in-flight requests first.
+type leastOutstandingSelector struct{}
+
+func (leastOutstandingSelector) name() string { return StrategyLeastOutstanding }
+
+func (leastOutstandingSelector) order(nodes []*item) []*item {
+	outstanding := make(map[*item]int64, len(nodes))
+	for _, node := range nodes {
+		outstanding[node] = node.stats.outstanding.Load()
+	}
+	ordered := slices.Clone(nodes)
+	slices.SortStableFunc(ordered, func(a, b *item) int {
+		return int(outstanding[a] - outstanding[b])
+	})
+	return ordered
+}
+
+// roundRobinSelector implements smooth weighted round-robin: each pick adds every node's weight to its
+// current value and subtracts the total weight from the picked node, which is the node with the
+// highest current value once the weights are added. The remaining nodes follow in config order.
+type roundRobinSelector struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+func (s *roundRobinSelector) name() string { return StrategyRoundRobin }
+
+func (s *roundRobinSelector) order(nodes []*item) []*item {
+	if len(nodes) < 2 {
+		return nodes
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	best := 0
+	for i, node := range nodes {
+		if s.current[node.id]+node.weight > s.current[nodes[best].id]+nodes[best].weight {
+			best = i
+		}
+	}
+	ordered := make([]*item, 0, len(nodes))
+	ordered = append(ordered, nodes[best])
+	ordered = append(ordered, nodes[:best]...)
+	return append(ordered, nodes[best+1:]...)
+}
+
+func (s *roundRobinSelector) picked(nodes []*item) {
+	if len(nodes) < 2 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for _, node := range nodes {
+		s.current[node.id] += node.weight
+		total += node.weight
+	}
+	s.current[nodes[0].id] -= total
+}
+
+func (s *roundRobinSelector) retain(nodes []*item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.current {
+		if !slices.ContainsFunc(nodes, func(node *item) bool { return node.id == id }) {
+			delete(s.current, id)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"net/http"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -11,16 +12,51 @@ import (
 type Service struct {
 	server *hTTPService
 	logger *logrus.Entry
+	client SimpleEthClient
+	beacon *BeaconClient // nil unless beacon nodes are configured
+	chains []Chain       // chains served alongside the default chain
+	cfg    *Config
 }
 
 // New constructs a Service with ethclient, logger and http server.
 func New(port int, l *logrus.Entry, client SimpleEthClient) *Service {
+	return NewFromConfig(&Config{Port: port}, l, client)
+}
+
+// NewFromConfig constructs a Service with ethclient, logger and http server
+// applying the request handling policies contained in the supplied config.
+func NewFromConfig(cfg *Config, l *logrus.Entry, client SimpleEthClient) *Service {
+	return NewWithBeacon(cfg, l, client, nil)
+}
+
+// NewWithBeacon is NewFromConfig for a service which also proxies the beacon node API to the
+// nodes of the supplied beacon client.
+func NewWithBeacon(cfg *Config, l *logrus.Entry, client SimpleEthClient, beacon *BeaconClient) *Service {
+	return NewWithChains(cfg, l, client, beacon, nil)
+}
+
+// NewWithChains is NewWithBeacon for a service which also serves the supplied chains alongside the
+// default chain of client. Requests are addressed to a chain by the /eth/v0/{chain}/ path prefix or
+// the X-Chain header.
+func NewWithChains(cfg *Config, l *logrus.Entry, client SimpleEthClient, beacon *BeaconClient, chains []Chain) *Service {
 	srv := &Service{
 		logger: l,
+		client: client,
+		beacon: beacon,
+		chains: chains,
+		cfg:    cfg,
+	}
+	api := makeProxyAPIs(client, beacon, cfg)
+	if len(chains) == 0 && cfg.Chain == "" {
+		srv.server = NewHTTPService(cfg.Port, api, l)
+		return srv
+	}
+	router := &chainRouter{defaultName: cfg.Chain, defaultHandler: api.routes(l), chains: make(map[string]http.Handler)}
+	for _, chain := range chains {
+		chainAPI := makeProxyAPIs(chain.Client, nil, chain.Config.serviceConfig(cfg))
+		router.chains[chain.Config.Name] = chainAPI.routes(l.WithField("chain", chain.Config.Name))
 	}
-	api := makeProxyAPIs(client)
-	httpSrv := NewHTTPService(port, api, l)
-	srv.server = httpSrv
+	srv.server = newHTTPService(cfg.Port, router, l)
 	return srv
 }
 
@@ -35,6 +71,17 @@ func (s *Service) Start() {
 		"buildDate":       BuildDate,
 		"commitTimestamp": CommitDate,
 	}).Info("build date")
+	if checker, ok := s.client.(healthChecker); ok {
+		checker.StartHealthChecks(s.cfg.HealthCheck, s.logger)
+	}
+	for _, chain := range s.chains {
+		if checker, ok := chain.Client.(healthChecker); ok {
+			checker.StartHealthChecks(s.cfg.HealthCheck, s.logger.WithField("chain", chain.Config.Name))
+		}
+	}
+	if s.beacon != nil {
+		s.beacon.StartHealthChecks(s.cfg.HealthCheck, s.logger)
+	}
 	s.server.Start()
 
 	s.logger.Infof("listening on port %v", s.server.Addr())
@@ -47,6 +94,23 @@ func (s *Service) Stop(sig os.Signal) {
 	if err := s.server.Stop(); err != nil {
 		s.logger.WithFields(logrus.Fields{"error": err}).Error("error stopping server")
 	}
+	if checker, ok := s.client.(healthChecker); ok {
+		checker.StopHealthChecks()
+	}
+	if s.beacon != nil {
+		s.beacon.StopHealthChecks()
+	}
+	if broadcaster, ok := s.client.(txBroadcaster); ok {
+		broadcaster.StopRebroadcasts()
+	}
+	for _, chain := range s.chains {
+		if checker, ok := chain.Client.(healthChecker); ok {
+			checker.StopHealthChecks()
+		}
+		if broadcaster, ok := chain.Client.(txBroadcaster); ok {
+			broadcaster.StopRebroadcasts()
+		}
+	}
 }
 
 // Server exposes the http server externally.
@@ -3,21 +3,54 @@ package proxy
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math/big"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -28,8 +61,77 @@ const (
 
 var (
 	dummyTx = types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1)})
+
+	dummyCallTrace     = json.RawMessage(`{"type":"CALL","from":"0x00000000000000000000000000000000000000aa","to":"0x00000000000000000000000000000000000000bb","value":"0x1","calls":[{"type":"CALL","from":"0x00000000000000000000000000000000000000bb","to":"0x00000000000000000000000000000000000000cc","value":"0x2"},{"type":"DELEGATECALL","from":"0x00000000000000000000000000000000000000bb","to":"0x00000000000000000000000000000000000000dd","value":"0x1"},{"type":"CALL","from":"0x00000000000000000000000000000000000000bb","to":"0x00000000000000000000000000000000000000ee","value":"0x5","error":"execution reverted","calls":[{"type":"CALL","from":"0x00000000000000000000000000000000000000ee","to":"0x00000000000000000000000000000000000000ff","value":"0x3"}]}]}`)
+	dummyPrestateTrace = json.RawMessage(`{"0x00000000000000000000000000000000000000aa":{"balance":"0x10"}}`)
+	dummyTransfers     = []InternalTransfer{{
+		Type:  "CALL",
+		From:  common.HexToAddress("0x00000000000000000000000000000000000000bb").Hex(),
+		To:    common.HexToAddress("0x00000000000000000000000000000000000000cc").Hex(),
+		Value: "2",
+		Depth: 1,
+	}}
+)
+
+var (
+	dummyProofAddr             = common.HexToAddress(dummyAddr)
+	dummyProofSlot             = common.HexToHash("0x01")
+	dummyStateRoot, dummyProof = mustMakeAccountProof(dummyProofAddr, big.NewInt(100), 7, dummyProofSlot, big.NewInt(42))
+	dummyStateHeader           = &types.Header{Number: big.NewInt(0), BaseFee: big.NewInt(1), ExcessBlobGas: new(uint64), Root: dummyStateRoot}
 )
 
+// proofList collects trie proof nodes in the hex encoding returned by eth_getProof.
+type proofList []string
+
+func (p *proofList) Put(key []byte, value []byte) error {
+	*p = append(*p, hexutil.Encode(value))
+	return nil
+}
+
+func (p *proofList) Delete(key []byte) error {
+	return nil
+}
+
+// mustMakeAccountProof builds a state trie holding a single account (with a single storage slot)
+// and returns the state root together with the corresponding eth_getProof result.
+func mustMakeAccountProof(addr common.Address, balance *big.Int, nonce uint64, slot common.Hash, value *big.Int) (common.Hash, *gethclient.AccountResult) {
+	db := triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil)
+
+	storageTrie := trie.NewEmpty(db)
+	encValue, _ := rlp.EncodeToBytes(value.Bytes())
+	storageKey := crypto.Keccak256(slot.Bytes())
+	storageTrie.MustUpdate(storageKey, encValue)
+	var storageProof proofList
+	if err := storageTrie.Prove(storageKey, &storageProof); err != nil {
+		panic(err)
+	}
+
+	account := &types.StateAccount{
+		Nonce:    nonce,
+		Balance:  uint256.MustFromBig(balance),
+		Root:     storageTrie.Hash(),
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	}
+	encAccount, _ := rlp.EncodeToBytes(account)
+	accountTrie := trie.NewEmpty(db)
+	accountKey := crypto.Keccak256(addr.Bytes())
+	accountTrie.MustUpdate(accountKey, encAccount)
+	var accountProof proofList
+	if err := accountTrie.Prove(accountKey, &accountProof); err != nil {
+		panic(err)
+	}
+
+	return accountTrie.Hash(), &gethclient.AccountResult{
+		Address:      addr,
+		AccountProof: accountProof,
+		Balance:      balance,
+		CodeHash:     types.EmptyCodeHash,
+		Nonce:        nonce,
+		StorageHash:  account.Root,
+		StorageProof: []gethclient.StorageResult{{Key: slot.Hex(), Value: value, Proof: storageProof}},
+	}
+}
+
 // Make sure to write some good tests
 
 var _ SimpleEthClient = (*fakeEthClient)(nil)
@@ -64,6 +166,56 @@ func (f *fakeEthClient) SendTransaction(ctx context.Context, tx *types.Transacti
 	return nil
 }
 
+func (f *fakeEthClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(2), nil
+}
+
+func (f *fakeEthClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (f *fakeEthClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return types.CopyHeader(dummyStateHeader), nil
+}
+
+func (f *fakeEthClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return 0, nil
+}
+
+func (f *fakeEthClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (f *fakeEthClient) GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	res := *dummyProof
+	if len(keys) == 0 {
+		res.StorageProof = nil
+	}
+	return &res, nil
+}
+
+func (f *fakeEthClient) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (f *fakeEthClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return 21000, nil
+}
+
+func (f *fakeEthClient) TraceTransaction(ctx context.Context, txHash common.Hash, tracer string) (json.RawMessage, error) {
+	if tracer == PrestateTracer {
+		return dummyPrestateTrace, nil
+	}
+	return dummyCallTrace, nil
+}
+
+func (f *fakeEthClient) TxPoolContentFrom(ctx context.Context, account common.Address) (*TxPoolContent, error) {
+	return &TxPoolContent{
+		Pending: map[uint64]*types.Transaction{0: dummyTx},
+		Queued:  map[uint64]*types.Transaction{2: dummyTx},
+	}, nil
+}
+
 type fakeEthClientWithErr struct {
 	err error
 }
@@ -96,6 +248,179 @@ func (f *fakeEthClientWithErr) SendTransaction(ctx context.Context, tx *types.Tr
 	return f.err
 }
 
+func (f *fakeEthClientWithErr) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(0), f.err
+}
+
+func (f *fakeEthClientWithErr) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(0), f.err
+}
+
+func (f *fakeEthClientWithErr) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{}, f.err
+}
+
+func (f *fakeEthClientWithErr) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	return nil, f.err
+}
+
+func (f *fakeEthClientWithErr) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return 0, f.err
+}
+
+func (f *fakeEthClientWithErr) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return 0, f.err
+}
+
+func (f *fakeEthClientWithErr) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return nil, f.err
+}
+
+func (f *fakeEthClientWithErr) TraceTransaction(ctx context.Context, txHash common.Hash, tracer string) (json.RawMessage, error) {
+	return nil, f.err
+}
+
+// fakeRevertError mimics the JSON-RPC error returned by a node when an eth_call reverts.
+type fakeRevertError struct {
+	reason string
+}
+
+func (e *fakeRevertError) Error() string { return "execution reverted: " + e.reason }
+
+func (e *fakeRevertError) ErrorCode() int { return 3 }
+
+func (e *fakeRevertError) ErrorData() interface{} {
+	stringType, _ := abi.NewType("string", "", nil)
+	data, _ := abi.Arguments{{Type: stringType}}.Pack(e.reason)
+	return hexutil.Encode(append(crypto.Keccak256([]byte("Error(string)"))[:4], data...))
+}
+
+type fakeEthClientWithRevert struct {
+	fakeEthClient
+}
+
+func newFakeEthClientWithRevert(_ string) (SimpleEthClient, error) {
+	return &fakeEthClientWithRevert{}, nil
+}
+
+func (f *fakeEthClientWithRevert) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	return nil, &fakeRevertError{reason: "insufficient balance"}
+}
+
+// fakeEthClientWithCallErr fails every eth_call with a JSON-RPC error other than a revert.
+type fakeEthClientWithCallErr struct {
+	fakeEthClient
+	err error
+}
+
+func newFakeEthClientWithCallErr(code int, msg string) func(string) (SimpleEthClient, error) {
+	return func(string) (SimpleEthClient, error) {
+		return &fakeEthClientWithCallErr{err: &fakeRPCError{code: code, msg: msg}}, nil
+	}
+}
+
+func (f *fakeEthClientWithCallErr) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	return nil, f.err
+}
+
+// fakeEthClientWithBadProof reports an inflated balance alongside an otherwise valid proof.
+type fakeEthClientWithBadProof struct {
+	fakeEthClient
+}
+
+func newFakeEthClientWithBadProof(_ string) (SimpleEthClient, error) {
+	return &fakeEthClientWithBadProof{}, nil
+}
+
+func (f *fakeEthClientWithBadProof) GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	res, _ := f.fakeEthClient.GetProof(ctx, account, keys, blockNumber)
+	res.Balance = new(big.Int).Add(res.Balance, big.NewInt(1))
+	return res, nil
+}
+
+// fakeEthClientWithForkedHeader reports a header with a different state root to its peers.
+type fakeEthClientWithForkedHeader struct {
+	fakeEthClient
+}
+
+func newFakeEthClientWithForkedHeader(_ string) (SimpleEthClient, error) {
+	return &fakeEthClientWithForkedHeader{}, nil
+}
+
+func (f *fakeEthClientWithForkedHeader) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header := types.CopyHeader(dummyStateHeader)
+	header.Root = common.Hash{1}
+	return header, nil
+}
+
+// fakeEthClientWithChain serves a chain of fakeChainLength headers linked by their parent hashes,
+// each with the state root of dummyStateHeader.
+type fakeEthClientWithChain struct {
+	fakeEthClient
+}
+
+const fakeChainLength = 4
+
+// fakeChain holds the headers served by fakeEthClientWithChain.
+var fakeChain = func() []*types.Header {
+	var headers []*types.Header
+	parent := common.Hash{}
+	for n := range int64(fakeChainLength) {
+		header := types.CopyHeader(dummyStateHeader)
+		header.Number, header.ParentHash = big.NewInt(n), parent
+		headers, parent = append(headers, header), header.Hash()
+	}
+	return headers
+}()
+
+func newFakeEthClientWithChain(_ string) (SimpleEthClient, error) {
+	return &fakeEthClientWithChain{}, nil
+}
+
+func (f *fakeEthClientWithChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return types.CopyHeader(fakeChain[fakeChainLength-1]), nil
+	}
+	if !number.IsInt64() || number.Int64() >= fakeChainLength {
+		return nil, ethereum.NotFound
+	}
+	return types.CopyHeader(fakeChain[number.Int64()]), nil
+}
+
+// newFakeEthClientWithoutProof returns a client that only implements SimpleEthClient (and therefore
+// neither ProofEthClient nor TxPoolEthClient).
+func newFakeEthClientWithoutProof(_ string) (SimpleEthClient, error) {
+	return struct{ SimpleEthClient }{&fakeEthClient{}}, nil
+}
+
+// fakeEthClientSyncing reports that the node is still syncing.
+type fakeEthClientSyncing struct {
+	fakeEthClient
+}
+
+func (f *fakeEthClientSyncing) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
+	return &ethereum.SyncProgress{CurrentBlock: 1, HighestBlock: 100}, nil
+}
+
+// fakeEthClientSlow answers balance requests after a delay, recording whether the request was
+// cancelled before it completed.
+type fakeEthClientSlow struct {
+	fakeEthClient
+	delay     time.Duration
+	balance   int64
+	cancelled chan struct{}
+}
+
+func (f *fakeEthClientSlow) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	select {
+	case <-time.After(f.delay):
+		return big.NewInt(f.balance), nil
+	case <-ctx.Done():
+		close(f.cancelled)
+		return nil, ctx.Err()
+	}
+}
+
 type fakeEthClientWithBlock struct {
 	fakeEthClient
 }
@@ -108,82 +433,468 @@ func (f *fakeEthClientWithBlock) BlockNumber(context.Context) (uint64, error) {
 	return rand.Uint64(), nil
 }
 
-func makeTestService(t *testing.T, urls string, constructor func(url string) (SimpleEthClient, error)) *Service {
+// fakeEthClientWithBalance reports a fixed balance for every account.
+type fakeEthClientWithBalance struct {
+	fakeEthClient
+	balance int64
+}
 
-	l, err := NewLogger("error", "plain")
+func (f *fakeEthClientWithBalance) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return big.NewInt(f.balance), nil
+}
+
+// fakeEthClientUnmined counts the transactions it is sent and reports a confirmed nonce of zero for
+// every account until mined is closed, and of one afterwards.
+type fakeEthClientUnmined struct {
+	fakeEthClient
+	sends atomic.Int64
+	mined chan struct{}
+}
+
+func (f *fakeEthClientUnmined) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	f.sends.Add(1)
+	return nil
+}
+
+func (f *fakeEthClientUnmined) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	select {
+	case <-f.mined:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// fakeEthClientAtHeight reports a fixed block height and uses the height as the balance of every
+// account so that tests can tell which node served a read. Sent transactions are counted.
+type fakeEthClientAtHeight struct {
+	fakeEthClient
+	height uint64
+	sends  atomic.Int64
+}
+
+func (f *fakeEthClientAtHeight) BlockNumber(context.Context) (uint64, error) {
+	return f.height, nil
+}
+
+func (f *fakeEthClientAtHeight) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return new(big.Int).SetUint64(f.height), nil
+}
+
+func (f *fakeEthClientAtHeight) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	f.sends.Add(1)
+	return nil
+}
+
+// fakeEthClientBlocking counts balance requests and answers them once release is closed.
+type fakeEthClientBlocking struct {
+	fakeEthClient
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (f *fakeEthClientBlocking) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	f.calls.Add(1)
+	select {
+	case <-f.release:
+		return big.NewInt(7), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fakeEthClientPersistent is a chain served over a persistent connection which can be dropped by
+// tests and which announces new heads to subscribers.
+type fakeEthClientPersistent struct {
+	fakeEthClientChain
+	down  atomic.Bool
+	heads chan *types.Header
+}
+
+func (f *fakeEthClientPersistent) Ping(ctx context.Context) error {
+	if f.down.Load() {
+		return syscall.ECONNRESET
+	}
+	return nil
+}
+
+func (f *fakeEthClientPersistent) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		for {
+			select {
+			case <-quit:
+				return nil
+			case head := <-f.heads:
+				ch <- head
+			}
+		}
+	}), nil
+}
+
+// fakeBeaconNode serves the node status, finality checkpoint and validator balance endpoints of the
+// beacon node API. Requests for other paths are echoed back.
+type fakeBeaconNode struct {
+	health       atomic.Int32 // status code of the health endpoint
+	syncDistance atomic.Uint64
+	elOffline    atomic.Bool
+	failing      atomic.Bool // answer requests other than health checks with a server error
+	requests     atomic.Int32
+}
+
+func newFakeBeaconNode() *fakeBeaconNode {
+	f := &fakeBeaconNode{}
+	f.health.Store(http.StatusOK)
+	return f
+}
+
+func (f *fakeBeaconNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	w.Header().Set("Content-Type", "application/json")
+	switch path := r.URL.Path; {
+	case path == beaconHealthPath:
+		w.WriteHeader(int(f.health.Load()))
+	case f.failing.Load():
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"code":500,"message":"internal error"}`)
+	case path == beaconSyncingPath:
+		fmt.Fprintf(w, `{"data":{"head_slot":"100","sync_distance":"%d","is_syncing":%v,"is_optimistic":false,"el_offline":%v}}`, f.syncDistance.Load(), f.syncDistance.Load() > 0, f.elOffline.Load())
+	case strings.HasPrefix(path, "/eth/v1/beacon/states/999/"):
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"code":404,"message":"state not found"}`)
+	case strings.HasSuffix(path, "/finality_checkpoints"):
+		fmt.Fprintf(w, `{"execution_optimistic":false,"finalized":true,"data":{"previous_justified":{"epoch":"9","root":"%s"},"current_justified":{"epoch":"10","root":"%s"},"finalized":{"epoch":"9","root":"%s"}}}`,
+			common.HexToHash("0x09").Hex(), common.HexToHash("0x0a").Hex(), common.HexToHash("0x09").Hex())
+	case strings.HasSuffix(path, "/validator_balances"):
+		var balances []string
+		for _, id := range r.URL.Query()["id"] {
+			if _, err := strconv.ParseUint(id, 10, 64); err == nil {
+				balances = append(balances, fmt.Sprintf(`{"index":"%s","balance":"32000000000"}`, id))
+			}
+		}
+		fmt.Fprintf(w, `{"execution_optimistic":false,"finalized":false,"data":[%s]}`, strings.Join(balances, ","))
+	default:
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Eth-Consensus-Version", "deneb")
+		w.Header().Set("X-Upstream", "internal")
+		fmt.Fprintf(w, `{"method":"%s","path":"%s","query":"%s","body":"%s","type":"%s"}`, r.Method, path, r.URL.RawQuery, body, r.Header.Get("Content-Type"))
+	}
+}
+
+// netAPI serves net_version for tests of real upstream connections.
+type netAPI struct{}
+
+func (netAPI) Version() string { return "1337" }
+
+// testCert is a certificate and key generated for TLS tests.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// makeTestCert creates a certificate from template signed by parent, or self-signed if parent is nil.
+func makeTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Use urls to embed err msg (used by )
-	cl, err := NewMultiNodeClient(urls, constructor)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore, template.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
 	if err != nil {
 		t.Fatal(err)
 	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
 
-	return New(8080, l, cl)
+// write writes the certificate and key to files named after name in dir, returning their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	certFile, keyFile = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, c.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, c.keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
 }
 
-func Test_Logger(t *testing.T) {
+// fakeEthClientChain serves a chain whose head and finalized block can be moved by tests. Receipts
+// are reported in the block with the same number as the first byte of the transaction hash. Balance,
+// receipt and call requests are counted.
+type fakeEthClientChain struct {
+	fakeEthClient
+	head, finalized atomic.Uint64
+	delay           time.Duration // added to balance requests
+	balanceCalls    atomic.Int64
+	receiptCalls    atomic.Int64
+	callCalls       atomic.Int64
+}
 
-	tests := []struct {
-		name      string
-		loglevel  string
-		logformat string
-		expectErr bool
-	}{
-		{
-			"normal-info-plain",
-			"info",
-			"plain",
-			false,
-		},
-		{
-			"normal-info-json",
-			"info",
-			"json",
-			false,
-		},
-		{
-			"normal-debug-plain",
-			"info",
-			"plain",
-			false,
-		},
-		{
-			"error-loglevel",
-			"invalid",
-			"plain",
-			true,
-		},
-		{
-			"error-logformat",
-			"info",
-			"invalid",
-			true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if _, err := NewLogger(tt.loglevel, tt.logformat); (err != nil) != tt.expectErr {
-				t.Errorf("unexpected error '%v'", err)
-			}
-		})
+func newFakeEthClientChain(_ string) (SimpleEthClient, error) {
+	return &fakeEthClientChain{}, nil
+}
 
+func (f *fakeEthClientChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	switch {
+	case number == nil:
+		return &types.Header{Number: new(big.Int).SetUint64(f.head.Load())}, nil
+	case number.Int64() == int64(rpc.FinalizedBlockNumber):
+		return &types.Header{Number: new(big.Int).SetUint64(f.finalized.Load())}, nil
+	default:
+		return &types.Header{Number: number}, nil
 	}
 }
 
-func Test_StartStop(t *testing.T) {
+func (f *fakeEthClientChain) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	f.balanceCalls.Add(1)
+	time.Sleep(f.delay)
+	return new(big.Int).SetUint64(f.head.Load()), nil
+}
 
-	srv := makeTestService(t, "-", newFakeEthClient)
+func (f *fakeEthClientChain) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	f.balanceCalls.Add(1)
+	return new(big.Int).SetUint64(f.head.Load()), nil
+}
 
-	srv.Start()
+func (f *fakeEthClientChain) CallContractAtHash(ctx context.Context, msg ethereum.CallMsg, blockHash common.Hash) ([]byte, error) {
+	f.callCalls.Add(1)
+	return blockHash[:4], nil
+}
 
-	srv.Stop(os.Kill)
+func (f *fakeEthClientChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	f.receiptCalls.Add(1)
+	return &types.Receipt{TxHash: txHash, BlockNumber: big.NewInt(int64(txHash[0]))}, nil
+}
 
+// fakeEthClientForks serves a canonical chain of linked headers which tests can replace to simulate a
+// reorg. Receipts are always reported in receiptBlock. Balance requests are counted.
+type fakeEthClientForks struct {
+	fakeEthClient
+	chain        atomic.Pointer[[]*types.Header]
+	receiptBlock *types.Header
+	balanceCalls atomic.Int64
 }
 
-func Test_SantizeConfig(t *testing.T) {
+// makeFork returns a chain of length headers whose blocks from the fork height onwards are tagged
+// with tag. Chains made with the same tag share the same blocks.
+func makeFork(length, fork int, tag byte) []*types.Header {
+	chain := make([]*types.Header, length)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i)), Difficulty: common.Big0}
+		if i > 0 {
+			chain[i].ParentHash = chain[i-1].Hash()
+		}
+		if i >= fork {
+			chain[i].Extra = []byte{tag}
+		}
+	}
+	return chain
+}
+
+func (f *fakeEthClientForks) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	chain := *f.chain.Load()
+	switch {
+	case number == nil:
+		return chain[len(chain)-1], nil
+	case number.Sign() < 0:
+		return chain[0], nil
+	case number.Int64() >= int64(len(chain)):
+		return nil, ethereum.NotFound
+	default:
+		return chain[number.Int64()], nil
+	}
+}
+
+func (f *fakeEthClientForks) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	f.balanceCalls.Add(1)
+	return big.NewInt(1), nil
+}
+
+func (f *fakeEthClientForks) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return &types.Receipt{TxHash: txHash, BlockHash: f.receiptBlock.Hash(), BlockNumber: f.receiptBlock.Number, Logs: []*types.Log{}}, nil
+}
+
+// fakeRPCError is a JSON-RPC error response with the given code.
+type fakeRPCError struct {
+	code int
+	msg  string
+}
+
+func (e *fakeRPCError) Error() string { return e.msg }
+
+func (e *fakeRPCError) ErrorCode() int { return e.code }
+
+// fakeEthClientWithoutTxPool counts txpool_contentFrom requests, answering that the method does not exist.
+type fakeEthClientWithoutTxPool struct {
+	fakeEthClient
+	calls *atomic.Int64
+}
+
+func (f *fakeEthClientWithoutTxPool) TxPoolContentFrom(ctx context.Context, account common.Address) (*TxPoolContent, error) {
+	f.calls.Add(1)
+	return nil, &fakeRPCError{code: rpcMethodNotFoundCode, msg: "the method txpool_contentFrom does not exist/is not available"}
+}
+
+// fakeEthClientFailing counts balance requests, failing each of them with err.
+type fakeEthClientFailing struct {
+	fakeEthClient
+	err   error
+	calls *atomic.Int64
+}
+
+func (f *fakeEthClientFailing) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	f.calls.Add(1)
+	return nil, f.err
+}
+
+// fakeEthClientOnChain reports the given chain ID.
+type fakeEthClientOnChain struct {
+	fakeEthClient
+	chainID int64
+}
+
+func (f *fakeEthClientOnChain) ChainID(context.Context) (*big.Int, error) {
+	return big.NewInt(f.chainID), nil
+}
+
+// fakeChainNodes are the chain ID and balance reported by the fake node of each chain.
+var fakeChainNodes = map[string]struct{ chainID, balance int64 }{
+	"mainnet": {1, 1},
+	"sepolia": {11155111, 2},
+	"base":    {8453, 3},
+}
+
+// newFakeChainNode dials the fake node of the chain named by the node URL.
+func newFakeChainNode(cfg NodeConfig) (SimpleEthClient, error) {
+	node, ok := fakeChainNodes[cfg.URL]
+	if !ok {
+		return nil, fmt.Errorf("unknown chain '%s'", cfg.URL)
+	}
+	return &fakeEthClientOnChainWithBalance{fakeEthClientWithBalance{balance: node.balance}, node.chainID}, nil
+}
+
+// fakeEthClientOnChainWithBalance reports the given chain ID and a fixed balance for every account.
+type fakeEthClientOnChainWithBalance struct {
+	fakeEthClientWithBalance
+	chainID int64
+}
+
+func (f *fakeEthClientOnChainWithBalance) ChainID(context.Context) (*big.Int, error) {
+	return big.NewInt(f.chainID), nil
+}
+
+func makeTestService(t *testing.T, urls string, constructor func(url string) (SimpleEthClient, error)) *Service {
+
+	l, err := NewLogger("error", "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Use urls to embed err msg (used by )
+	cl, err := NewMultiNodeClient(urls, constructor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(8080, l, cl)
+}
+
+func makeTestServiceWithNodes(t *testing.T, nodes []NodeConfig, constructor func(url string) (SimpleEthClient, error)) *Service {
+
+	l, err := NewLogger("error", "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl, err := NewMultiNodeClientFromNodes(nodes, constructor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(8080, l, cl)
+}
+
+func Test_Logger(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		loglevel  string
+		logformat string
+		expectErr bool
+	}{
+		{
+			"normal-info-plain",
+			"info",
+			"plain",
+			false,
+		},
+		{
+			"normal-info-json",
+			"info",
+			"json",
+			false,
+		},
+		{
+			"normal-debug-plain",
+			"info",
+			"plain",
+			false,
+		},
+		{
+			"error-loglevel",
+			"invalid",
+			"plain",
+			true,
+		},
+		{
+			"error-logformat",
+			"info",
+			"invalid",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLogger(tt.loglevel, tt.logformat); (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error '%v'", err)
+			}
+		})
+
+	}
+}
+
+func Test_StartStop(t *testing.T) {
+
+	srv := makeTestService(t, "-", newFakeEthClient)
+
+	srv.Start()
+
+	srv.Stop(os.Kill)
+
+}
+
+func Test_SantizeConfig(t *testing.T) {
 
 	tests := []struct {
 		name           string
@@ -238,6 +949,21 @@ func Test_SantizeConfig(t *testing.T) {
 				return cfg
 			},
 		},
+		{
+			"health-checks-disabled",
+			func() Config {
+				cfg := emptyConfig
+				cfg.HealthCheck.Disabled = true
+				cfg.HealthCheck.Interval = time.Minute
+				return cfg
+			},
+			func() Config {
+				cfg := defaultConfig
+				cfg.HealthCheck.Disabled = true
+				cfg.HealthCheck.Interval = 0
+				return cfg
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -253,6 +979,34 @@ func Test_SantizeConfig(t *testing.T) {
 	}
 }
 
+func Test_ConfigValidate(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		admin       AdminConfig
+		urls        string
+		chains      []ChainConfig
+		expectedErr string
+	}{
+		{"admin-disabled", AdminConfig{}, "", nil, ""},
+		{"admin-with-token", AdminConfig{Enabled: true, Token: "secret"}, "", nil, ""},
+		{"admin-without-token", AdminConfig{Enabled: true}, "", nil, "admin api enabled without a token"},
+		{"node-urls", AdminConfig{}, "https://mainnet.infura.io/v3/key,ws://localhost:8546,ipc:///geth.ipc,/var/lib/geth/geth.ipc,${ETH_PROXY_UNSET_URL}", nil, ""},
+		{"unknown-scheme", AdminConfig{}, "htps://mainnet.infura.io/v3/4c664372f60943f690c615f182d50c63", nil, "unsupported node url 'htps://mainnet.infura.io/v3/xxxxx': http(s)://, ws(s)://, ipc:// or an absolute IPC socket path expected"},
+		{"chain-relative-path", AdminConfig{}, "", []ChainConfig{{Name: "sepolia", URLs: "geth.ipc"}}, "unsupported node url 'geth.ipc': http(s)://, ws(s)://, ipc:// or an absolute IPC socket path expected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Admin: tt.admin, URLs: tt.urls, Chains: tt.chains}
+			err := cfg.Validate()
+			if (err != nil) != (tt.expectedErr != "") || (err != nil && err.Error() != tt.expectedErr) {
+				t.Errorf("unexpected error, got %v want '%s'", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
 func Test_MultiNodeClient(t *testing.T) {
 
 	tests := []struct {
@@ -290,193 +1044,3295 @@ func Test_MultiNodeClient(t *testing.T) {
 			100,
 		},
 		{
-			"error",
-			func() string { return "testurl1,testurl2" },
-			newFakeEthClientErr,
-			true,
-			0,
+			"error",
+			func() string { return "testurl1,testurl2" },
+			newFakeEthClientErr,
+			true,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl, err := NewMultiNodeClient(tt.urls(), tt.constructor)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("unexpected error: %v", err)
+
+			}
+			if err != nil {
+				return
+			}
+			if g, w := len(cl.snapshot()), tt.expectedNodeCount; g != w {
+				t.Errorf("unexpected node count, got %v, want %v", g, w)
+			}
+			if g, w := cl.routing().selector.name(), StrategyEWMA; g != w {
+				t.Errorf("unexpected default selection strategy, got %v, want %v", g, w)
+			}
+		})
+	}
+
+}
+
+func Test_CircuitBreaker(t *testing.T) {
+
+	now := time.Unix(0, 0)
+	b := newCircuitBreaker(HealthCheckConfig{FailureThreshold: 2, SuccessThreshold: 2, Cooldown: time.Minute})
+	b.now = func() time.Time { return now }
+
+	steps := []struct {
+		name     string
+		event    func() BreakerState
+		expected BreakerState
+	}{
+		{"first-failure", b.failure, BreakerClosed},
+		{"success-resets-failures", b.success, BreakerClosed},
+		{"failure", b.failure, BreakerClosed},
+		{"threshold-opens", b.failure, BreakerOpen},
+		{"open-during-cooldown", b.State, BreakerOpen},
+		{"half-open-after-cooldown", func() BreakerState { now = now.Add(time.Minute); return b.State() }, BreakerHalfOpen},
+		{"half-open-failure-reopens", b.failure, BreakerOpen},
+		{"half-open-again", func() BreakerState { now = now.Add(time.Minute); return b.State() }, BreakerHalfOpen},
+		{"half-open-success", b.success, BreakerHalfOpen},
+		{"success-threshold-closes", b.success, BreakerClosed},
+	}
+
+	for _, step := range steps {
+		if g, w := step.event(), step.expected; g != w {
+			t.Fatalf("%v: unexpected breaker state, got %v want %v", step.name, g, w)
+		}
+	}
+}
+
+func Test_HealthChecks(t *testing.T) {
+
+	broken := &fakeEthClientWithErr{err: errors.New("connection refused")}
+	cl, err := NewMultiNodeClient("ok,broken,syncing", func(url string) (SimpleEthClient, error) {
+		switch url {
+		case "broken":
+			return broken, nil
+		case "syncing":
+			return &fakeEthClientSyncing{}, nil
+		default:
+			return &fakeEthClient{}, nil
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := HealthCheckConfig{FailureThreshold: 2, SuccessThreshold: 1, Cooldown: time.Hour}.withDefaults()
+	now := time.Now()
+	for _, node := range cl.snapshot() {
+		node.breaker.configure(cfg)
+		node.breaker.now = func() time.Time { return now }
+	}
+
+	states := func() map[string]BreakerState {
+		m := make(map[string]BreakerState)
+		for _, h := range cl.NodeHealth() {
+			m[h.ID] = h.State
+		}
+		return m
+	}
+
+	ctx := context.Background()
+
+	cl.probeNodes(ctx, cfg)
+	if g, w := states(), map[string]BreakerState{"0": BreakerClosed, "1": BreakerClosed, "2": BreakerClosed}; fmt.Sprint(g) != fmt.Sprint(w) {
+		t.Fatalf("unexpected states after first probe, got %v want %v", g, w)
+	}
+
+	cl.probeNodes(ctx, cfg)
+	if g, w := states(), map[string]BreakerState{"0": BreakerClosed, "1": BreakerOpen, "2": BreakerOpen}; fmt.Sprint(g) != fmt.Sprint(w) {
+		t.Fatalf("unexpected states after second probe, got %v want %v", g, w)
+	}
+
+	for _, h := range cl.NodeHealth() {
+		if h.State == BreakerOpen && h.Error == "" {
+			t.Errorf("node %v: expected probe error to be reported", h.ID)
+		}
+	}
+
+	// open nodes are taken out of rotation
+	if nodes := cl.rotation(nil); len(nodes) != 1 || nodes[0].id != "0" {
+		t.Fatalf("expected only node 0 in rotation, got %d nodes", len(nodes))
+	}
+	if _, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// recovered node is brought back once the cooldown has elapsed and probes succeed
+	broken.err = nil
+	now = now.Add(time.Hour)
+	cl.probeNodes(ctx, cfg)
+	if g, w := states(), map[string]BreakerState{"0": BreakerClosed, "1": BreakerClosed, "2": BreakerOpen}; fmt.Sprint(g) != fmt.Sprint(w) {
+		t.Fatalf("unexpected states after recovery, got %v want %v", g, w)
+	}
+
+	t.Run("node-far-ahead", func(t *testing.T) {
+		cl, err := NewMultiNodeClient("100,101,99999999", func(url string) (SimpleEthClient, error) {
+			height, err := strconv.ParseUint(url, 10, 64)
+			return &fakeEthClientAtHeight{height: height}, err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := HealthCheckConfig{FailureThreshold: 1, MaxLag: 3}.withDefaults()
+		for _, node := range cl.snapshot() {
+			node.breaker.configure(cfg)
+		}
+		cl.probeNodes(ctx, cfg)
+		for _, h := range cl.NodeHealth() {
+			if h.State != BreakerClosed || h.Error != "" {
+				t.Errorf("node %v: expected probe to pass, got state %v error '%v'", h.ID, h.State, h.Error)
+			}
+		}
+		if g, w := len(cl.rotation(nil)), 3; g != w {
+			t.Errorf("unexpected number of nodes in rotation, got %d want %d", g, w)
+		}
+	})
+
+	t.Run("node-behind-agreed-head", func(t *testing.T) {
+		cl, err := NewMultiNodeClient("10,100,101", func(url string) (SimpleEthClient, error) {
+			height, err := strconv.ParseUint(url, 10, 64)
+			return &fakeEthClientAtHeight{height: height}, err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := HealthCheckConfig{FailureThreshold: 1, MaxLag: 3}.withDefaults()
+		for _, node := range cl.snapshot() {
+			node.breaker.configure(cfg)
+		}
+		cl.probeNodes(ctx, cfg)
+		for _, h := range cl.NodeHealth() {
+			if g, w := h.State, map[string]BreakerState{"0": BreakerOpen, "1": BreakerClosed, "2": BreakerClosed}[h.ID]; g != w {
+				t.Errorf("node %v: unexpected state, got %v want %v", h.ID, g, w)
+			}
+		}
+	})
+}
+
+func Test_SelectionStrategies(t *testing.T) {
+
+	newClient := func(t *testing.T, strategy string) *multiNodeClient {
+		cl, err := NewMultiNodeClientFromNodes([]NodeConfig{{URL: "a", Weight: 3}, {URL: "b"}, {URL: "c"}}, newFakeEthClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetSelectionStrategy(strategy); err != nil {
+			t.Fatal(err)
+		}
+		return cl
+	}
+
+	// order returns the rotation of the next request, recording that the request was routed to its
+	// first node.
+	order := func(cl *multiNodeClient) string {
+		var ids []string
+		nodes := cl.rotation(nil)
+		for _, node := range nodes {
+			ids = append(ids, node.id)
+		}
+		cl.picked(nodes)
+		return strings.Join(ids, ",")
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		setup    func(nodes []*item)
+		expected []string // order returned by consecutive selections
+	}{
+		{
+			"priority",
+			StrategyPriority,
+			func(nodes []*item) { nodes[0].stats.observe(time.Second) },
+			[]string{"0,1,2", "0,1,2"},
+		},
+		{
+			"ewma",
+			StrategyEWMA,
+			func(nodes []*item) {
+				nodes[0].stats.observe(50 * time.Millisecond)
+				nodes[1].stats.observe(10 * time.Millisecond)
+			},
+			[]string{"2,1,0"},
+		},
+		{
+			"least-outstanding",
+			StrategyLeastOutstanding,
+			func(nodes []*item) {
+				nodes[0].stats.outstanding.Store(3)
+				nodes[1].stats.outstanding.Store(1)
+			},
+			[]string{"2,1,0"},
+		},
+		{
+			"weighted-round-robin",
+			StrategyRoundRobin,
+			func(nodes []*item) {},
+			[]string{"0,1,2", "1,0,2", "0,1,2", "2,0,1", "0,1,2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := newClient(t, tt.strategy)
+			tt.setup(cl.snapshot())
+			for i, w := range tt.expected {
+				if g := order(cl); g != w {
+					t.Fatalf("selection %d: unexpected node order, got %v want %v", i, g, w)
+				}
+			}
+		})
+	}
+
+	t.Run("request-distribution", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			strategy string
+			nodes    []NodeConfig
+			expected []int64 // balance requests served by each node
+		}{
+			// The slow node answers the first request, after which the fast node is measured and
+			// keeps the remaining requests.
+			{"ewma", StrategyEWMA, []NodeConfig{{URL: "slow"}, {URL: "fast"}}, []int64{1, 9}},
+			// Orderings made outside requests (health checks, head tracking) do not advance the
+			// round-robin state.
+			{"round-robin", StrategyRoundRobin, []NodeConfig{{URL: "fast", Weight: 4}, {URL: "fast"}}, []int64{8, 2}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var clients []*fakeEthClientChain
+				cl, err := NewMultiNodeClientFromNodes(tt.nodes, func(url string) (SimpleEthClient, error) {
+					c := &fakeEthClientChain{}
+					if url == "slow" {
+						c.delay = 20 * time.Millisecond
+					}
+					clients = append(clients, c)
+					return c, nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := cl.SetSelectionStrategy(tt.strategy); err != nil {
+					t.Fatal(err)
+				}
+				ctx := context.Background()
+				for i := 0; i < 10; i++ {
+					if _, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), nil); err != nil {
+						t.Fatal(err)
+					}
+					cl.bestHead()
+					if err := cl.checkChainTips(ctx); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for i, c := range clients {
+					if g, w := c.balanceCalls.Load(), tt.expected[i]; g != w {
+						t.Errorf("node %d: unexpected balance requests, got %d want %d", i, g, w)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("round-robin-removed-node-pruned", func(t *testing.T) {
+		cl := newClient(t, StrategyRoundRobin)
+		order(cl)
+		if err := cl.RemoveNode(context.Background(), "0"); err != nil {
+			t.Fatal(err)
+		}
+		sel := cl.routing().selector.(*roundRobinSelector)
+		if _, ok := sel.current["0"]; ok {
+			t.Fatal("expected round-robin state of the removed node to be dropped")
+		}
+		if g, w := len(sel.current), 2; g != w {
+			t.Errorf("unexpected round-robin state size, got %d want %d", g, w)
+		}
+	})
+
+	t.Run("unknown-strategy", func(t *testing.T) {
+		cl := newClient(t, StrategyPriority)
+		if err := cl.SetSelectionStrategy("random"); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func Test_HedgedReads(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		hedge           HedgeConfig
+		expectedBalance int64
+		expectCancelled bool
+	}{
+		{"hedging-disabled", HedgeConfig{}, 1, false},
+		{"hedge-wins", HedgeConfig{Percentile: 99, MaxDelay: 20 * time.Millisecond}, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slow := &fakeEthClientSlow{delay: 300 * time.Millisecond, balance: 1, cancelled: make(chan struct{})}
+			fast := &fakeEthClientSlow{delay: 0, balance: 2, cancelled: make(chan struct{})}
+			cl, err := NewMultiNodeClient("slow,fast", func(url string) (SimpleEthClient, error) {
+				if url == "slow" {
+					return slow, nil
+				}
+				return fast, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetHedgeConfig(tt.hedge); err != nil {
+				t.Fatal(err)
+			}
+
+			bal, err := cl.BalanceAt(context.Background(), common.HexToAddress(dummyAddr), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g, w := bal.Int64(), tt.expectedBalance; g != w {
+				t.Errorf("unexpected balance, got %v want %v", g, w)
+			}
+
+			select {
+			case <-slow.cancelled:
+				if !tt.expectCancelled {
+					t.Error("slow request unexpectedly cancelled")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.expectCancelled {
+					t.Error("expected slow request to be cancelled")
+				}
+			}
+		})
+	}
+
+	t.Run("percentile-delay", func(t *testing.T) {
+		node := &item{stats: &nodeStats{}}
+		cfg := HedgeConfig{Percentile: 90, MinDelay: time.Millisecond, MaxDelay: time.Second}.withDefaults()
+		if g, w := cfg.hedgeDelay(node), time.Second; g != w {
+			t.Fatalf("expected max delay without samples, got %v want %v", g, w)
+		}
+		for i := 1; i <= 100; i++ {
+			node.stats.observe(time.Duration(i) * time.Millisecond)
+		}
+		if g, w := cfg.hedgeDelay(node), 90*time.Millisecond; g != w {
+			t.Fatalf("unexpected hedge delay, got %v want %v", g, w)
+		}
+	})
+
+	t.Run("retry-policy", func(t *testing.T) {
+		calls := &atomic.Int64{}
+		cl, err := NewMultiNodeClient("a,b,c", func(string) (SimpleEthClient, error) {
+			return &fakeEthClientFailing{err: errors.New("connection refused"), calls: calls}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetHedgeConfig(HedgeConfig{Percentile: 99, MaxDelay: time.Second}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetRetryConfig(RetryConfig{MaxAttempts: 5, InitialBackoff: time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cl.BalanceAt(context.Background(), common.HexToAddress(dummyAddr), nil); err == nil {
+			t.Fatal("expected error")
+		}
+		if g, w := calls.Load(), int64(5); g != w {
+			t.Errorf("unexpected number of attempts, got %v want %v", g, w)
+		}
+	})
+
+	t.Run("attempt-timeout", func(t *testing.T) {
+		slow := &fakeEthClientSlow{delay: time.Second, balance: 1, cancelled: make(chan struct{})}
+		fast := &fakeEthClientSlow{balance: 2, cancelled: make(chan struct{})}
+		cl, err := NewMultiNodeClient("slow,fast", func(url string) (SimpleEthClient, error) {
+			if url == "slow" {
+				return slow, nil
+			}
+			return fast, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetHedgeConfig(HedgeConfig{Percentile: 99, MinDelay: time.Second, MaxDelay: time.Second}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetRetryConfig(RetryConfig{AttemptTimeout: 20 * time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		bal, err := cl.BalanceAt(context.Background(), common.HexToAddress(dummyAddr), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := bal.Int64(), int64(2); g != w {
+			t.Errorf("unexpected balance, got %v want %v", g, w)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("slow attempt was not timed out, request took %v", elapsed)
+		}
+	})
+}
+
+func Test_QuorumReads(t *testing.T) {
+
+	constructorByURL := func(url string) (SimpleEthClient, error) {
+		balance, err := strconv.ParseInt(url, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &fakeEthClientWithBalance{balance: balance}, nil
+	}
+
+	tests := []struct {
+		name             string
+		urls             string
+		quorum           QuorumConfig
+		expectedResponse any
+		expectedCode     int
+	}{
+		{
+			"quorum-disabled",
+			"1,2,3",
+			QuorumConfig{},
+			&BalanceResponse{Balance: "1"},
+			http.StatusOK,
+		},
+		{
+			"agree",
+			"1,2,1",
+			QuorumConfig{Agree: 2},
+			&BalanceResponse{Balance: "1", Quorum: 2},
+			http.StatusOK,
+		},
+		{
+			"diverge",
+			"1,2,3",
+			QuorumConfig{Agree: 2},
+			map[string]string{"error": "quorum read error: upstream nodes disagree: block 0, quorum 2 of 3 [node 0: 1, node 1: 2, node 2: 3]"},
+			http.StatusBadGateway,
+		},
+		{
+			"diverge-node-subset",
+			"1,2,1",
+			QuorumConfig{Nodes: 2, Agree: 2},
+			map[string]string{"error": "quorum read error: upstream nodes disagree: block 0, quorum 2 of 2 [node 0: 1, node 1: 2]"},
+			http.StatusBadGateway,
+		},
+		{
+			"too-few-nodes",
+			"1",
+			QuorumConfig{Agree: 2},
+			map[string]string{"error": "quorum read error: " + errQuorumUnavailable.Error() + ": quorum 2 exceeds available node count 1"},
+			http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			l, err := NewLogger("error", "plain")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cl, err := NewMultiNodeClient(tt.urls, constructorByURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+				t.Fatal(err)
+			}
+			s := NewFromConfig(&Config{Port: 8080, QuorumReads: QuorumReadsConfig{Balance: tt.quorum}}, l, cl)
+			s.Start()
+			defer s.Stop(os.Kill)
+
+			time.Sleep(10 * time.Millisecond)
+
+			b, code, err := executeRequest(http.MethodGet, fmt.Sprintf("http://0.0.0.0%v%v%v", s.Server().Addr(), EthV0BalancePrfx, dummyAddr))
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+			if g, w := code, tt.expectedCode; g != w {
+				t.Errorf("%v unexpected response code, want %v got %v", tt.name, w, g)
+			}
+
+			expectedJSON, _ := json.Marshal(tt.expectedResponse)
+
+			if g, w := b, expectedJSON; !bytes.Equal(g, w) {
+				t.Errorf("%v unexpected response, want %s, got %s", tt.name, w, g)
+			}
+		})
+	}
+}
+
+func Test_BroadcastTx(t *testing.T) {
+
+	constructorByURL := func(url string) (SimpleEthClient, error) {
+		switch url {
+		case "known":
+			return newFakeEthClientWithErr("already known")
+		case "down":
+			return newFakeEthClientWithErr("connection refused")
+		default:
+			return newFakeEthClient(url)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		urls            string
+		broadcast       BroadcastConfig
+		expectedResults []BroadcastResult
+		expectedErr     string
+	}{
+		{
+			"broadcast-disabled",
+			"known,ok",
+			BroadcastConfig{},
+			nil,
+			"",
+		},
+		{
+			"broadcast",
+			"ok,known,down",
+			BroadcastConfig{Enabled: true},
+			[]BroadcastResult{
+				{Node: "0", Accepted: true},
+				{Node: "1", Accepted: true, Known: true},
+				{Node: "2", Error: "connection refused"},
+			},
+			"",
+		},
+		{
+			"broadcast-rejected",
+			"down,down",
+			BroadcastConfig{Enabled: true},
+			[]BroadcastResult{
+				{Node: "0", Error: "connection refused"},
+				{Node: "1", Error: "connection refused"},
+			},
+			"transaction rejected by every node [node 0: connection refused, node 1: connection refused]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl, err := NewMultiNodeClient(tt.urls, constructorByURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetBroadcastConfig(tt.broadcast); err != nil {
+				t.Fatal(err)
+			}
+			results, err := cl.BroadcastTransaction(context.Background(), dummyTx)
+			if err != nil && err.Error() != tt.expectedErr || err == nil && tt.expectedErr != "" {
+				t.Fatalf("unexpected error, got %v want '%v'", err, tt.expectedErr)
+			}
+			if !reflect.DeepEqual(results, tt.expectedResults) {
+				t.Errorf("unexpected results, got %+v want %+v", results, tt.expectedResults)
+			}
+		})
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedTx := func(nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: nonce}), types.LatestSignerForChainID(big.NewInt(1)), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+
+	t.Run("rebroadcast-until-mined", func(t *testing.T) {
+		node := &fakeEthClientUnmined{mined: make(chan struct{})}
+		cl, err := NewMultiNodeClient("node", func(string) (SimpleEthClient, error) { return node, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetBroadcastConfig(BroadcastConfig{Enabled: true, RebroadcastInterval: 5 * time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+		defer cl.StopRebroadcasts()
+
+		if _, err := cl.BroadcastTransaction(context.Background(), signedTx(0)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if g := node.sends.Load(); g < 3 {
+			t.Fatalf("expected unmined tx to be re-broadcast, got %v sends", g)
+		}
+		close(node.mined)
+		time.Sleep(20 * time.Millisecond)
+		sends := node.sends.Load()
+		time.Sleep(50 * time.Millisecond)
+		if g, w := node.sends.Load(), sends; g != w {
+			t.Errorf("expected re-broadcasts to stop once mined, got %v sends want %v", g, w)
+		}
+	})
+
+	t.Run("rebroadcast-pending-limit", func(t *testing.T) {
+		node := &fakeEthClientUnmined{mined: make(chan struct{})}
+		cl, err := NewMultiNodeClient("node", func(string) (SimpleEthClient, error) { return node, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetBroadcastConfig(BroadcastConfig{Enabled: true, RebroadcastInterval: time.Hour, MaxPending: 2}); err != nil {
+			t.Fatal(err)
+		}
+		defer cl.StopRebroadcasts()
+
+		for nonce := range uint64(3) {
+			if _, err := cl.BroadcastTransaction(context.Background(), signedTx(nonce)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r := cl.rebroadcaster
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if g, w := len(r.pending), 2; g != w {
+			t.Errorf("unexpected number of pending re-broadcasts, got %d want %d", g, w)
+		}
+	})
+
+	t.Run("rebroadcast-replaced", func(t *testing.T) {
+		node := &fakeEthClientUnmined{mined: make(chan struct{})}
+		close(node.mined) // another transaction with the same nonce was mined
+		cl, err := NewMultiNodeClient("node", func(string) (SimpleEthClient, error) { return node, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetBroadcastConfig(BroadcastConfig{Enabled: true, RebroadcastInterval: time.Hour}); err != nil {
+			t.Fatal(err)
+		}
+		defer cl.StopRebroadcasts()
+
+		if _, err := cl.BroadcastTransaction(context.Background(), signedTx(0)); err != nil {
+			t.Fatal(err)
+		}
+		cl.rebroadcastPending(cl.rebroadcaster)
+		if g := node.sends.Load(); g != 1 {
+			t.Errorf("expected replaced tx not to be re-broadcast, got %v sends", g)
+		}
+		if g := len(cl.rebroadcaster.pending); g != 0 {
+			t.Errorf("expected replaced tx to be dropped, got %d pending", g)
+		}
+	})
+}
+
+func Test_HeadRouting(t *testing.T) {
+
+	constructorByURL := func(url string) (SimpleEthClient, error) {
+		height, err := strconv.ParseUint(url, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &fakeEthClientAtHeight{height: height}, nil
+	}
+
+	tests := []struct {
+		name            string
+		urls            string
+		head            HeadConfig
+		probeHeads      bool
+		minBlock        uint64
+		expectedBalance uint64
+		expectedNodes   []string // nodes eligible for the read
+		expectedErr     error
+	}{
+		{"head-routing-disabled", "10,100,101", HeadConfig{}, true, 0, 10, []string{"0", "1", "2"}, nil},
+		{"heads-unknown", "10,100,101", HeadConfig{MaxLag: 5}, false, 0, 10, []string{"0", "1", "2"}, nil},
+		{"lagging-node-excluded", "10,100,101", HeadConfig{MaxLag: 5}, true, 0, 100, []string{"1", "2"}, nil},
+		{"lag-within-limit", "10,100,101", HeadConfig{MaxLag: 100}, true, 0, 10, []string{"0", "1", "2"}, nil},
+		{"node-far-ahead", "100,101,99999999", HeadConfig{MaxLag: 5}, true, 0, 100, []string{"0", "1", "2"}, nil},
+		{"min-block", "10,100,101", HeadConfig{}, false, 50, 100, []string{"1", "2"}, nil},
+		{"min-block-not-reached", "10,100,101", HeadConfig{}, false, 200, 0, nil, ErrMinBlockNotReached},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl, err := NewMultiNodeClient(tt.urls, constructorByURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+				t.Fatal(err)
+			}
+			cl.SetHeadConfig(tt.head)
+			if tt.probeHeads {
+				cl.probeNodes(context.Background(), HealthCheckConfig{}.withDefaults())
+			}
+
+			ctx := context.Background()
+			if tt.minBlock > 0 {
+				ctx = WithMinBlock(ctx, tt.minBlock)
+			}
+			nodes, _ := cl.readRotation(ctx, nil)
+			var ids []string
+			for _, node := range nodes {
+				ids = append(ids, node.id)
+			}
+			if !reflect.DeepEqual(ids, tt.expectedNodes) {
+				t.Errorf("unexpected read nodes, got %v want %v", ids, tt.expectedNodes)
+			}
+			bal, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), nil)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("unexpected error, got %v want %v", err, tt.expectedErr)
+			}
+			if err == nil && bal.Uint64() != tt.expectedBalance {
+				t.Errorf("unexpected balance, got %v want %v", bal, tt.expectedBalance)
+			}
+		})
+	}
+
+	t.Run("only-lagging-nodes", func(t *testing.T) {
+		cl, err := NewMultiNodeClientFromNodes([]NodeConfig{{URL: "10", Tags: []string{TagArchive}}, {URL: "100"}, {URL: "101"}}, constructorByURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl.SetHeadConfig(HeadConfig{MaxLag: 5})
+		cl.probeNodes(context.Background(), HealthCheckConfig{}.withDefaults())
+		if _, err := cl.readRotation(context.Background(), func(node *item) bool { return node.hasTag(TagArchive) }); !errors.Is(err, ErrNodesLagging) {
+			t.Fatalf("unexpected error, got %v want %v", err, ErrNodesLagging)
+		}
+	})
+
+	t.Run("unhealthy-node-heads-ignored", func(t *testing.T) {
+		cl, err := NewMultiNodeClient("100,150,160", constructorByURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl.SetHeadConfig(HeadConfig{MaxLag: 5})
+		cl.probeNodes(context.Background(), HealthCheckConfig{}.withDefaults())
+		if _, err := cl.DrainNode("1"); err != nil {
+			t.Fatal(err)
+		}
+		broken := cl.snapshot()[2].breaker
+		broken.configure(HealthCheckConfig{FailureThreshold: 1}.withDefaults())
+		broken.failure()
+		if g, w := cl.bestHead(), uint64(100); g != w {
+			t.Errorf("unexpected best head, got %d want %d", g, w)
+		}
+	})
+
+	t.Run("node-far-ahead-recent-state", func(t *testing.T) {
+		cl, err := NewMultiNodeClientFromNodes([]NodeConfig{{URL: "1000", Tags: []string{TagArchive}}, {URL: "1001"}, {URL: "99999999"}}, constructorByURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl.probeNodes(context.Background(), HealthCheckConfig{}.withDefaults())
+		if cl.stateCapable(big.NewInt(990)) != nil {
+			t.Error("expected a recent read to be served by any node")
+		}
+	})
+
+	t.Run("min-block-param", func(t *testing.T) {
+		s := makeTestService(t, "10,100", constructorByURL)
+		s.Start()
+		defer s.Stop(os.Kill)
+
+		time.Sleep(10 * time.Millisecond)
+
+		for _, tc := range []struct {
+			query        string
+			expectedCode int
+		}{
+			{"?minblock=100", http.StatusOK},
+			{"?minblock=101", http.StatusServiceUnavailable},
+			{"?minblock=latest", http.StatusBadRequest},
+		} {
+			_, code, err := executeRequest(http.MethodGet, fmt.Sprintf("http://0.0.0.0%v%v%v%v", s.Server().Addr(), EthV0BalancePrfx, dummyAddr, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g, w := code, tc.expectedCode; g != w {
+				t.Errorf("%v: unexpected response code, want %v got %v", tc.query, w, g)
+			}
+		}
+	})
+}
+
+func Test_NodeRoles(t *testing.T) {
+
+	clients := make(map[string]*fakeEthClientAtHeight)
+	constructorByURL := func(url string) (SimpleEthClient, error) {
+		height, err := strconv.ParseUint(url, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		clients[url] = &fakeEthClientAtHeight{height: height}
+		return clients[url], nil
+	}
+
+	tests := []struct {
+		name            string
+		nodes           []NodeConfig
+		block           int64 // balance read at this block (latest if negative)
+		openBreakers    []string
+		expectedBalance uint64
+		expectedErr     error
+	}{
+		{"latest-read", []NodeConfig{{URL: "1000", Tags: []string{TagFull}}, {URL: "1001", Tags: []string{TagArchive}}}, -1, nil, 1000, nil},
+		{"recent-read", []NodeConfig{{URL: "1000", Tags: []string{TagFull}}, {URL: "1001", Tags: []string{TagArchive}}}, 1000, nil, 1000, nil},
+		{"historical-read-archive", []NodeConfig{{URL: "1000", Tags: []string{TagFull}}, {URL: "1001", Tags: []string{TagArchive}}}, 10, nil, 1001, nil},
+		{"historical-read-untagged", []NodeConfig{{URL: "1000", Tags: []string{TagFull}}, {URL: "1001"}}, 10, nil, 1001, nil},
+		{"historical-read-full-only", []NodeConfig{{URL: "1000", Tags: []string{TagFull}}, {URL: "1001", Tags: []string{TagFull}}}, 10, nil, 0, ErrArchiveNotSupported},
+		{"primary-preferred", []NodeConfig{{URL: "1000", Tags: []string{TagFallback}}, {URL: "1001", Tags: []string{TagPrimary}}}, -1, nil, 1001, nil},
+		{"fallback-when-primaries-unhealthy", []NodeConfig{{URL: "1000", Tags: []string{TagFallback}}, {URL: "1001", Tags: []string{TagPrimary}}, {URL: "1002"}}, -1, []string{"1", "2"}, 1000, nil},
+		{"send-only-not-read", []NodeConfig{{URL: "1000", Tags: []string{TagSendOnly}}, {URL: "1001"}}, -1, nil, 1001, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl, err := NewMultiNodeClientFromNodes(tt.nodes, constructorByURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			cl.refreshHeads(ctx, cl.snapshot())
+			for _, id := range tt.openBreakers {
+				node, err := cl.node(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for node.breaker.available() {
+					node.breaker.failure()
+				}
+			}
+
+			var block *big.Int
+			if tt.block >= 0 {
+				block = big.NewInt(tt.block)
+			}
+			bal, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), block)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("unexpected error, got %v want %v", err, tt.expectedErr)
+			}
+			if err == nil && bal.Uint64() != tt.expectedBalance {
+				t.Errorf("unexpected balance, got %v want %v", bal, tt.expectedBalance)
+			}
+		})
+	}
+
+	t.Run("send-only-pinned", func(t *testing.T) {
+		cl, err := NewMultiNodeClientFromNodes([]NodeConfig{{URL: "1000"}, {URL: "1001", Tags: []string{TagSendOnly}}, {URL: "1002", Tags: []string{TagSendOnly}}}, constructorByURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := types.NewTx(&types.LegacyTx{Nonce: 1})
+		if err := cl.SendTransaction(context.Background(), tx); err != nil {
+			t.Fatal(err)
+		}
+		if g := clients["1000"].sends.Load(); g != 0 {
+			t.Errorf("expected no transactions sent to the read node, got %d", g)
+		}
+		if g := clients["1001"].sends.Load() + clients["1002"].sends.Load(); g != 1 {
+			t.Errorf("expected 1 transaction sent to send-only nodes, got %d", g)
+		}
+	})
+
+	t.Run("broadcast-send-only-and-full-nodes", func(t *testing.T) {
+		cl, err := NewMultiNodeClientFromNodes([]NodeConfig{{URL: "2000"}, {URL: "2001"}, {URL: "2002", Tags: []string{TagSendOnly}}}, constructorByURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetBroadcastConfig(BroadcastConfig{Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+		results, err := cl.BroadcastTransaction(context.Background(), types.NewTx(&types.LegacyTx{Nonce: 1}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var nodes []string
+		for _, res := range results {
+			nodes = append(nodes, res.Node)
+		}
+		slices.Sort(nodes)
+		if g, w := nodes, []string{"0", "1", "2"}; !reflect.DeepEqual(g, w) {
+			t.Errorf("unexpected broadcast nodes, got %v want %v", g, w)
+		}
+		for _, url := range []string{"2000", "2001", "2002"} {
+			if g := clients[url].sends.Load(); g != 1 {
+				t.Errorf("expected 1 transaction sent to node %s, got %d", url, g)
+			}
+		}
+	})
+}
+
+func Test_RetryPolicy(t *testing.T) {
+
+	t.Run("classification", func(t *testing.T) {
+		for _, tc := range []struct {
+			err       error
+			retryable bool
+		}{
+			{context.DeadlineExceeded, true},
+			{fmt.Errorf("read tcp: %w", syscall.ECONNRESET), true},
+			{rpc.HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+			{rpc.HTTPError{StatusCode: http.StatusBadGateway}, true},
+			{rpc.HTTPError{StatusCode: http.StatusUnauthorized}, false},
+			{&fakeRPCError{code: rpcLimitExceededCode, msg: "limit exceeded"}, true},
+			{&fakeRPCError{code: -32000, msg: "missing trie node 1a2b"}, true},
+			{&fakeRPCError{code: -32000, msg: "nonce too low"}, false},
+			{&fakeRevertError{reason: "insufficient balance"}, false},
+			{ethereum.NotFound, false},
+			{context.Canceled, false},
+		} {
+			if g, w := isRetryable(tc.err), tc.retryable; g != w {
+				t.Errorf("%v: unexpected classification, got retryable=%v want %v", tc.err, g, w)
+			}
+		}
+	})
+
+	tests := []struct {
+		name          string
+		retry         RetryConfig
+		err           error
+		expectedCalls int64
+	}{
+		{"retryable-error-tries-each-node", RetryConfig{}, errors.New("connection refused"), 3},
+		{"retryable-error-max-attempts", RetryConfig{MaxAttempts: 5, InitialBackoff: time.Millisecond}, errors.New("connection refused"), 5},
+		{"terminal-error", RetryConfig{MaxAttempts: 5}, &fakeRPCError{code: -32000, msg: "nonce too low"}, 1},
+		{"not-found", RetryConfig{}, ethereum.NotFound, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &atomic.Int64{}
+			cl, err := NewMultiNodeClient("a,b,c", func(string) (SimpleEthClient, error) {
+				return &fakeEthClientFailing{err: tt.err, calls: calls}, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cl.SetRetryConfig(tt.retry); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cl.BalanceAt(context.Background(), common.HexToAddress(dummyAddr), nil); !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error, got %v want %v", err, tt.err)
+			}
+			if g, w := calls.Load(), tt.expectedCalls; g != w {
+				t.Errorf("unexpected number of attempts, got %v want %v", g, w)
+			}
+		})
+	}
+
+	t.Run("attempt-timeout", func(t *testing.T) {
+		slow := &fakeEthClientSlow{delay: time.Second, balance: 1, cancelled: make(chan struct{})}
+		fast := &fakeEthClientSlow{balance: 2, cancelled: make(chan struct{})}
+		cl, err := NewMultiNodeClient("slow,fast", func(url string) (SimpleEthClient, error) {
+			if url == "slow" {
+				return slow, nil
+			}
+			return fast, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetRetryConfig(RetryConfig{AttemptTimeout: 20 * time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		bal, err := cl.BalanceAt(context.Background(), common.HexToAddress(dummyAddr), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := bal.Int64(), int64(2); g != w {
+			t.Errorf("unexpected balance, got %v want %v", g, w)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("slow attempt was not timed out, request took %v", elapsed)
+		}
+	})
+
+	t.Run("attempt-share-of-deadline", func(t *testing.T) {
+		hung := &fakeEthClientSlow{delay: time.Hour, balance: 1, cancelled: make(chan struct{})}
+		fast := &fakeEthClientSlow{balance: 2, cancelled: make(chan struct{})}
+		cl, err := NewMultiNodeClient("hung,fast", func(url string) (SimpleEthClient, error) {
+			if url == "hung" {
+				return hung, nil
+			}
+			return fast, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancelFunc := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancelFunc()
+		bal, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := bal.Int64(), int64(2); g != w {
+			t.Errorf("unexpected balance, got %v want %v", g, w)
+		}
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		cfg := RetryConfig{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}.withDefaults()
+		for retry, upper := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 10: 40 * time.Millisecond} {
+			if d := cfg.backoff(retry); d < upper/2 || d > upper {
+				t.Errorf("retry %d: backoff %v outside [%v, %v]", retry, d, upper/2, upper)
+			}
+		}
+	})
+}
+
+func Test_NodeBudgets(t *testing.T) {
+
+	t.Run("rate-limit", func(t *testing.T) {
+		b := newNodeBudget("test", NodeConfig{RateLimit: 1})
+		if b.nearLimit() {
+			t.Fatal("unused node unexpectedly near its rate limit")
+		}
+		b.record(1)
+		if !b.nearLimit() {
+			t.Fatal("expected node to be near its rate limit")
+		}
+	})
+
+	t.Run("rolling-daily-quota", func(t *testing.T) {
+		now := time.Unix(1_700_000_000, 0)
+		b := newNodeBudget("test", NodeConfig{DailyQuota: 10})
+		b.now = func() time.Time { return now }
+		b.record(8)
+		if b.nearLimit() {
+			t.Fatal("node unexpectedly near its quota")
+		}
+		now = now.Add(time.Hour)
+		b.record(1)
+		if g, w := b.used(), int64(9); g != w {
+			t.Fatalf("unexpected quota usage, got %v want %v", g, w)
+		}
+		if !b.nearLimit() {
+			t.Fatal("expected node to be near its quota")
+		}
+		now = now.Add(23 * time.Hour)
+		if g, w := b.used(), int64(1); g != w {
+			t.Fatalf("unexpected quota usage after the first hour left the window, got %v want %v", g, w)
+		}
+	})
+
+	t.Run("routing-prefers-nodes-within-budget", func(t *testing.T) {
+		cl, err := NewMultiNodeClientFromNodes([]NodeConfig{{URL: "a", DailyQuota: 1}, {URL: "b"}}, newFakeEthClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cl.NonceAt(context.Background(), common.HexToAddress(dummyAddr), nil); err != nil {
+			t.Fatal(err)
+		}
+		if nodes := cl.rotation(nil); nodes[0].id != "1" || nodes[1].id != "0" {
+			t.Fatalf("expected node over its quota to be tried last, got order %v, %v", nodes[0].id, nodes[1].id)
+		}
+	})
+
+	t.Run("429-saturates-node", func(t *testing.T) {
+		calls := &atomic.Int64{}
+		cl, err := NewMultiNodeClient("limited,ok", func(url string) (SimpleEthClient, error) {
+			if url == "limited" {
+				return &fakeEthClientFailing{err: rpc.HTTPError{StatusCode: http.StatusTooManyRequests}, calls: calls}, nil
+			}
+			return newFakeEthClient(url)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := cl.BalanceAt(context.Background(), common.HexToAddress(dummyAddr), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if g, w := calls.Load(), int64(1); g != w {
+			t.Errorf("expected saturated node to be skipped, got %v calls want %v", g, w)
+		}
+	})
+}
+
+func Test_Redial(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		chainID       int64 // chain ID reported by the redialled node
+		expectedNodes []string
+		expectedErr   string
+	}{
+		{"reconnects", 1, []string{"0", "1", "2"}, ""},
+		{"wrong-chain", 5, []string{"0", "2"}, "chain id 5 does not match chain id 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var up atomic.Bool
+			cl, err := NewMultiNodeClient("a,down,b", func(url string) (SimpleEthClient, error) {
+				if url == "down" {
+					if !up.Load() {
+						return nil, errors.New("connection refused")
+					}
+					return &fakeEthClientOnChain{chainID: tt.chainID}, nil
+				}
+				return &fakeEthClientOnChain{chainID: 1}, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			health := cl.NodeHealth()
+			if len(health) != 3 || health[1].ID != "1" || !health[1].Disconnected || health[1].Error != "connection refused" {
+				t.Fatalf("expected node 1 to be reported as disconnected, got %+v", health)
+			}
+			if nodes := cl.rotation(nil); len(nodes) != 2 {
+				t.Fatalf("expected 2 nodes in rotation, got %d", len(nodes))
+			}
+
+			cl.StartHealthChecks(HealthCheckConfig{RedialBackoff: time.Millisecond, MaxRedialBackoff: 5 * time.Millisecond}, nil)
+			defer cl.StopHealthChecks()
+			up.Store(true)
+
+			deadline := time.Now().Add(time.Second)
+			for len(cl.snapshot()) != len(tt.expectedNodes) || tt.expectedErr != "" && !strings.Contains(cl.NodeHealth()[1].Error, tt.expectedErr) {
+				if time.Now().After(deadline) {
+					t.Fatalf("timed out waiting for redial, health %+v", cl.NodeHealth())
+				}
+				time.Sleep(time.Millisecond)
+			}
+			var ids []string
+			for _, node := range cl.snapshot() {
+				ids = append(ids, node.id)
+			}
+			if !reflect.DeepEqual(ids, tt.expectedNodes) {
+				t.Errorf("unexpected nodes, got %v want %v", ids, tt.expectedNodes)
+			}
+		})
+	}
+}
+
+func Test_Coalescing(t *testing.T) {
+
+	const callers = 50
+
+	tests := []struct {
+		name          string
+		enabled       bool
+		cancelFirst   bool // the caller which starts the shared call gives up before it completes
+		expectedCalls int64
+	}{
+		{"disabled", false, false, callers},
+		{"enabled", true, false, 1},
+		{"first-caller-cancelled", true, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &fakeEthClientBlocking{release: make(chan struct{})}
+			cl, err := NewMultiNodeClient("a", func(string) (SimpleEthClient, error) { return node, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			cl.SetCoalesceConfig(CoalesceConfig{Enabled: tt.enabled})
+
+			firstCtx, cancelFirst := context.WithCancel(context.Background())
+			defer cancelFirst()
+			firstErr := make(chan error, 1)
+			go func() {
+				_, err := cl.BalanceAt(firstCtx, common.HexToAddress(dummyAddr), nil)
+				firstErr <- err
+			}()
+			for node.calls.Load() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			if tt.cancelFirst {
+				cancelFirst()
+				if err := <-firstErr; !errors.Is(err, context.Canceled) {
+					t.Fatalf("expected the first caller to be cancelled, got %v", err)
+				}
+			}
+
+			var wg sync.WaitGroup
+			balances := make([]*big.Int, callers-1)
+			errs := make([]error, callers-1)
+			for i := range balances {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					balances[i], errs[i] = cl.BalanceAt(context.Background(), common.HexToAddress(dummyAddr), nil)
+				}()
+			}
+			for !tt.enabled && node.calls.Load() < callers {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(20 * time.Millisecond) // let the callers join the call in flight
+			close(node.release)
+			wg.Wait()
+
+			for i := range balances {
+				if errs[i] != nil || balances[i].Int64() != 7 {
+					t.Fatalf("caller %d: unexpected result %v, %v", i, balances[i], errs[i])
+				}
+			}
+			if g, w := node.calls.Load(), tt.expectedCalls; g != w {
+				t.Errorf("unexpected number of upstream calls, got %d want %d", g, w)
+			}
+		})
+	}
+}
+
+func Test_ResponseCache(t *testing.T) {
+
+	chain := &fakeEthClientChain{}
+	chain.head.Store(100)
+	chain.finalized.Store(90)
+	cl, err := NewMultiNodeClient("a", func(string) (SimpleEthClient, error) { return chain, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.SetCacheConfig(CacheConfig{Enabled: true})
+	ctx := context.Background()
+
+	finalizedTx, unfinalizedTx := common.Hash{80}, common.Hash{95}
+	readBalance := func(number *big.Int) {
+		if _, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), number); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readReceipts := func() {
+		for _, hash := range []common.Hash{finalizedTx, unfinalizedTx} {
+			if _, err := cl.TransactionReceipt(ctx, hash); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	steps := []struct {
+		name                 string
+		newHead              uint64 // head reported to the cache before the step, if set
+		read                 func()
+		expectedBalanceCalls int64
+		expectedReceiptCalls int64
+	}{
+		{"head-unknown", 0, func() { readBalance(nil); readBalance(nil) }, 2, 0},
+		{"latest-cached", 100, func() { readBalance(nil); readBalance(nil) }, 3, 0},
+		{"latest-invalidated-by-new-head", 101, func() { readBalance(nil); readBalance(nil) }, 4, 0},
+		{"finalized-block-cached", 0, func() { readBalance(big.NewInt(50)); readBalance(big.NewInt(50)) }, 5, 0},
+		{"receipts", 0, func() { readReceipts(); readReceipts() }, 5, 2},
+		{"unfinalized-receipt-invalidated", 102, readReceipts, 5, 3},
+		{"finalized-block-kept", 103, func() { readBalance(big.NewInt(50)) }, 5, 3},
+	}
+
+	for _, step := range steps {
+		if step.newHead > 0 {
+			chain.head.Store(step.newHead)
+			cl.pollHead(ctx)
+		}
+		step.read()
+		if g, w := chain.balanceCalls.Load(), step.expectedBalanceCalls; g != w {
+			t.Errorf("%v: unexpected balance calls, got %d want %d", step.name, g, w)
+		}
+		if g, w := chain.receiptCalls.Load(), step.expectedReceiptCalls; g != w {
+			t.Errorf("%v: unexpected receipt calls, got %d want %d", step.name, g, w)
+		}
+	}
+
+	t.Run("block-hash", func(t *testing.T) {
+		balanceCalls, hash := chain.balanceCalls.Load(), common.Hash{0xab}
+		for i := 0; i < 2; i++ {
+			if _, err := cl.BalanceAtHash(ctx, common.HexToAddress(dummyAddr), hash); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cl.CallContractAtHash(ctx, ethereum.CallMsg{To: &common.Address{1}, Data: []byte{1}}, hash); err != nil {
+				t.Fatal(err)
+			}
+			chain.head.Store(chain.head.Load() + 1)
+			cl.pollHead(ctx)
+		}
+		if g, w := chain.balanceCalls.Load()-balanceCalls, int64(1); g != w {
+			t.Errorf("unexpected balance calls, got %d want %d", g, w)
+		}
+		if g, w := chain.callCalls.Load(), int64(1); g != w {
+			t.Errorf("unexpected calls, got %d want %d", g, w)
+		}
+		if _, err := cl.CallContractAtHash(ctx, ethereum.CallMsg{To: &common.Address{1}, Data: []byte{2}}, hash); err != nil {
+			t.Fatal(err)
+		}
+		if g, w := chain.callCalls.Load(), int64(2); g != w {
+			t.Errorf("unexpected calls after changing the call data, got %d want %d", g, w)
+		}
+	})
+
+	t.Run("size-limit", func(t *testing.T) {
+		c := newResponseCache(CacheConfig{MaxBytes: 1000}, "")
+		c.setHead(1, 0)
+		for i := 0; i < 100; i++ {
+			c.put(fmt.Sprintf("key%d", i), big.NewInt(int64(i)), scopeHead, 1)
+		}
+		if c.bytes > c.maxBytes || c.lru.Len() == 0 || c.lru.Len() == 100 {
+			t.Errorf("unexpected cache size %d bytes (%d entries), limit %d", c.bytes, c.lru.Len(), c.maxBytes)
+		}
+		if _, ok := c.get("key99"); !ok {
+			t.Errorf("expected the most recent entry to be cached")
+		}
+		if _, ok := c.get("key0"); ok {
+			t.Errorf("expected the least recently used entry to be evicted")
+		}
+	})
+}
+
+func Test_UpstreamAuth(t *testing.T) {
+
+	const key = "4c664372f60943f690c615f182d50c63"
+	jwtSecret := strings.Repeat("ab", 32)
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ETH_PROXY_TEST_KEY", key)
+	t.Setenv("ETH_PROXY_TEST_HEADER", "env-header")
+
+	t.Run("redact-url", func(t *testing.T) {
+		tests := []struct {
+			url      string
+			expected string
+		}{
+			{"https://mainnet.infura.io/v3/" + key, "https://mainnet.infura.io/v3/xxxxx"},
+			{"wss://mainnet.infura.io/ws/v3/" + key, "wss://mainnet.infura.io/ws/v3/xxxxx"},
+			{"https://user:" + key + "@node.example.com:8545/rpc?apikey=" + key, "https://xxxxx@node.example.com:8545/rpc?apikey=xxxxx"},
+			{"https://mainnet.infura.io/v3/${INFURA_KEY}", "https://mainnet.infura.io/v3/${INFURA_KEY}"},
+			{"http://localhost:8545", "http://localhost:8545"},
+			{"/var/lib/geth/geth.ipc", "/var/lib/geth/geth.ipc"},
+			{"htps://mainnet.infura.io/v3/" + key, "htps://mainnet.infura.io/v3/xxxxx"},
+			{"mainnet.infura.io/v3/" + key, "xxxxx/v3/xxxxx"},
+		}
+		for _, tt := range tests {
+			if g, w := RedactURL(tt.url), tt.expected; g != w {
+				t.Errorf("unexpected redacted url, got %v want %v", g, w)
+			}
+		}
+	})
+
+	t.Run("secrets", func(t *testing.T) {
+		tests := []struct {
+			value       string
+			expected    string
+			expectedErr bool
+		}{
+			{"inline", "inline", false},
+			{"env:ETH_PROXY_TEST_HEADER", "env-header", false},
+			{"env:ETH_PROXY_TEST_UNSET", "", true},
+			{"file:" + secretFile, "file-token", false},
+			{"file:" + secretFile + ".missing", "", true},
+		}
+		for _, tt := range tests {
+			secret, err := resolveSecret(tt.value)
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("%v: unexpected error %v", tt.value, err)
+			}
+			if g, w := secret, tt.expected; g != w {
+				t.Errorf("%v: unexpected secret, got %v want %v", tt.value, g, w)
+			}
+		}
+		url, err := expandURL("https://mainnet.infura.io/v3/${ETH_PROXY_TEST_KEY}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := url, "https://mainnet.infura.io/v3/"+key; g != w {
+			t.Errorf("unexpected expanded url, got %v want %v", g, w)
+		}
+		if _, err := expandURL("https://mainnet.infura.io/v3/${ETH_PROXY_TEST_UNSET}"); err == nil {
+			t.Errorf("expected error for unset environment variable")
+		}
+	})
+
+	t.Run("request-auth", func(t *testing.T) {
+		srv := rpc.NewServer()
+		if err := srv.RegisterName("net", netAPI{}); err != nil {
+			t.Fatal(err)
+		}
+		defer srv.Stop()
+		var headers atomic.Pointer[http.Header]
+		var paths atomic.Pointer[string]
+		httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h, p := r.Header.Clone(), r.URL.Path
+			headers.Store(&h)
+			paths.Store(&p)
+			srv.ServeHTTP(w, r)
+		}))
+		defer httpSrv.Close()
+		u, _ := url.Parse(httpSrv.URL)
+
+		tests := []struct {
+			name          string
+			url           string
+			auth          AuthConfig
+			expectedAuth  string // prefix of the Authorization header
+			expectedExtra string // value of the X-Api-Key header
+			expectedErr   bool
+		}{
+			{"key-in-url", httpSrv.URL + "/v3/${ETH_PROXY_TEST_KEY}", AuthConfig{}, "", "", false},
+			{"basic", "http://user:" + key + "@" + u.Host + "/", AuthConfig{}, "Basic " + base64.StdEncoding.EncodeToString([]byte("user:"+key)), "", false},
+			{"basic-config", httpSrv.URL, AuthConfig{Username: "user", Password: "env:ETH_PROXY_TEST_KEY"}, "Basic " + base64.StdEncoding.EncodeToString([]byte("user:"+key)), "", false},
+			{"bearer-headers", httpSrv.URL, AuthConfig{Bearer: "file:" + secretFile, Headers: map[string]string{"X-Api-Key": "env:ETH_PROXY_TEST_HEADER"}}, "Bearer file-token", "env-header", false},
+			{"jwt", httpSrv.URL, AuthConfig{JWTSecret: "0x" + jwtSecret}, "Bearer ey", "", false},
+			{"jwt-invalid-secret", httpSrv.URL, AuthConfig{JWTSecret: "abcd"}, "", "", true},
+			{"several-schemes", httpSrv.URL, AuthConfig{Bearer: "token", JWTSecret: jwtSecret}, "", "", true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				client, err := NewEthClientFromConfig(NodeConfig{URL: tt.url, Auth: tt.auth})
+				if tt.expectedErr {
+					if err == nil {
+						t.Fatalf("expected error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer client.(*ethClient).Close()
+				if err := client.(pinger).Ping(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				h := *headers.Load()
+				if g, w := h.Get("Authorization"), tt.expectedAuth; !strings.HasPrefix(g, w) || (w == "" && g != "") {
+					t.Errorf("unexpected authorization header, got %v want %v", g, w)
+				}
+				if g, w := h.Get("X-Api-Key"), tt.expectedExtra; g != w {
+					t.Errorf("unexpected X-Api-Key header, got %v want %v", g, w)
+				}
+				if tt.name == "key-in-url" {
+					if g, w := *paths.Load(), "/v3/"+key; g != w {
+						t.Errorf("unexpected request path, got %v want %v", g, w)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("redacted-errors", func(t *testing.T) {
+		httpClient, err := NewEthClient("http://127.0.0.1:1/v3/" + key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer httpClient.(*ethClient).Close()
+		_, callErr := httpClient.BlockNumber(context.Background())
+		_, dialErr := NewMultiNodeClientWithDialer([]NodeConfig{{URL: "ws://127.0.0.1:1/v3/${ETH_PROXY_TEST_KEY}"}, {URL: "ws://127.0.0.1:1/v3/" + key}}, NewEthClientFromConfig)
+		for _, err := range []error{callErr, dialErr} {
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if strings.Contains(err.Error(), key) {
+				t.Errorf("secret in error message: %v", err)
+			}
+		}
+		if !strings.Contains(dialErr.Error(), "url='ws://127.0.0.1:1/v3/xxxxx'") {
+			t.Errorf("expected redacted url in error message: %v", dialErr)
+		}
+
+		cl, err := NewMultiNodeClientFromNodes([]NodeConfig{{URL: "https://mainnet.infura.io/v3/" + key}}, func(string) (SimpleEthClient, error) { return &fakeEthClient{}, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := cl.Nodes()[0].URL, "https://mainnet.infura.io/v3/xxxxx"; g != w {
+			t.Errorf("unexpected node url, got %v want %v", g, w)
+		}
+	})
+}
+
+func Test_Transports(t *testing.T) {
+
+	t.Run("transport-of", func(t *testing.T) {
+		tests := []struct {
+			url      string
+			expected Transport
+		}{
+			{"https://mainnet.infura.io/v3/key", TransportHTTP},
+			{"HTTP://localhost:8545", TransportHTTP},
+			{"ws://localhost:8546", TransportWS},
+			{"wss://mainnet.infura.io/ws/v3/key", TransportWS},
+			{"/var/lib/geth/geth.ipc", TransportIPC},
+			{"ipc:///var/lib/geth/geth.ipc", TransportIPC},
+			{"htps://mainnet.infura.io/v3/key", TransportUnknown},
+			{"mainnet.infura.io/v3/key", TransportUnknown},
+			{"geth.ipc", TransportUnknown},
+		}
+		for _, tt := range tests {
+			if g, w := TransportOf(tt.url), tt.expected; g != w {
+				t.Errorf("%v: unexpected transport, got %v want %v", tt.url, g, w)
+			}
+		}
+		const key = "4c664372f60943f690c615f182d50c63"
+		_, err := NewEthClient("htps://mainnet.infura.io/v3/" + key)
+		if g, w := fmt.Sprint(err), "unsupported node url 'htps://mainnet.infura.io/v3/xxxxx': http(s)://, ws(s)://, ipc:// or an absolute IPC socket path expected"; g != w {
+			t.Errorf("unexpected dial error, got %v want %v", g, w)
+		}
+		if g, w := dialURL("ipc:///var/lib/geth/geth.ipc"), "/var/lib/geth/geth.ipc"; g != w {
+			t.Errorf("unexpected dial url, got %v want %v", g, w)
+		}
+	})
+
+	t.Run("ws-reconnect", func(t *testing.T) {
+		srv := rpc.NewServer()
+		if err := srv.RegisterName("net", netAPI{}); err != nil {
+			t.Fatal(err)
+		}
+		defer srv.Stop()
+		httpSrv := httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+		defer httpSrv.Close()
+
+		client, err := NewEthClient("ws" + strings.TrimPrefix(httpSrv.URL, "http"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.(*ethClient).Close()
+		ctx := context.Background()
+		if err := client.(pinger).Ping(ctx); err != nil {
+			t.Fatal(err)
+		}
+		httpSrv.CloseClientConnections()
+		var pingErr error
+		for i := 0; i < 50; i++ {
+			if pingErr = client.(pinger).Ping(ctx); pingErr == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if pingErr != nil {
+			t.Fatalf("connection was not restored: %v", pingErr)
+		}
+	})
+
+	t.Run("keepalive", func(t *testing.T) {
+		node := &fakeEthClientPersistent{}
+		cl, err := NewMultiNodeClient("ws://a,https://b", func(url string) (SimpleEthClient, error) {
+			if url == "ws://a" {
+				return node, nil
+			}
+			return &fakeEthClientPersistent{}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl.StartHealthChecks(HealthCheckConfig{FailureThreshold: 1, Keepalive: -1}, nil)
+		defer cl.StopHealthChecks()
+		ws := cl.snapshot()[0]
+		reconnectsBefore := testutil.ToFloat64(nodeReconnectsCounter.WithLabelValues(ws.id))
+
+		steps := []struct {
+			name               string
+			down               bool
+			expectedLost       bool
+			expectedState      BreakerState
+			expectedReconnects float64
+		}{
+			{"connected", false, false, BreakerClosed, 0},
+			{"dropped", true, true, BreakerOpen, 0},
+			{"restored", false, false, BreakerOpen, 1}, // the breaker waits for its cooldown
+		}
+		for _, step := range steps {
+			node.down.Store(step.down)
+			cl.keepalive(context.Background())
+			if g, w := ws.connLost.Load(), step.expectedLost; g != w {
+				t.Errorf("%v: unexpected connection lost flag, got %v want %v", step.name, g, w)
+			}
+			if g, w := ws.breaker.State(), step.expectedState; g != w {
+				t.Errorf("%v: unexpected breaker state, got %v want %v", step.name, g, w)
+			}
+			if g, w := testutil.ToFloat64(nodeReconnectsCounter.WithLabelValues(ws.id))-reconnectsBefore, step.expectedReconnects; g != w {
+				t.Errorf("%v: unexpected reconnects, got %v want %v", step.name, g, w)
+			}
+			if g := cl.snapshot()[1].breaker.State(); g != BreakerClosed {
+				t.Errorf("%v: http node should not be pinged, breaker %v", step.name, g)
+			}
+		}
+	})
+
+	t.Run("head-subscription", func(t *testing.T) {
+		node := &fakeEthClientPersistent{heads: make(chan *types.Header)}
+		node.head.Store(10)
+		cl, err := NewMultiNodeClient("ws://a", func(string) (SimpleEthClient, error) { return node, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl.SetCacheConfig(CacheConfig{Enabled: true, HeadInterval: time.Hour})
+		cl.StartHealthChecks(HealthCheckConfig{Keepalive: -1}, nil)
+		defer cl.StopHealthChecks()
+		cache := cl.routing().cache
+
+		for _, head := range []uint64{11, 12} {
+			node.head.Store(head)
+			node.heads <- &types.Header{Number: new(big.Int).SetUint64(head)} // blocks until subscribed
+			deadline := time.Now().Add(time.Second)
+			for current, _ := cache.current(); current != head; current, _ = cache.current() {
+				if time.Now().After(deadline) {
+					t.Fatalf("head %d announced by subscription was not tracked, current %d", head, current)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+	})
+}
+
+func Test_UpstreamTLS(t *testing.T) {
+
+	dir := t.TempDir()
+	ca := makeTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil)
+	otherCA := makeTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "other-ca"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil)
+	server := makeTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "node.internal"},
+		DNSNames:    []string{"node.internal"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	clientTemplate := func() *x509.Certificate {
+		return &x509.Certificate{Subject: pkix.Name{CommonName: "eth-proxy"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}
+	}
+	client := makeTestCert(t, clientTemplate(), ca)
+	untrustedClient := makeTestCert(t, clientTemplate(), otherCA)
+	caFile, _ := ca.write(t, dir, "ca")
+	otherCAFile, _ := otherCA.write(t, dir, "other-ca")
+	certFile, keyFile := client.write(t, dir, "client")
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("net", netAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	ws := srv.WebsocketHandler([]string{"*"})
+	httpSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			ws.ServeHTTP(w, r)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	httpSrv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.cert.Raw}, PrivateKey: server.key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MaxVersion:   tls.VersionTLS12,
+	}
+	httpSrv.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	httpSrv.StartTLS()
+	defer httpSrv.Close()
+	urls := []string{httpSrv.URL, "wss" + strings.TrimPrefix(httpSrv.URL, "https")}
+
+	ping := func(cfg NodeConfig) error {
+		c, err := NewEthClientFromConfig(cfg)
+		if err != nil {
+			return err
+		}
+		defer c.(*ethClient).Close()
+		return c.(pinger).Ping(context.Background())
+	}
+
+	t.Run("settings", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			tls         TLSConfig
+			expectedErr bool
+		}{
+			{"mtls", TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, false},
+			{"server-name", TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "node.internal"}, false},
+			{"wrong-server-name", TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "other.internal"}, true},
+			{"untrusted-server", TLSConfig{CAFile: otherCAFile, CertFile: certFile, KeyFile: keyFile}, true},
+			{"no-client-cert", TLSConfig{CAFile: caFile}, true},
+			{"system-roots", TLSConfig{CertFile: certFile, KeyFile: keyFile}, true},
+			{"min-version", TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"}, false},
+			{"min-version-unsupported", TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"}, true},
+			{"min-version-invalid", TLSConfig{CAFile: caFile, MinVersion: "1.4"}, true},
+			{"missing-key", TLSConfig{CAFile: caFile, CertFile: certFile}, true},
+			{"missing-file", TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}, true},
+		}
+
+		for _, tt := range tests {
+			for _, url := range urls {
+				t.Run(tt.name+"-"+string(TransportOf(url)), func(t *testing.T) {
+					err := ping(NodeConfig{URL: url, TLS: tt.tls})
+					if (err != nil) != tt.expectedErr {
+						t.Errorf("unexpected error %v", err)
+					}
+				})
+			}
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		reloadCert, reloadKey := untrustedClient.write(t, dir, "reload")
+		c, err := NewEthClientFromConfig(NodeConfig{URL: urls[0], TLS: TLSConfig{CAFile: caFile, CertFile: reloadCert, KeyFile: reloadKey}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.(*ethClient).Close()
+		if err := c.(pinger).Ping(context.Background()); err == nil {
+			t.Fatalf("expected the untrusted client certificate to be rejected")
+		}
+
+		client.write(t, dir, "reload")
+		later := time.Now().Add(time.Minute)
+		for _, file := range []string{reloadCert, reloadKey} {
+			if err := os.Chtimes(file, later, later); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := c.(pinger).Ping(context.Background()); err != nil {
+			t.Fatalf("reloaded client certificate was not used: %v", err)
+		}
+	})
+}
+
+func Test_ReorgDetection(t *testing.T) {
+
+	original := makeFork(13, 13, 0)
+	node := &fakeEthClientForks{receiptBlock: original[8]}
+	cl, err := NewMultiNodeClient("a", func(string) (SimpleEthClient, error) { return node, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.SetReorgConfig(ReorgConfig{Enabled: true, Depth: 16})
+	cl.SetCacheConfig(CacheConfig{Enabled: true})
+	ctx := context.Background()
+	handler := TxReceipt(cl)
+	reorgsBefore := testutil.ToFloat64(chainReorgsCounter.WithLabelValues(""))
+
+	steps := []struct {
+		name                 string
+		chain                []*types.Header
+		expectedReorgs       float64 // reorgs counted since the first step
+		expectedReorged      bool    // the receipt in block 8 of the original chain is flagged
+		expectedBalanceCalls int64   // latest balance is read before and after each step
+	}{
+		{"first-head", original[:10], 0, false, 2},
+		{"gap-filled", original, 0, false, 3},
+		{"same-head", original, 0, false, 3},
+		{"reorg-at-head-height", makeFork(13, 8, 1), 1, true, 4},
+		{"orphaned-fork-replaced", makeFork(11, 10, 2), 2, false, 5},
+		{"fork-extended", makeFork(14, 10, 2), 2, false, 6},
+	}
+
+	for _, step := range steps {
+		if _, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), nil); err != nil {
+			t.Fatal(err)
+		}
+		chain := step.chain
+		node.chain.Store(&chain)
+		cl.pollHead(ctx)
+		if _, err := cl.BalanceAt(ctx, common.HexToAddress(dummyAddr), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, EthV0TxReceiptPrfx+dummyTxid, nil), httprouter.Params{{Key: IDKey[1:], Value: dummyTxid}})
+		var resp TxReceiptResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if g, w := testutil.ToFloat64(chainReorgsCounter.WithLabelValues(""))-reorgsBefore, step.expectedReorgs; g != w {
+			t.Errorf("%v: unexpected reorgs, got %v want %v", step.name, g, w)
+		}
+		if g, w := resp.Reorged, step.expectedReorged; g != w {
+			t.Errorf("%v: unexpected reorged flag, got %v want %v", step.name, g, w)
+		}
+		if g, w := node.balanceCalls.Load(), step.expectedBalanceCalls; g != w {
+			t.Errorf("%v: unexpected balance calls, got %d want %d", step.name, g, w)
+		}
+	}
+}
+
+func Test_AdminAPI(t *testing.T) {
+
+	const token = "secret"
+
+	constructor := func(url string) (SimpleEthClient, error) {
+		if url == "ws://unreachable" {
+			return nil, errors.New("connection refused")
+		}
+		return newFakeEthClient(url)
+	}
+
+	l, err := NewLogger("error", "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := NewMultiNodeClient("a,b", constructor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewFromConfig(&Config{Port: 8080, Admin: AdminConfig{Enabled: true, Token: token}}, l, cl)
+	s.Start()
+	defer s.Stop(os.Kill)
+
+	time.Sleep(10 * time.Millisecond)
+
+	baseURL := fmt.Sprintf("http://0.0.0.0%v", s.Server().Addr())
+
+	steps := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		body         string
+		expectedCode int
+		expectedIDs  []string // nodes listed once the step has completed
+		expectedBody string
+	}{
+		{"unauthorized", http.MethodGet, AdminV0NodesEndPnt, "wrong", "", http.StatusUnauthorized, []string{"0", "1"}, `{"error":"unauthorized"}`},
+		{"list", http.MethodGet, AdminV0NodesEndPnt, token, "", http.StatusOK, []string{"0", "1"}, ""},
+		{"add", http.MethodPost, AdminV0NodesEndPnt, token, `{"url":"ws://c","tags":["archive"]}`, http.StatusCreated, []string{"0", "1", "2"}, `{"id":"2","url":"ws://c","transport":"ws","tags":["archive"],"weight":1,"state":"closed","outstanding":0}`},
+		{"add-unknown-scheme", http.MethodPost, AdminV0NodesEndPnt, token, `{"url":"htps://c"}`, http.StatusBadRequest, []string{"0", "1", "2"}, `{"error":"invalid node config: unsupported node url 'htps://c': http(s)://, ws(s)://, ipc:// or an absolute IPC socket path expected"}`},
+		{"add-unreachable", http.MethodPost, AdminV0NodesEndPnt, token, `{"url":"ws://unreachable"}`, http.StatusBadGateway, []string{"0", "1", "2"}, `{"error":"node validation failed: dial error: connection refused"}`},
+		{"add-secret-indirection", http.MethodPost, AdminV0NodesEndPnt, token, `{"url":"http://x/${HOME}","auth":{"bearer":"file:/etc/passwd"}}`, http.StatusBadRequest, []string{"0", "1", "2"}, `{"error":"invalid node config: environment variables cannot be referenced by nodes added at runtime"}`},
+		{"add-secret-file", http.MethodPost, AdminV0NodesEndPnt, token, `{"url":"http://x","auth":{"headers":{"X-Api-Key":"env:HOME"}}}`, http.StatusBadRequest, []string{"0", "1", "2"}, `{"error":"invalid node config: secrets cannot be read from environment variables or files for nodes added at runtime"}`},
+		{"add-tls-files", http.MethodPost, AdminV0NodesEndPnt, token, `{"url":"https://x","tls":{"cafile":"/etc/passwd"}}`, http.StatusBadRequest, []string{"0", "1", "2"}, `{"error":"invalid node config: certificate files cannot be configured for nodes added at runtime"}`},
+		{"drain", http.MethodPost, AdminV0NodesPrfx + "0/drain", token, "", http.StatusOK, []string{"0", "1", "2"}, `{"id":"0","url":"a","weight":1,"state":"closed","draining":true,"outstanding":0}`},
+		{"remove", http.MethodDelete, AdminV0NodesPrfx + "0", token, "", http.StatusNoContent, []string{"1", "2"}, ""},
+		{"remove-unknown", http.MethodDelete, AdminV0NodesPrfx + "0", token, "", http.StatusNotFound, []string{"1", "2"}, `{"error":"node not found: '0'"}`},
+		{"remove-second", http.MethodDelete, AdminV0NodesPrfx + "1", token, "", http.StatusNoContent, []string{"2"}, ""},
+		{"remove-last", http.MethodDelete, AdminV0NodesPrfx + "2", token, "", http.StatusConflict, []string{"2"}, `{"error":"` + ErrLastNode.Error() + `"}`},
+	}
+
+	for _, step := range steps {
+		b, code, err := executeRequestWithToken(step.method, baseURL+step.path, step.token, []byte(step.body))
+		if err != nil {
+			t.Fatalf("%v: %v", step.name, err)
+		}
+		if g, w := code, step.expectedCode; g != w {
+			t.Errorf("%v: unexpected response code, want %v got %v", step.name, w, g)
+		}
+		if step.expectedBody != "" && string(b) != step.expectedBody {
+			t.Errorf("%v: unexpected response, want %s got %s", step.name, step.expectedBody, b)
+		}
+		var ids []string
+		for _, info := range cl.Nodes() {
+			ids = append(ids, info.ID)
+		}
+		if !reflect.DeepEqual(ids, step.expectedIDs) {
+			t.Errorf("%v: unexpected nodes, want %v got %v", step.name, step.expectedIDs, ids)
+		}
+	}
+
+	if nodes := cl.rotation(nil); len(nodes) != 1 || nodes[0].id != "2" {
+		t.Errorf("expected only the added node in rotation, got %d nodes", len(nodes))
+	}
+}
+
+func Test_BeaconAPI(t *testing.T) {
+
+	nodes := []*fakeBeaconNode{newFakeBeaconNode(), newFakeBeaconNode()}
+	var cfg BeaconConfig
+	for _, node := range nodes {
+		srv := httptest.NewServer(node)
+		defer srv.Close()
+		cfg.Nodes = append(cfg.Nodes, NodeConfig{URL: srv.URL})
+	}
+	cfg.Strategy = StrategyPriority
+	beacon, err := NewBeaconClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("health-checks", func(t *testing.T) {
+		hc := HealthCheckConfig{FailureThreshold: 1}.withDefaults()
+		tests := []struct {
+			name          string
+			health        int32
+			syncDistance  uint64
+			elOffline     bool
+			expectedErr   string
+			expectedState BreakerState
+		}{
+			{"ready", http.StatusOK, 0, false, "", BreakerClosed},
+			{"syncing-near-head", http.StatusPartialContent, 2, false, "", BreakerClosed},
+			{"syncing", http.StatusPartialContent, 40, false, "node is 40 slots behind the chain head", BreakerOpen},
+			{"el-offline", http.StatusOK, 0, true, "execution client of the node is offline", BreakerOpen},
+			{"not-initialized", http.StatusServiceUnavailable, 0, false, "503 Service Unavailable", BreakerOpen},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				nodes[1].health.Store(tt.health)
+				nodes[1].syncDistance.Store(tt.syncDistance)
+				nodes[1].elOffline.Store(tt.elOffline)
+				node := beacon.nodes.snapshot()[1]
+				node.breaker = newCircuitBreaker(hc)
+				beacon.probeNodes(context.Background(), hc)
+				health := beacon.NodeHealth()[1]
+				if g, w := health.Error, tt.expectedErr; g != w {
+					t.Errorf("unexpected health check error, got %v want %v", g, w)
+				}
+				if g, w := health.State, tt.expectedState; g != w {
+					t.Errorf("unexpected breaker state, got %v want %v", g, w)
+				}
+				if tt.expectedErr == "" && health.HeadSlot != 100 {
+					t.Errorf("unexpected head slot %d", health.HeadSlot)
+				}
+			})
+		}
+		nodes[1].health.Store(http.StatusOK)
+		nodes[1].syncDistance.Store(0)
+		beacon.nodes.snapshot()[1].breaker = newCircuitBreaker(hc)
+	})
+
+	l, err := NewLogger("error", "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := NewMultiNodeClient("a", newFakeEthClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewWithBeacon(&Config{Port: 8080}, l, cl, beacon)
+	s.Start()
+	defer s.Stop(os.Kill)
+
+	time.Sleep(10 * time.Millisecond)
+
+	baseURL := fmt.Sprintf("http://0.0.0.0%v", s.Server().Addr())
+
+	t.Run("endpoints", func(t *testing.T) {
+		tests := []struct {
+			name             string
+			method           string
+			path             string
+			body             string
+			failing          bool // the first node answers with a server error
+			expectedCode     int
+			expectedBody     string
+			expectedRequests int32 // requests made to the first node
+		}{
+			{"passthrough", http.MethodGet, "/beacon/eth/v1/node/version?x=1", "", false, http.StatusOK, `{"method":"GET","path":"/eth/v1/node/version","query":"x=1","body":"","type":"application/json"}`, 1},
+			{"passthrough-post", http.MethodPost, "/beacon/eth/v1/beacon/pool/attestations", "[]", false, http.StatusOK, `{"method":"POST","path":"/eth/v1/beacon/pool/attestations","query":"","body":"[]","type":"application/json"}`, 1},
+			{"passthrough-failover", http.MethodGet, "/beacon/eth/v1/node/version", "", true, http.StatusOK, `{"method":"GET","path":"/eth/v1/node/version","query":"","body":"","type":"application/json"}`, 1},
+			{"passthrough-not-found", http.MethodGet, "/beacon/eth/v1/beacon/states/999/root", "", false, http.StatusNotFound, `{"code":404,"message":"state not found"}`, 1},
+			{"finality", http.MethodGet, EthV0BeaconFinalityEndPnt, "", true, http.StatusOK, `{"state":"head","previous_justified":{"epoch":"9","root":"` + common.HexToHash("0x09").Hex() + `"},"current_justified":{"epoch":"10","root":"` + common.HexToHash("0x0a").Hex() + `"},"finalized":{"epoch":"9","root":"` + common.HexToHash("0x09").Hex() + `"}}`, 1},
+			{"finality-not-found", http.MethodGet, EthV0BeaconFinalityEndPnt + "?state=999", "", false, http.StatusNotFound, `{"error":"beacon client error: 404 Not Found: {\"code\":404,\"message\":\"state not found\"}"}`, 1},
+			{"finality-invalid-state", http.MethodGet, EthV0BeaconFinalityEndPnt + "?state=latest", "", false, http.StatusBadRequest, `{"error":"invalid state id 'latest'"}`, 0},
+			{"balances", http.MethodGet, EthV0BeaconBalancesPrfx + "1,2,0x" + strings.Repeat("ab", 48) + "?state=finalized", "", false, http.StatusOK, `{"state":"finalized","balances":[{"index":"1","balance":"32000000000"},{"index":"2","balance":"32000000000"}]}`, 1},
+			{"balances-invalid-validator", http.MethodGet, EthV0BeaconBalancesPrfx + "1,abc", "", false, http.StatusBadRequest, `{"error":"invalid validator 'abc'"}`, 0},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				nodes[0].failing.Store(tt.failing)
+				defer nodes[0].failing.Store(false)
+				before := nodes[0].requests.Load()
+				b, code, err := executeRequestWithBody(tt.method, baseURL+tt.path, []byte(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if g, w := code, tt.expectedCode; g != w {
+					t.Errorf("unexpected response code, want %v got %v", w, g)
+				}
+				if g, w := string(b), tt.expectedBody; g != w {
+					t.Errorf("unexpected response, want %s got %s", w, g)
+				}
+				if g, w := nodes[0].requests.Load()-before, tt.expectedRequests; g != w {
+					t.Errorf("unexpected requests to the first node, want %v got %v", w, g)
+				}
+			})
+		}
+	})
+
+	t.Run("passthrough-headers", func(t *testing.T) {
+		resp, err := http.Get(baseURL + "/beacon/eth/v2/beacon/blocks/head")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if g, w := resp.Header.Get("Eth-Consensus-Version"), "deneb"; g != w {
+			t.Errorf("unexpected consensus version header, got %v want %v", g, w)
+		}
+		if g := resp.Header.Get("X-Upstream"); g != "" {
+			t.Errorf("unexpected header forwarded: %v", g)
+		}
+	})
+
+	t.Run("health", func(t *testing.T) {
+		nodes[1].syncDistance.Store(40)
+		defer nodes[1].syncDistance.Store(0)
+		b, code, err := executeRequest(http.MethodGet, baseURL+HeathEndPnt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := code, http.StatusServiceUnavailable; g != w {
+			t.Errorf("unexpected response code, want %v got %v", w, g)
+		}
+		var health HealthResponse
+		if err := json.Unmarshal(b, &health); err != nil {
+			t.Fatal(err)
+		}
+		if g, w := health.Failures, []string{"node beacon-1 err: node is 40 slots behind the chain head"}; !reflect.DeepEqual(g, w) {
+			t.Errorf("unexpected failures, got %v want %v", g, w)
+		}
+		if g, w := len(health.Beacon), 2; g != w {
+			t.Errorf("unexpected beacon nodes, got %v want %v", g, w)
+		}
+	})
+}
+
+func Test_API(t *testing.T) {
+
+	apiTests := []struct {
+		name               string
+		urls               string
+		serviceConstructor func(urls string) *Service
+		endpoint           func() string
+		methodType         string
+		expectedResponse   any
+		expectedCode       int
+	}{
+		//
+		// READ REQUESTS
+		//
+		{
+			"status",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return StatusEndPnt },
+			http.MethodGet,
+			&StatusResponse{Message: "OK", Version: Version, Service: ServiceName},
+			http.StatusOK,
+		},
+		{
+			"health",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return HeathEndPnt },
+			http.MethodGet,
+			&HealthResponse{Version: Version, Service: ServiceName, Failures: []string{}, Nodes: []NodeHealth{{ID: "0", State: BreakerClosed}}},
+			http.StatusOK,
+		},
+		{
+			"eth-balance",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return fmt.Sprintf("%v%v", EthV0BalancePrfx, dummyAddr) },
+			http.MethodGet,
+			&BalanceResponse{Balance: "0"},
+			http.StatusOK,
+		},
+		{
+			"eth-tx",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return fmt.Sprintf("%v%v", EthV0TxPrfx, dummyTxid) },
+			http.MethodGet,
+			&TxResponse{Tx: dummyTx, Txid: dummyTxid, IsPending: false},
+			http.StatusOK,
+		},
+		{
+			"eth-tx-receipt",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return fmt.Sprintf("%v%v", EthV0TxReceiptPrfx, dummyTxid) },
+			http.MethodGet,
+			&TxReceiptResponse{Receipt: &types.Receipt{}},
+			http.StatusOK,
+		},
+		{
+			"eth-tx-send",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string {
+				b, _ := dummyTx.MarshalBinary()
+				return fmt.Sprintf("%v0x%x", EthV0SendTxPrfx, b)
+			},
+			http.MethodPost,
+			&TxResponse{Txid: dummyTx.Hash().Hex()},
+			http.StatusOK,
+		},
+		{
+			"eth-fee",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return EthV0FeeEndPnt },
+			http.MethodGet,
+			&FeeResponse{GasPrice: "2", GasTipCap: "1", BaseFee: "1", BlobBaseFee: "1"},
+			http.StatusOK,
+		},
+		{
+			"eth-tx-trace",
+			"-",
+			func(urls string) *Service {
+				return makeTestServiceWithNodes(t, []NodeConfig{{URL: urls, Tags: []string{TagDebug}}}, newFakeEthClient)
+			},
+			func() string { return fmt.Sprintf("%v%v", EthV0TxTracePrfx, dummyTxid) },
+			http.MethodGet,
+			&TraceResponse{Txid: dummyTxid, CallTrace: dummyCallTrace, PrestateTrace: dummyPrestateTrace, InternalTransfers: dummyTransfers},
+			http.StatusOK,
+		},
+		{
+			"eth-tx-trace-prestate",
+			"-",
+			func(urls string) *Service {
+				return makeTestServiceWithNodes(t, []NodeConfig{{URL: urls, Tags: []string{TagDebug}}}, newFakeEthClient)
+			},
+			func() string {
+				return fmt.Sprintf("%v%v?%v=%v", EthV0TxTracePrfx, dummyTxid, TracerKey, PrestateTracer)
+			},
+			http.MethodGet,
+			&TraceResponse{Txid: dummyTxid, PrestateTrace: dummyPrestateTrace},
+			http.StatusOK,
+		},
+		//
+		// CLIENT ERRORS
+		//
+		{
+			"eth-balance-malformed",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return fmt.Sprintf("%v0xnotanaddress", EthV0BalancePrfx) },
+			http.MethodGet,
+			map[string]string{"error": "invalid address format"},
+			http.StatusBadRequest,
+		},
+		{
+			"eth-tx-send-malformed",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string {
+				return EthV0SendTxPrfx + "0xnotATx"
+			},
+			http.MethodPost,
+			map[string]string{"error": "invalid tx data: invalid hex string"},
+			http.StatusBadRequest,
+		},
+		{
+			"eth-tx-malformed",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return EthV0TxPrfx + "0xnotahash" },
+			http.MethodGet,
+			map[string]string{"error": "invalid hash"},
+			http.StatusBadRequest,
+		},
+		{
+			"eth-tx-receipt-short-hash",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return EthV0TxReceiptPrfx + dummyTxid[:len(dummyTxid)-2] },
+			http.MethodGet,
+			map[string]string{"error": "invalid hash"},
+			http.StatusBadRequest,
+		},
+		{
+			"eth-tx-trace-malformed",
+			"-",
+			func(urls string) *Service {
+				return makeTestServiceWithNodes(t, []NodeConfig{{URL: urls, Tags: []string{TagDebug}}}, newFakeEthClient)
+			},
+			func() string { return EthV0TxTracePrfx + "0xnotahash" },
+			http.MethodGet,
+			map[string]string{"error": "invalid hash"},
+			http.StatusBadRequest,
+		},
+		{
+			"eth-tx-trace-short-hash",
+			"-",
+			func(urls string) *Service {
+				return makeTestServiceWithNodes(t, []NodeConfig{{URL: urls, Tags: []string{TagDebug}}}, newFakeEthClient)
+			},
+			func() string { return EthV0TxTracePrfx + dummyTxid[:len(dummyTxid)-2] },
+			http.MethodGet,
+			map[string]string{"error": "invalid hash"},
+			http.StatusBadRequest,
+		},
+		{
+			"eth-tx-trace-bad-tracer",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return fmt.Sprintf("%v%v?%v=4byteTracer", EthV0TxTracePrfx, dummyTxid, TracerKey) },
+			http.MethodGet,
+			map[string]string{"error": "unsupported tracer '4byteTracer'"},
+			http.StatusBadRequest,
+		},
+		//
+		// SERVER ERRORS
+		//
+		{
+			"health-node-err",
+			"testErr",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
+			func() string { return HeathEndPnt },
+			http.MethodGet,
+			&HealthResponse{Version: Version, Service: ServiceName, Failures: []string{"node 0 err: testErr"}, Nodes: []NodeHealth{{ID: "0", State: BreakerClosed}}},
+			http.StatusServiceUnavailable,
+		},
+		{
+			"eth-balance-err",
+			"testErr",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
+			func() string { return fmt.Sprintf("%v%v", EthV0BalancePrfx, dummyAddr) },
+			http.MethodGet,
+			map[string]string{"error": "eth client error: testErr"},
+			http.StatusInternalServerError,
+		},
+		{
+			"eth-tx-err",
+			"testErr",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
+			func() string { return fmt.Sprintf("%v%v", EthV0TxPrfx, dummyTxid) },
+			http.MethodGet,
+			map[string]string{"error": "eth client error: testErr"},
+			http.StatusInternalServerError,
+		},
+		{
+			"eth-receipt-err",
+			"testErr",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
+			func() string { return fmt.Sprintf("%v%v", EthV0TxReceiptPrfx, dummyTxid) },
+			http.MethodGet,
+			map[string]string{"error": "eth client error: testErr"},
+			http.StatusInternalServerError,
+		},
+		{
+			"eth-tx-send-err",
+			"testErr",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
+			func() string {
+				b, _ := dummyTx.MarshalBinary()
+				return fmt.Sprintf("%v0x%x", EthV0SendTxPrfx, b)
+			},
+			http.MethodPost,
+			map[string]string{"error": "eth client error: testErr"},
+			http.StatusInternalServerError,
+		},
+		{
+			"eth-fee-err",
+			"testErr",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
+			func() string { return EthV0FeeEndPnt },
+			http.MethodGet,
+			map[string]string{"error": "eth client error: testErr"},
+			http.StatusInternalServerError,
+		},
+		{
+			"eth-tx-trace-no-debug-nodes",
+			"-",
+			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
+			func() string { return fmt.Sprintf("%v%v", EthV0TxTracePrfx, dummyTxid) },
+			http.MethodGet,
+			map[string]string{"error": ErrDebugNotSupported.Error()},
+			http.StatusNotImplemented,
+		},
+		{
+			"eth-tx-trace-err",
+			"testErr",
+			func(urls string) *Service {
+				return makeTestServiceWithNodes(t, []NodeConfig{{URL: urls, Tags: []string{TagDebug}}}, newFakeEthClientWithErr)
+			},
+			func() string { return fmt.Sprintf("%v%v", EthV0TxTracePrfx, dummyTxid) },
+			http.MethodGet,
+			map[string]string{"error": "eth client error: testErr"},
+			http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range apiTests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := tt.serviceConstructor(tt.urls)
+			s.Start()
+			defer s.Stop(os.Kill)
+
+			time.Sleep(10 * time.Millisecond)
+
+			b, code, err := executeRequest(tt.methodType, fmt.Sprintf("http://0.0.0.0%v%v", s.Server().Addr(), tt.endpoint()))
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+			if g, w := code, tt.expectedCode; g != w {
+				t.Errorf("%v unexpected response code, want %v got %v", tt.name, w, g)
+			}
+
+			expectedJSON, _ := json.Marshal(tt.expectedResponse)
+
+			if g, w := b, expectedJSON; !bytes.Equal(g, w) {
+				t.Errorf("%v unexpected response, want %s, got %s", tt.name, w, g)
+			}
+		})
+
+	}
+}
+
+func Test_InternalTransfers(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		trace     json.RawMessage
+		expected  []InternalTransfer
+		expectErr bool
+	}{
+		{
+			"nested-calls",
+			dummyCallTrace,
+			dummyTransfers,
+			false,
+		},
+		{
+			"reverted-tx",
+			json.RawMessage(`{"type":"CALL","error":"execution reverted","calls":[{"type":"CALL","from":"0x00000000000000000000000000000000000000bb","to":"0x00000000000000000000000000000000000000cc","value":"0x2"}]}`),
+			nil,
+			false,
+		},
+		{
+			"selfdestruct",
+			json.RawMessage(`{"type":"CALL","calls":[{"type":"SELFDESTRUCT","from":"0x00000000000000000000000000000000000000bb","to":"0x00000000000000000000000000000000000000cc","value":"0x2"}]}`),
+			[]InternalTransfer{{Type: "SELFDESTRUCT", From: dummyTransfers[0].From, To: dummyTransfers[0].To, Value: "2", Depth: 1}},
+			false,
+		},
+		{
+			"malformed",
+			json.RawMessage(`[]`),
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfers, err := internalTransfers(tt.trace)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			g, _ := json.Marshal(transfers)
+			w, _ := json.Marshal(tt.expected)
+			if !bytes.Equal(g, w) {
+				t.Errorf("unexpected transfers, want %s, got %s", w, g)
+			}
+		})
+	}
+}
+
+// makeBlobTx returns an unsigned blob transaction carrying blobCount blobs and a valid sidecar.
+func makeBlobTx(t *testing.T, blobCount int) *types.Transaction {
+	sidecar := &types.BlobTxSidecar{}
+	for i := 0; i < blobCount; i++ {
+		var blob kzg4844.Blob
+		blob[31] = byte(i) // first field element, big-endian
+		commitment, err := kzg4844.BlobToCommitment(&blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blob, commitment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sidecar.Blobs = append(sidecar.Blobs, blob)
+		sidecar.Commitments = append(sidecar.Commitments, commitment)
+		sidecar.Proofs = append(sidecar.Proofs, proof)
+	}
+	return types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		BlobFeeCap: uint256.NewInt(1),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	})
+}
+
+func Test_ValidateBlobTx(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		tx        func() *types.Transaction
+		expectErr bool
+	}{
+		{
+			"non-blob-tx",
+			func() *types.Transaction { return dummyTx },
+			false,
+		},
+		{
+			"single-blob",
+			func() *types.Transaction { return makeBlobTx(t, 1) },
+			false,
+		},
+		{
+			"max-blobs",
+			func() *types.Transaction { return makeBlobTx(t, maxBlobsPerTx) },
+			false,
+		},
+		{
+			"too-many-blobs",
+			func() *types.Transaction { return makeBlobTx(t, maxBlobsPerTx+1) },
+			true,
+		},
+		{
+			"no-blobs",
+			func() *types.Transaction {
+				return types.NewTx(&types.BlobTx{ChainID: uint256.NewInt(1), Sidecar: &types.BlobTxSidecar{}})
+			},
+			true,
+		},
+		{
+			"missing-sidecar",
+			func() *types.Transaction { return makeBlobTx(t, 1).WithoutBlobTxSidecar() },
+			true,
+		},
+		{
+			"missing-proof",
+			func() *types.Transaction {
+				tx := makeBlobTx(t, 2)
+				sidecar := tx.BlobTxSidecar()
+				sidecar.Proofs = sidecar.Proofs[:1]
+				return tx
+			},
+			true,
+		},
+		{
+			"commitment-hash-mismatch",
+			func() *types.Transaction {
+				tx := makeBlobTx(t, 2)
+				sidecar := tx.BlobTxSidecar()
+				sidecar.Commitments[0], sidecar.Commitments[1] = sidecar.Commitments[1], sidecar.Commitments[0]
+				return tx
+			},
+			true,
+		},
+		{
+			"invalid-proof",
+			func() *types.Transaction {
+				tx := makeBlobTx(t, 2)
+				sidecar := tx.BlobTxSidecar()
+				sidecar.Proofs[0], sidecar.Proofs[1] = sidecar.Proofs[1], sidecar.Proofs[0]
+				return tx
+			},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateBlobTx(tt.tx()); (err != nil) != tt.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_SendTxBody(t *testing.T) {
+
+	blobTx := makeBlobTx(t, 1)
+
+	tests := []struct {
+		name             string
+		tx               func() []byte
+		expectedResponse any
+		expectedCode     int
+	}{
+		{
+			"dynamic-fee-tx",
+			func() []byte {
+				b, _ := dummyTx.MarshalBinary()
+				return b
+			},
+			&TxResponse{Txid: dummyTx.Hash().Hex()},
+			http.StatusOK,
+		},
+		{
+			"blob-tx-network-form",
+			func() []byte {
+				b, _ := blobTx.MarshalBinary()
+				return b
+			},
+			&TxResponse{Txid: blobTx.Hash().Hex()},
+			http.StatusOK,
+		},
+		{
+			"blob-tx-without-sidecar",
+			func() []byte {
+				b, _ := blobTx.WithoutBlobTxSidecar().MarshalBinary()
+				return b
+			},
+			map[string]string{"error": "invalid blob tx: " + errMissingSidecar.Error()},
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := makeTestService(t, "-", newFakeEthClient)
+			s.Start()
+			defer s.Stop(os.Kill)
+
+			time.Sleep(10 * time.Millisecond)
+
+			body, _ := json.Marshal(&SendTxRequest{Tx: tt.tx()})
+			b, code, err := executeRequestWithBody(http.MethodPost, fmt.Sprintf("http://0.0.0.0%v%v", s.Server().Addr(), EthV0SendTxBodyEndPnt), body)
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+			if g, w := code, tt.expectedCode; g != w {
+				t.Errorf("%v unexpected response code, want %v got %v", tt.name, w, g)
+			}
+
+			expectedJSON, _ := json.Marshal(tt.expectedResponse)
+
+			if g, w := b, expectedJSON; !bytes.Equal(g, w) {
+				t.Errorf("%v unexpected response, want %s, got %s", tt.name, w, g)
+			}
+		})
+	}
+}
+
+func Test_SimulateTx(t *testing.T) {
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedTx, err := types.SignTx(dummyTx, types.LatestSignerForChainID(dummyTx.ChainId()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	txid := signedTx.Hash().Hex()
+
+	tests := []struct {
+		name             string
+		serviceConstruct func() *Service
+		tx               *types.Transaction
+		endpoint         string
+		expectedResponse any
+		expectedCode     int
+	}{
+		{
+			"simulate",
+			func() *Service { return makeTestService(t, "-", newFakeEthClient) },
+			signedTx,
+			EthV0SimulateEndPnt,
+			&SimulationResponse{Txid: txid, From: sender, Success: true, Result: "0x", GasEstimate: 21000},
+			http.StatusOK,
+		},
+		{
+			"simulate-revert",
+			func() *Service { return makeTestService(t, "-", newFakeEthClientWithRevert) },
+			signedTx,
+			EthV0SimulateEndPnt,
+			&SimulationResponse{Txid: txid, From: sender, RevertReason: "insufficient balance"},
+			http.StatusOK,
+		},
+		{
+			"simulate-bare-revert",
+			func() *Service {
+				return makeTestService(t, "-", newFakeEthClientWithCallErr(rpcServerErrorCode, "execution reverted"))
+			},
+			signedTx,
+			EthV0SimulateEndPnt,
+			&SimulationResponse{Txid: txid, From: sender, RevertReason: "execution reverted"},
+			http.StatusOK,
+		},
+		{
+			"simulate-out-of-gas",
+			func() *Service {
+				return makeTestService(t, "-", newFakeEthClientWithCallErr(rpcServerErrorCode, "err: out of gas (supplied gas 21000)"))
+			},
+			signedTx,
+			EthV0SimulateEndPnt,
+			&SimulationResponse{Txid: txid, From: sender, RevertReason: "err: out of gas (supplied gas 21000)"},
+			http.StatusOK,
+		},
+		{
+			"simulate-invalid-opcode",
+			func() *Service {
+				return makeTestService(t, "-", newFakeEthClientWithCallErr(rpcServerErrorCode, "invalid opcode: INVALID"))
+			},
+			signedTx,
+			EthV0SimulateEndPnt,
+			&SimulationResponse{Txid: txid, From: sender, RevertReason: "invalid opcode: INVALID"},
+			http.StatusOK,
+		},
+		{
+			"simulate-unsigned",
+			func() *Service { return makeTestService(t, "-", newFakeEthClient) },
+			dummyTx,
+			EthV0SimulateEndPnt,
+			map[string]string{"error": "could not recover sender: invalid transaction v, r, s values"},
+			http.StatusBadRequest,
+		},
+		{
+			"simulate-node-err",
+			func() *Service { return makeTestService(t, "testErr", newFakeEthClientWithErr) },
+			signedTx,
+			EthV0SimulateEndPnt,
+			map[string]string{"error": "eth client error: testErr"},
+			http.StatusBadGateway,
+		},
+		{
+			"simulate-rate-limited",
+			func() *Service {
+				return makeTestService(t, "-", newFakeEthClientWithCallErr(rpcLimitExceededCode, "limit exceeded"))
+			},
+			signedTx,
+			EthV0SimulateEndPnt,
+			map[string]string{"error": "eth client error: limit exceeded"},
+			http.StatusBadGateway,
+		},
+		{
+			"simulate-method-not-found",
+			func() *Service {
+				return makeTestService(t, "-", newFakeEthClientWithCallErr(rpcMethodNotFoundCode, "the method eth_call does not exist/is not available"))
+			},
+			signedTx,
+			EthV0SimulateEndPnt,
+			map[string]string{"error": "eth client error: the method eth_call does not exist/is not available"},
+			http.StatusBadGateway,
+		},
+		{
+			"send-with-simulation-rate-limited",
+			func() *Service {
+				return makeTestService(t, "-", newFakeEthClientWithCallErr(rpcLimitExceededCode, "limit exceeded"))
+			},
+			signedTx,
+			EthV0SendTxBodyEndPnt + "?" + SimulateKey + "=true",
+			map[string]string{"error": "eth client error: limit exceeded"},
+			http.StatusBadGateway,
+		},
+		{
+			"send-with-simulation",
+			func() *Service { return makeTestService(t, "-", newFakeEthClient) },
+			signedTx,
+			EthV0SendTxBodyEndPnt + "?" + SimulateKey + "=true",
+			&TxResponse{Txid: txid, Simulation: &SimulationResponse{Txid: txid, From: sender, Success: true, Result: "0x"}},
+			http.StatusOK,
+		},
+		{
+			"send-with-simulation-bare-revert",
+			func() *Service {
+				return makeTestService(t, "-", newFakeEthClientWithCallErr(rpcServerErrorCode, "execution reverted"))
+			},
+			signedTx,
+			EthV0SendTxBodyEndPnt + "?" + SimulateKey + "=true",
+			map[string]string{"error": "transaction would revert: execution reverted"},
+			http.StatusUnprocessableEntity,
+		},
+		{
+			"send-with-simulation-revert",
+			func() *Service { return makeTestService(t, "-", newFakeEthClientWithRevert) },
+			signedTx,
+			EthV0SendTxBodyEndPnt + "?" + SimulateKey + "=true",
+			map[string]string{"error": "transaction would revert: insufficient balance"},
+			http.StatusUnprocessableEntity,
+		},
+		{
+			"send-mandatory-simulation-revert",
+			func() *Service {
+				l, err := NewLogger("error", "plain")
+				if err != nil {
+					t.Fatal(err)
+				}
+				cl, err := NewMultiNodeClient("-", newFakeEthClientWithRevert)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return NewFromConfig(&Config{Port: 8080, SimulateTxs: true}, l, cl)
+			},
+			signedTx,
+			EthV0SendTxBodyEndPnt,
+			map[string]string{"error": "transaction would revert: insufficient balance"},
+			http.StatusUnprocessableEntity,
+		},
+		{
+			"send-without-simulation",
+			func() *Service { return makeTestService(t, "-", newFakeEthClientWithRevert) },
+			signedTx,
+			EthV0SendTxBodyEndPnt,
+			&TxResponse{Txid: txid},
+			http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := tt.serviceConstruct()
+			s.Start()
+			defer s.Stop(os.Kill)
+
+			time.Sleep(10 * time.Millisecond)
+
+			txBytes, _ := tt.tx.MarshalBinary()
+			body, _ := json.Marshal(&SendTxRequest{Tx: txBytes})
+			b, code, err := executeRequestWithBody(http.MethodPost, fmt.Sprintf("http://0.0.0.0%v%v", s.Server().Addr(), tt.endpoint), body)
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+			if g, w := code, tt.expectedCode; g != w {
+				t.Errorf("%v unexpected response code, want %v got %v", tt.name, w, g)
+			}
+
+			expectedJSON, _ := json.Marshal(tt.expectedResponse)
+
+			if g, w := b, expectedJSON; !bytes.Equal(g, w) {
+				t.Errorf("%v unexpected response, want %s, got %s", tt.name, w, g)
+			}
+		})
+	}
+}
+
+func Test_PinnedReads(t *testing.T) {
+
+	blockHash := common.Hash{0xab, 0xcd, 0xef, 0x01}.Hex()
+	to := common.HexToAddress(dummyAddr)
+	call, _ := json.Marshal(&CallRequest{To: &to, Data: hexutil.Bytes{1, 2, 3}})
+
+	tests := []struct {
+		name             string
+		constructor      func(url string) (SimpleEthClient, error)
+		methodType       string
+		endpoint         string
+		body             []byte
+		expectedResponse any
+		expectedCode     int
+	}{
+		{
+			"balance-at-hash",
+			newFakeEthClientChain,
+			http.MethodGet,
+			EthV0BalancePrfx + dummyAddr + "?" + BlockHashKey + "=" + blockHash,
+			nil,
+			&BalanceResponse{Balance: "0"},
+			http.StatusOK,
+		},
+		{
+			"balance-at-hash-not-supported",
+			newFakeEthClient,
+			http.MethodGet,
+			EthV0BalancePrfx + dummyAddr + "?" + BlockHashKey + "=" + blockHash,
+			nil,
+			map[string]string{"error": "eth client error: upstream client does not support reads at a block hash"},
+			http.StatusNotImplemented,
+		},
+		{
+			"balance-at-hash-and-block",
+			newFakeEthClientChain,
+			http.MethodGet,
+			EthV0BalancePrfx + dummyAddr + "?" + BlockHashKey + "=" + blockHash + "&" + BlockKey + "=5",
+			nil,
+			map[string]string{"error": "blockhash cannot be combined with block, verified or quorum reads"},
+			http.StatusBadRequest,
+		},
+		{
+			"balance-at-invalid-hash",
+			newFakeEthClientChain,
+			http.MethodGet,
+			EthV0BalancePrfx + dummyAddr + "?" + BlockHashKey + "=0x1234",
+			nil,
+			map[string]string{"error": "invalid block hash '0x1234'"},
+			http.StatusBadRequest,
+		},
+		{
+			"call-pending",
+			newFakeEthClient,
+			http.MethodPost,
+			EthV0CallEndPnt,
+			call,
+			&CallResponse{Result: "0x"},
+			http.StatusOK,
+		},
+		{
+			"call-at-hash",
+			newFakeEthClientChain,
+			http.MethodPost,
+			EthV0CallEndPnt + "?" + BlockHashKey + "=" + blockHash,
+			call,
+			&CallResponse{Result: "0xabcdef01", BlockHash: blockHash},
+			http.StatusOK,
+		},
+		{
+			"call-revert",
+			newFakeEthClientWithRevert,
+			http.MethodPost,
+			EthV0CallEndPnt,
+			call,
+			map[string]string{"error": "call reverted: insufficient balance"},
+			http.StatusUnprocessableEntity,
+		},
+		{
+			"call-invalid-body",
+			newFakeEthClient,
+			http.MethodPost,
+			EthV0CallEndPnt,
+			[]byte("{"),
+			map[string]string{"error": "invalid call data: unexpected EOF"},
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := makeTestService(t, "-", tt.constructor)
+			s.Start()
+			defer s.Stop(os.Kill)
+
+			time.Sleep(10 * time.Millisecond)
+
+			b, code, err := executeRequestWithBody(tt.methodType, fmt.Sprintf("http://0.0.0.0%v%v", s.Server().Addr(), tt.endpoint), tt.body)
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+			if g, w := code, tt.expectedCode; g != w {
+				t.Errorf("%v unexpected response code, want %v got %v", tt.name, w, g)
+			}
+
+			expectedJSON, _ := json.Marshal(tt.expectedResponse)
+
+			if g, w := b, expectedJSON; !bytes.Equal(g, w) {
+				t.Errorf("%v unexpected response, want %s, got %s", tt.name, w, g)
+			}
+		})
+	}
+}
+
+func Test_VerifiedReads(t *testing.T) {
+
+	constructorByURL := func(url string) (SimpleEthClient, error) {
+		switch url {
+		case "forked":
+			return newFakeEthClientWithForkedHeader(url)
+		case "bad-proof":
+			return newFakeEthClientWithBadProof(url)
+		case "no-proof":
+			return newFakeEthClientWithoutProof(url)
+		case "chain":
+			return newFakeEthClientWithChain(url)
+		default:
+			return newFakeEthClient(url)
+		}
+	}
+
+	checkpointHash := dummyStateHeader.Hash().Hex()
+	chainCheckpointHash := fakeChain[1].Hash().Hex()
+
+	tests := []struct {
+		name             string
+		urls             string
+		proofCfg         ProofConfig
+		endpoint         string
+		expectedResponse any
+		expectedCode     int
+	}{
+		{
+			"balance",
+			"node,node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			&BalanceResponse{Balance: "100", Verified: true},
+			http.StatusOK,
+		},
+		{
+			"balance-unverified",
+			"node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v", EthV0BalancePrfx, dummyAddr),
+			&BalanceResponse{Balance: "0"},
+			http.StatusOK,
+		},
+		{
+			"nonce",
+			"node,node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true", EthV0NoncePrfx, dummyAddr, VerifiedKey),
+			&NonceResponse{Nonce: 7, Verified: true},
+			http.StatusOK,
+		},
+		{
+			"nonce-unverified",
+			"node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v", EthV0NoncePrfx, dummyAddr),
+			&NonceResponse{Nonce: 0},
+			http.StatusOK,
+		},
+		{
+			"storage",
+			"node,node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v/%v?%v=true", EthV0StoragePrfx, dummyAddr, dummyProofSlot.Hex(), VerifiedKey),
+			&StorageResponse{Value: common.BigToHash(big.NewInt(42)).Hex(), Verified: true},
+			http.StatusOK,
+		},
+		{
+			"storage-unverified",
+			"node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v/0x01", EthV0StoragePrfx, dummyAddr),
+			&StorageResponse{Value: common.Hash{}.Hex()},
+			http.StatusOK,
+		},
+		{
+			"storage-bad-slot",
+			"node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v/0xzz", EthV0StoragePrfx, dummyAddr),
+			map[string]string{"error": "invalid storage slot"},
+			http.StatusBadRequest,
+		},
+		{
+			"balance-bad-block",
+			"node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true&%v=latest", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			map[string]string{"error": "invalid block number 'latest'"},
+			http.StatusBadRequest,
+		},
+		{
+			"balance-majority-quorum",
+			"node,node,forked",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			&BalanceResponse{Balance: "100", Verified: true},
+			http.StatusOK,
+		},
+		{
+			"balance-no-quorum",
+			"node,node,forked",
+			ProofConfig{Quorum: 3},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			map[string]string{"error": "verified read error: upstream nodes did not reach quorum on block hash: block 0, 2 distinct hashes reported, quorum 3"},
+			http.StatusBadGateway,
+		},
+		{
+			"balance-checkpoint",
+			"node,forked,forked",
+			ProofConfig{CheckpointNumber: 0, CheckpointHash: checkpointHash},
+			fmt.Sprintf("%v%v?%v=true&%v=0", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			&BalanceResponse{Balance: "100", Verified: true},
+			http.StatusOK,
+		},
+		{
+			"balance-min-block-not-reached",
+			"node,node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true&%v=5", EthV0BalancePrfx, dummyAddr, VerifiedKey, MinBlockKey),
+			map[string]string{"error": "verified read error: no upstream node has reached the minimum block 5 (best head 0)"},
+			http.StatusServiceUnavailable,
+		},
+		{
+			"balance-single-node",
+			"node",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			map[string]string{"error": "verified read error: upstream nodes did not reach quorum on block hash: quorum 2 exceeds node count 1"},
+			http.StatusBadGateway,
+		},
+		{
+			"balance-single-node-quorum",
+			"node,node",
+			ProofConfig{Quorum: 1},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			map[string]string{"error": "verified read error: upstream nodes did not reach quorum on block hash: quorum 1 is below the minimum of 2 nodes"},
+			http.StatusBadGateway,
+		},
+		{
+			"balance-single-node-allowed",
+			"node",
+			ProofConfig{AllowSingleNode: true},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			&BalanceResponse{Balance: "100", Verified: true},
+			http.StatusOK,
+		},
+		{
+			"balance-checkpoint-descendant",
+			"chain",
+			ProofConfig{CheckpointNumber: 1, CheckpointHash: chainCheckpointHash},
+			fmt.Sprintf("%v%v?%v=true&%v=3", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			&BalanceResponse{Balance: "100", Verified: true, Block: 3},
+			http.StatusOK,
+		},
+		{
+			"balance-checkpoint-ancestor",
+			"chain",
+			ProofConfig{CheckpointNumber: 1, CheckpointHash: chainCheckpointHash},
+			fmt.Sprintf("%v%v?%v=true&%v=0", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			&BalanceResponse{Balance: "100", Verified: true},
+			http.StatusOK,
+		},
+		{
+			"balance-checkpoint-latest",
+			"chain",
+			ProofConfig{CheckpointNumber: 1, CheckpointHash: chainCheckpointHash},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			&BalanceResponse{Balance: "100", Verified: true, Block: 3},
+			http.StatusOK,
+		},
+		{
+			"balance-checkpoint-too-far",
+			"chain",
+			ProofConfig{CheckpointNumber: 1, CheckpointHash: chainCheckpointHash, CheckpointDistance: 1},
+			fmt.Sprintf("%v%v?%v=true&%v=3", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			map[string]string{"error": "verified read error: upstream nodes did not reach quorum on block hash: quorum 2 exceeds node count 1"},
+			http.StatusBadGateway,
+		},
+		{
+			"balance-checkpoint-unlinked",
+			"node",
+			ProofConfig{CheckpointNumber: 0, CheckpointHash: checkpointHash},
+			fmt.Sprintf("%v%v?%v=true&%v=1", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			map[string]string{"error": "verified read error: header 1 not found"},
+			http.StatusInternalServerError,
+		},
+		{
+			"balance-checkpoint-wrong-chain",
+			"chain",
+			ProofConfig{CheckpointNumber: 1, CheckpointHash: checkpointHash},
+			fmt.Sprintf("%v%v?%v=true&%v=3", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			map[string]string{"error": fmt.Sprintf("verified read error: block hash does not match configured checkpoint: block 1 hash %v", chainCheckpointHash)},
+			http.StatusBadGateway,
+		},
+		{
+			"balance-checkpoint-mismatch",
+			"node",
+			ProofConfig{CheckpointNumber: 0, CheckpointHash: common.Hash{1}.Hex()},
+			fmt.Sprintf("%v%v?%v=true&%v=0", EthV0BalancePrfx, dummyAddr, VerifiedKey, BlockKey),
+			map[string]string{"error": fmt.Sprintf("verified read error: block hash does not match configured checkpoint: block 0 hash %v", checkpointHash)},
+			http.StatusBadGateway,
+		},
+		{
+			"balance-invalid-proof",
+			"bad-proof,bad-proof",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			map[string]string{"error": "verified read error: invalid state proof: balance mismatch"},
+			http.StatusBadGateway,
+		},
+		{
+			"balance-proof-not-supported",
+			"no-proof,no-proof",
+			ProofConfig{},
+			fmt.Sprintf("%v%v?%v=true", EthV0BalancePrfx, dummyAddr, VerifiedKey),
+			map[string]string{"error": "verified read error: " + ErrProofNotSupported.Error()},
+			http.StatusNotImplemented,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cl, err := NewMultiNodeClient(tt.urls(), tt.constructor)
-			if (err != nil) != tt.expectErr {
-				t.Fatalf("unexpected error: %v", err)
 
+			l, err := NewLogger("error", "plain")
+			if err != nil {
+				t.Fatal(err)
 			}
+			cl, err := NewMultiNodeClient(tt.urls, constructorByURL)
 			if err != nil {
-				return
+				t.Fatal(err)
 			}
-			if g, w := len(cl.nodes), tt.expectedNodeCount; g != w {
-				t.Errorf("unexpected node count, got %v, want %v", g, w)
+			s := NewFromConfig(&Config{Port: 8080, Proof: tt.proofCfg}, l, cl)
+			s.Start()
+			defer s.Stop(os.Kill)
+
+			time.Sleep(10 * time.Millisecond)
+
+			b, code, err := executeRequest(http.MethodGet, fmt.Sprintf("http://0.0.0.0%v%v", s.Server().Addr(), tt.endpoint))
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+			if g, w := code, tt.expectedCode; g != w {
+				t.Errorf("%v unexpected response code, want %v got %v", tt.name, w, g)
 			}
-			n := len(cl.nodes) - 1
-			if len(cl.nodes) > 1 {
-				cl.increaseNodePriority(n, fmt.Sprintf("%d", n))
+
+			expectedJSON, _ := json.Marshal(tt.expectedResponse)
+
+			if g, w := b, expectedJSON; !bytes.Equal(g, w) {
+				t.Errorf("%v unexpected response, want %s, got %s", tt.name, w, g)
 			}
 		})
 	}
 
+	t.Run("drained-nodes-not-queried", func(t *testing.T) {
+		cl, err := NewMultiNodeClient("node,node,forked,forked", constructorByURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"2", "3"} {
+			if _, err := cl.DrainNode(id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		header, err := cl.headerQuorum(context.Background(), nil, 0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := header.Hash(), dummyStateHeader.Hash(); g != w {
+			t.Errorf("unexpected header hash, got %v want %v", g, w)
+		}
+
+		node := cl.snapshot()[0]
+		res := fanOut(context.Background(), []*item{node}, func(context.Context, SimpleEthClient) (int64, error) {
+			return node.stats.outstanding.Load(), nil
+		})
+		if g, w := res[0].val, int64(1); g != w {
+			t.Errorf("expected fanned out call to be in flight, got %d outstanding requests", g)
+		}
+	})
 }
 
-func Test_API(t *testing.T) {
+func Test_TxPool(t *testing.T) {
 
-	apiTests := []struct {
-		name               string
-		urls               string
-		serviceConstructor func(urls string) *Service
-		endpoint           func() string
-		methodType         string
-		expectedResponse   any
-		expectedCode       int
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress(dummyAddr)
+
+	signedTx := func(nonce uint64) *types.Transaction {
+		tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     nonce,
+			To:        &to,
+			Value:     big.NewInt(1),
+			Gas:       21000,
+			GasFeeCap: big.NewInt(2),
+			GasTipCap: big.NewInt(1),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	sent := []*types.Transaction{signedTx(0), signedTx(2)}
+
+	entry := func(nonce uint64, tx *types.Transaction) TxPoolEntry {
+		e := TxPoolEntry{Nonce: nonce, Txid: tx.Hash().Hex(), Value: tx.Value().String(), GasFeeCap: tx.GasFeeCap().String(), GasTipCap: tx.GasTipCap().String()}
+		if tx.To() != nil {
+			e.To = tx.To().Hex()
+		}
+		return e
+	}
+
+	var unsupportedCalls atomic.Int64
+	withoutTxPool := func(url string) (SimpleEthClient, error) {
+		if url == "no-txpool" {
+			return &fakeEthClientWithoutTxPool{calls: &unsupportedCalls}, nil
+		}
+		return newFakeEthClient(url)
+	}
+
+	tests := []struct {
+		name             string
+		urls             string
+		constructor      func(url string) (SimpleEthClient, error)
+		address          string
+		expectedResponse any
+		expectedCode     int
 	}{
-		//
-		// READ REQUESTS
-		//
-		{
-			"status",
-			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string { return StatusEndPnt },
-			http.MethodGet,
-			&StatusResponse{Message: "OK", Version: Version, Service: ServiceName},
-			http.StatusOK,
-		},
-		{
-			"health",
-			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string { return HeathEndPnt },
-			http.MethodGet,
-			&HealthResponse{Version: Version, Service: ServiceName, Failures: []string{}},
-			http.StatusOK,
-		},
 		{
-			"eth-balance",
+			"txpool-content-from",
 			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string { return fmt.Sprintf("%v%v", EthV0BalancePrfx, dummyAddr) },
-			http.MethodGet,
-			&BalanceResponse{Balance: "0"},
+			newFakeEthClient,
+			sender.Hex(),
+			&TxPoolResponse{
+				Address:   sender.Hex(),
+				Source:    TxPoolSourceNode,
+				Pending:   []TxPoolEntry{entry(0, dummyTx)},
+				Queued:    []TxPoolEntry{entry(2, dummyTx)},
+				NonceGaps: []uint64{1},
+			},
 			http.StatusOK,
 		},
 		{
-			"eth-tx",
-			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string { return fmt.Sprintf("%v%v", EthV0TxPrfx, dummyTxid) },
-			http.MethodGet,
-			&TxResponse{Tx: dummyTx, Txid: dummyTxid, IsPending: false},
+			"txpool-unsupported-by-first-node",
+			"no-txpool,txpool",
+			withoutTxPool,
+			sender.Hex(),
+			&TxPoolResponse{
+				Address:   sender.Hex(),
+				Source:    TxPoolSourceNode,
+				Pending:   []TxPoolEntry{entry(0, dummyTx)},
+				Queued:    []TxPoolEntry{entry(2, dummyTx)},
+				NonceGaps: []uint64{1},
+			},
 			http.StatusOK,
 		},
 		{
-			"eth-tx-receipt",
+			"proxy-journal-fallback",
 			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string { return fmt.Sprintf("%v%v", EthV0TxReceiptPrfx, dummyTxid) },
-			http.MethodGet,
-			&types.Receipt{},
+			newFakeEthClientWithoutProof,
+			sender.Hex(),
+			&TxPoolResponse{
+				Address:   sender.Hex(),
+				Source:    TxPoolSourceProxy,
+				Pending:   []TxPoolEntry{entry(0, sent[0])},
+				Queued:    []TxPoolEntry{entry(2, sent[1])},
+				NonceGaps: []uint64{1},
+			},
 			http.StatusOK,
 		},
 		{
-			"eth-tx-send",
+			"proxy-journal-unknown-sender",
 			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string {
-				b, _ := dummyTx.MarshalBinary()
-				return fmt.Sprintf("%v0x%x", EthV0SendTxPrfx, b)
+			newFakeEthClientWithoutProof,
+			dummyAddr,
+			&TxPoolResponse{
+				Address: common.HexToAddress(dummyAddr).Hex(),
+				Source:  TxPoolSourceProxy,
+				Pending: []TxPoolEntry{},
+				Queued:  []TxPoolEntry{},
 			},
-			http.MethodPost,
-			&TxResponse{Txid: dummyTx.Hash().Hex()},
 			http.StatusOK,
 		},
-		//
-		// CLIENT ERRORS
-		//
 		{
-			"eth-balance-malformed",
+			"invalid-address",
 			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string { return fmt.Sprintf("%v0xnotanaddress", EthV0BalancePrfx) },
-			http.MethodGet,
+			newFakeEthClient,
+			"0x1234",
 			map[string]string{"error": "invalid address format"},
 			http.StatusBadRequest,
 		},
-		{
-			"eth-tx-send-malformed",
-			"-",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClient) },
-			func() string {
-				return EthV0SendTxPrfx + "0xnotATx"
-			},
-			http.MethodPost,
-			map[string]string{"error": "invalid tx data: invalid hex string"},
-			http.StatusBadRequest,
-		},
-		//
-		// SERVER ERRORS
-		//
-		{
-			"health-node-err",
-			"testErr",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
-			func() string { return HeathEndPnt },
-			http.MethodGet,
-			&HealthResponse{Version: Version, Service: ServiceName, Failures: []string{"node 0 err: testErr"}},
-			http.StatusServiceUnavailable,
-		},
-		{
-			"eth-balance-err",
-			"testErr",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
-			func() string { return fmt.Sprintf("%v%v", EthV0BalancePrfx, dummyAddr) },
-			http.MethodGet,
-			map[string]string{"error": "eth client error: testErr"},
-			http.StatusInternalServerError,
-		},
-		{
-			"eth-tx-err",
-			"testErr",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
-			func() string { return fmt.Sprintf("%v%v", EthV0TxPrfx, dummyTxid) },
-			http.MethodGet,
-			map[string]string{"error": "eth client error: testErr"},
-			http.StatusInternalServerError,
-		},
-		{
-			"eth-receipt-err",
-			"testErr",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
-			func() string { return fmt.Sprintf("%v%v", EthV0TxReceiptPrfx, dummyTxid) },
-			http.MethodGet,
-			map[string]string{"error": "eth client error: testErr"},
-			http.StatusInternalServerError,
-		},
-		{
-			"eth-tx-send-err",
-			"testErr",
-			func(urls string) *Service { return makeTestService(t, urls, newFakeEthClientWithErr) },
-			func() string {
-				b, _ := dummyTx.MarshalBinary()
-				return fmt.Sprintf("%v0x%x", EthV0SendTxPrfx, b)
-			},
-			http.MethodPost,
-			map[string]string{"error": "eth client error: testErr"},
-			http.StatusInternalServerError,
-		},
 	}
 
-	for _, tt := range apiTests {
+	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			s := tt.serviceConstructor(tt.urls)
+			s := makeTestService(t, tt.urls, tt.constructor)
 			s.Start()
 			defer s.Stop(os.Kill)
 
 			time.Sleep(10 * time.Millisecond)
 
-			b, code, err := executeRequest(tt.methodType, fmt.Sprintf("http://0.0.0.0%v%v", s.Server().Addr(), tt.endpoint()))
+			for _, tx := range sent {
+				body, _ := tx.MarshalBinary()
+				body, _ = json.Marshal(&SendTxRequest{Tx: body})
+				if _, code, err := executeRequestWithBody(http.MethodPost, fmt.Sprintf("http://0.0.0.0%v%v", s.Server().Addr(), EthV0SendTxBodyEndPnt), body); err != nil || code != http.StatusOK {
+					t.Fatalf("%v: send failed code=%v err=%v", tt.name, code, err)
+				}
+			}
+
+			b, code, err := executeRequest(http.MethodGet, fmt.Sprintf("http://0.0.0.0%v%v%v", s.Server().Addr(), EthV0TxPoolPrfx, tt.address))
 			if err != nil {
 				t.Fatalf("%v: %v", tt.name, err)
 			}
@@ -490,8 +4346,39 @@ func Test_API(t *testing.T) {
 				t.Errorf("%v unexpected response, want %s, got %s", tt.name, w, g)
 			}
 		})
-
 	}
+
+	t.Run("unsupported-node-remembered", func(t *testing.T) {
+		unsupportedCalls.Store(0)
+		cl, err := NewMultiNodeClient("no-txpool,txpool", withoutTxPool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.SetSelectionStrategy(StrategyPriority); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := cl.TxPoolContentFrom(context.Background(), sender); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if g, w := unsupportedCalls.Load(), int64(1); g != w {
+			t.Errorf("expected the node without txpool_contentFrom to be called once, got %d calls", g)
+		}
+
+		// Once the retry interval has passed the node is asked again.
+		node := cl.snapshot()[0]
+		node.noTxPool.Store(time.Now().Add(-time.Second).UnixNano())
+		if _, err := cl.TxPoolContentFrom(context.Background(), sender); err != nil {
+			t.Fatal(err)
+		}
+		if g, w := unsupportedCalls.Load(), int64(2); g != w {
+			t.Errorf("expected the node without txpool_contentFrom to be asked again after the retry interval, got %d calls", g)
+		}
+		if until := time.Unix(0, node.noTxPool.Load()); time.Until(until) < txPoolRetryInterval-time.Minute {
+			t.Errorf("expected the node to be skipped for the retry interval, skipped until %v", until)
+		}
+	})
 }
 
 func Test_HealthCheckErr(t *testing.T) {
@@ -548,11 +4435,22 @@ func Test_HealthCheckErr(t *testing.T) {
 }
 
 func executeRequest(methodType, url string) (respBytes []byte, code int, err error) {
-	req, err := http.NewRequestWithContext(context.Background(), methodType, url, nil)
+	return executeRequestWithBody(methodType, url, nil)
+}
+
+func executeRequestWithBody(methodType, url string, body []byte) (respBytes []byte, code int, err error) {
+	return executeRequestWithToken(methodType, url, "", body)
+}
+
+func executeRequestWithToken(methodType, url, token string, body []byte) (respBytes []byte, code int, err error) {
+	req, err := http.NewRequestWithContext(context.Background(), methodType, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, 0, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	response, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -570,3 +4468,160 @@ func executeRequest(methodType, url string) (respBytes []byte, code int, err err
 	}
 	return b, response.StatusCode, nil
 }
+
+func Test_MultiChain(t *testing.T) {
+
+	t.Run("config", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			chain       string
+			chains      []ChainConfig
+			expectedErr string
+		}{
+			{"valid", "mainnet", []ChainConfig{{Name: "sepolia", ChainID: 11155111, URLs: "sepolia"}, {Name: "base-8453", URLs: "base"}}, ""},
+			{"invalid-name", "", []ChainConfig{{Name: "Sepolia", URLs: "sepolia"}}, "invalid chain name 'Sepolia': lower-case letters, digits and dashes expected"},
+			{"invalid-default-name", "main_net", nil, "invalid chain name 'main_net': lower-case letters, digits and dashes expected"},
+			{"reserved-name", "", []ChainConfig{{Name: "beacon", URLs: "sepolia"}}, "invalid chain name 'beacon': reserved by the /eth/v0/beacon endpoints"},
+			{"duplicate-name", "mainnet", []ChainConfig{{Name: "mainnet", URLs: "mainnet"}}, "duplicate chain name 'mainnet'"},
+			{"chain-id-mismatch", "", []ChainConfig{{Name: "sepolia", ChainID: 11155111, URLs: "sepolia,base"}}, "chain sepolia: node sepolia-1 chain id 8453 does not match configured chain id 11155111"},
+			{"no-nodes", "", []ChainConfig{{Name: "sepolia", URLs: "goerli"}}, "chain sepolia: cannot connect to any nodes url='goerli' err='unknown chain 'goerli''"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewChains(&Config{Chain: tt.chain, Chains: tt.chains}, newFakeChainNode)
+				if (err != nil) != (tt.expectedErr != "") || (err != nil && err.Error() != tt.expectedErr) {
+					t.Errorf("unexpected error, got %v want '%s'", err, tt.expectedErr)
+				}
+			})
+		}
+	})
+
+	cfg := &Config{
+		Port:    8080,
+		Chain:   "mainnet",
+		ChainID: 1,
+		URLs:    "mainnet",
+		Admin:   AdminConfig{Enabled: true, Token: "secret"},
+		Chains:  []ChainConfig{{Name: "sepolia", ChainID: 11155111, URLs: "sepolia,sepolia", Strategy: StrategyRoundRobin}},
+	}
+	l, err := NewLogger("error", "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := NewChainClient(cfg.DefaultChain(), newFakeChainNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chains, err := NewChains(cfg, newFakeChainNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewWithChains(cfg, l, cl, nil, chains)
+	s.Start()
+	defer s.Stop(os.Kill)
+
+	time.Sleep(10 * time.Millisecond)
+
+	baseURL := fmt.Sprintf("http://0.0.0.0%v", s.Server().Addr())
+
+	t.Run("routing", func(t *testing.T) {
+		tests := []struct {
+			name            string
+			path            string
+			chain           string // X-Chain header
+			expectedCode    int
+			expectedBalance string
+		}{
+			{"default", EthV0BalancePrfx + dummyAddr, "", http.StatusOK, "1"},
+			{"default-by-name", "/eth/v0/mainnet/balance/" + dummyAddr, "", http.StatusOK, "1"},
+			{"path", "/eth/v0/sepolia/balance/" + dummyAddr, "", http.StatusOK, "2"},
+			{"header", EthV0BalancePrfx + dummyAddr, "sepolia", http.StatusOK, "2"},
+			{"path-over-header", "/eth/v0/sepolia/balance/" + dummyAddr, "mainnet", http.StatusOK, "2"},
+			{"unknown-path", "/eth/v0/goerli/balance/" + dummyAddr, "", http.StatusNotFound, ""},
+			{"unknown-header", EthV0BalancePrfx + dummyAddr, "goerli", http.StatusNotFound, ""},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodGet, baseURL+tt.path, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.chain != "" {
+					req.Header.Set(ChainHeader, tt.chain)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if g, w := resp.StatusCode, tt.expectedCode; g != w {
+					t.Fatalf("unexpected status code, got %v want %v", g, w)
+				}
+				if tt.expectedCode != http.StatusOK {
+					return
+				}
+				var balance BalanceResponse
+				if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+					t.Fatal(err)
+				}
+				if g, w := balance.Balance, tt.expectedBalance; g != w {
+					t.Errorf("unexpected balance, got %v want %v", g, w)
+				}
+			})
+		}
+	})
+
+	t.Run("nodes", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, baseURL+AdminV0NodesEndPnt, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(ChainHeader, "sepolia")
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var infos []NodeInfo
+		if err := json.NewDecoder(resp.Body).Decode(&infos); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, info := range infos {
+			ids = append(ids, info.ID)
+		}
+		if g, w := strings.Join(ids, ","), "sepolia-0,sepolia-1"; g != w {
+			t.Errorf("unexpected node ids, got %v want %v", g, w)
+		}
+
+		admin := chains[0].Client.(NodeAdmin)
+		if _, err := admin.AddNode(context.Background(), NodeConfig{URL: "base"}); err == nil || !strings.Contains(err.Error(), "chain id 8453 does not match configured chain id 11155111") {
+			t.Errorf("expected chain id mismatch, got %v", err)
+		}
+		info, err := admin.AddNode(context.Background(), NodeConfig{URL: "sepolia"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, w := info.ID, "sepolia-2"; g != w {
+			t.Errorf("unexpected node id, got %v want %v", g, w)
+		}
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		for _, strategy := range []struct{ chain, name string }{{"", StrategyEWMA}, {"sepolia", StrategyRoundRobin}} {
+			if g := testutil.ToFloat64(selectionStrategyGauge.WithLabelValues(strategy.chain, strategy.name)); g != 1 {
+				t.Errorf("expected the %s strategy to be recorded for chain %s, got %v", strategy.name, strategy.chain, g)
+			}
+		}
+		mainnet, sepolia := newResponseCache(CacheConfig{MaxBytes: 1000}, "mainnet"), newResponseCache(CacheConfig{MaxBytes: 1000}, "sepolia")
+		mainnet.setHead(1, 0)
+		mainnet.put("key", big.NewInt(1), scopeHead, 1)
+		if g := testutil.ToFloat64(cacheBytesGauge.WithLabelValues("mainnet")); g != float64(mainnet.bytes) || g == 0 {
+			t.Errorf("unexpected mainnet cache size %v, want %v", g, mainnet.bytes)
+		}
+		if g := testutil.ToFloat64(cacheBytesGauge.WithLabelValues("sepolia")); g != float64(sepolia.bytes) {
+			t.Errorf("unexpected sepolia cache size %v, want %v", g, sepolia.bytes)
+		}
+	})
+}
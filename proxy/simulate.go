@@ -0,0 +1,207 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	SimulateKey = "simulate" // optional send endpoint query parameter requesting a pre-broadcast simulation
+
+	rpcExecutionRevertedCode = 3      // JSON-RPC error code of an eth_call which reverted with revert data
+	rpcServerErrorCode       = -32000 // JSON-RPC error code of other eth_call failures, including bare reverts and VM errors
+)
+
+// vmExecutionErrors are the messages of the EVM execution errors (see go-ethereum core/vm) returned
+// for an eth_call which ran but failed, such as a bare revert() without data or running out of gas.
+var vmExecutionErrors = []string{
+	"execution reverted", "out of gas", "max call depth exceeded", "insufficient balance for transfer",
+	"contract address collision", "max code size exceeded", "max initcode size exceeded", "invalid jump destination",
+	"write protection", "return data out of bounds", "gas uint64 overflow", "invalid code: must not begin with 0xef",
+	"nonce uint64 overflow", "stack underflow", "stack limit reached", "invalid opcode",
+}
+
+// errSimulationReverted is returned when a transaction fails simulation and must not be broadcast.
+var errSimulationReverted = errors.New("transaction would revert")
+
+// SimulationResponse contains the outcome of executing a signed transaction against
+// the pending state without broadcasting it.
+type SimulationResponse struct {
+	Txid         string `json:"txid"`
+	From         string `json:"from"`
+	Success      bool   `json:"success"`
+	Result       string `json:"result,omitempty"`
+	GasEstimate  uint64 `json:"gas_estimate,omitempty"` // eth_estimateGas result, only reported by the simulate endpoint
+	RevertReason string `json:"revert_reason,omitempty"`
+}
+
+// txCallMsg recovers the transaction sender and builds the equivalent eth_call message.
+func txCallMsg(tx *types.Transaction) (ethereum.CallMsg, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return ethereum.CallMsg{}, fmt.Errorf("could not recover sender: %w", err)
+	}
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		msg.GasPrice = tx.GasPrice()
+	default:
+		msg.GasFeeCap, msg.GasTipCap = tx.GasFeeCap(), tx.GasTipCap()
+	}
+	if tx.Type() == types.BlobTxType {
+		msg.BlobGasFeeCap, msg.BlobHashes = tx.BlobGasFeeCap(), tx.BlobHashes()
+	}
+	return msg, nil
+}
+
+// simulateTx executes the transaction with eth_call against the pending state, estimating its gas
+// with eth_estimateGas if estimate is set. Execution reverts are returned as an unsuccessful
+// simulation; any other error (rate limiting, unsupported methods, invalid parameters etc.) means
+// the simulation could not be performed.
+func simulateTx(ctx context.Context, ethClient SimpleEthClient, tx *types.Transaction, msg ethereum.CallMsg, estimate bool) (*SimulationResponse, error) {
+	sim := &SimulationResponse{Txid: tx.Hash().Hex(), From: msg.From.Hex()}
+
+	result, err := ethClient.PendingCallContract(ctx, msg)
+	if err != nil {
+		if !isExecutionRevert(err) {
+			return nil, err
+		}
+		sim.RevertReason = revertReason(err)
+		return sim, nil
+	}
+	sim.Success, sim.Result = true, hexutil.Encode(result)
+
+	if estimate {
+		gas, err := ethClient.EstimateGas(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		sim.GasEstimate = gas
+	}
+	return sim, nil
+}
+
+// isExecutionRevert reports whether an eth_call error shows that the call was executed and failed: an
+// error with the execution reverted code, an execution reverted message carrying revert data, or a
+// server error carrying an EVM execution error such as a revert without data, running out of gas or
+// an invalid opcode.
+func isExecutionRevert(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	msg := strings.ToLower(rpcErr.Error())
+	var dataErr rpc.DataError
+	switch {
+	case rpcErr.ErrorCode() == rpcExecutionRevertedCode:
+		return true
+	case strings.Contains(msg, "execution reverted") && errors.As(err, &dataErr) && dataErr.ErrorData() != nil:
+		return true
+	case rpcErr.ErrorCode() != rpcServerErrorCode:
+		return false
+	}
+	for _, vmErr := range vmExecutionErrors {
+		if strings.Contains(msg, vmErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// revertReason extracts a human readable reason from an eth_call error. Error(string) revert
+// data is decoded, custom errors are returned as hex and all other errors use the node message.
+func revertReason(err error) string {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if b, decodeErr := hexutil.Decode(data); decodeErr == nil && len(b) > 0 {
+				if reason, unpackErr := abi.UnpackRevert(b); unpackErr == nil {
+					return reason
+				}
+				return data
+			}
+		}
+	}
+	return err.Error()
+}
+
+// simulateOrReject runs a pre-broadcast simulation, writing the appropriate error response and
+// returning a nil result if the transaction cannot be simulated or would revert.
+func simulateOrReject(ctx context.Context, w http.ResponseWriter, ethClient SimpleEthClient, tx *types.Transaction) *SimulationResponse {
+	msg, err := txCallMsg(tx)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return nil
+	}
+	sim, err := simulateTx(ctx, ethClient, tx, msg, false)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, fmt.Errorf("eth client error: %v", err))
+		return nil
+	}
+	if !sim.Success {
+		respondWithError(w, http.StatusUnprocessableEntity, fmt.Errorf("%w: %s", errSimulationReverted, sim.RevertReason))
+		return nil
+	}
+	return sim
+}
+
+// SimulateTx returns a handler which executes a signed transaction against the pending
+// state and reports the outcome without broadcasting it.
+func SimulateTx(ethClient SimpleEthClient) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+		var req SendTxRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTxBodySize)).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid tx data: %v", err))
+			return
+		}
+
+		tx := &types.Transaction{}
+		if err := tx.UnmarshalBinary(req.Tx); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("could not unmarshal tx JSON: %v", err))
+			return
+		}
+
+		msg, err := txCallMsg(tx)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+
+		sim, err := simulateTx(ctx, ethClient, tx, msg, true)
+		if err != nil {
+			respondWithError(w, http.StatusBadGateway, fmt.Errorf("eth client error: %v", err))
+			return
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, sim); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}
+
+// wantsSimulation reports whether the caller requested a pre-broadcast simulation.
+func wantsSimulation(r *http.Request) bool {
+	return r.URL.Query().Get(SimulateKey) == "true"
+}
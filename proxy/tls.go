@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// tlsVersions are the values accepted for TLSConfig.MinVersion.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// enabled reports whether any TLS setting was configured.
+func (c TLSConfig) enabled() bool {
+	return c != TLSConfig{}
+}
+
+// clientConfig returns the TLS client config for the node at host, or nil if no TLS setting was
+// configured. The CA bundle and client certificate are loaded immediately and reloaded by later
+// handshakes once their files change; onReload is called after each reload.
+func (c TLSConfig) clientConfig(host string, onReload func()) (*tls.Config, error) {
+	if !c.enabled() {
+		return nil, nil
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("tls: both a certificate and a key file are required for a client certificate")
+	}
+	serverName := c.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	cfg := &tls.Config{ServerName: c.ServerName, MinVersion: tls.VersionTLS12}
+	if c.MinVersion != "" {
+		version, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown minimum version '%s'", c.MinVersion)
+		}
+		cfg.MinVersion = version
+	}
+	files := &certFiles{cfg: c, onReload: onReload}
+	if err := files.load(); err != nil {
+		return nil, err
+	}
+	if c.CertFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := files.current()
+			return cert, nil
+		}
+	}
+	if c.CAFile != "" {
+		// The node certificate is verified against the current CA bundle by VerifyConnection rather than
+		// by the handshake, whose root pool cannot be replaced once the config is in use.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			_, roots := files.current()
+			return verifyPeer(cs, roots, serverName)
+		}
+	}
+	return cfg, nil
+}
+
+// verifyPeer verifies the certificate chain presented by the server against roots and the server name.
+func verifyPeer(cs tls.ConnectionState, roots *x509.CertPool, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: serverName})
+	return err
+}
+
+// fileStamp identifies the version of a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certFiles holds the CA bundle and client certificate loaded from the files of a TLSConfig.
+type certFiles struct {
+	cfg      TLSConfig
+	onReload func()
+
+	mu     sync.Mutex
+	stamps map[string]fileStamp // stamp of each file when it was last loaded
+	cert   *tls.Certificate
+	roots  *x509.CertPool
+}
+
+// current returns the client certificate and CA bundle, reloading them first if any of their files has
+// changed. The previous certificates are kept if the new files cannot be loaded (for example while they
+// are being replaced), and the reload is tried again by the next handshake.
+func (f *certFiles) current() (*tls.Certificate, *x509.CertPool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.changed() && f.load() == nil && f.onReload != nil {
+		go f.onReload()
+	}
+	cert := f.cert
+	if cert == nil {
+		cert = &tls.Certificate{} // no client certificate is sent
+	}
+	return cert, f.roots
+}
+
+// files returns the paths of the configured certificate files.
+func (f *certFiles) files() []string {
+	var files []string
+	for _, file := range []string{f.cfg.CAFile, f.cfg.CertFile, f.cfg.KeyFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// changed reports whether any file differs from the version last loaded.
+func (f *certFiles) changed() bool {
+	for _, file := range f.files() {
+		info, err := os.Stat(file)
+		if err != nil || f.stamps[file] != (fileStamp{info.ModTime(), info.Size()}) {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the configured files.
+func (f *certFiles) load() error {
+	stamps := make(map[string]fileStamp)
+	for _, file := range f.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("tls: %v", err)
+		}
+		stamps[file] = fileStamp{info.ModTime(), info.Size()}
+	}
+	var cert *tls.Certificate
+	if f.cfg.CertFile != "" {
+		pair, err := tls.LoadX509KeyPair(filepath.Clean(f.cfg.CertFile), filepath.Clean(f.cfg.KeyFile))
+		if err != nil {
+			return fmt.Errorf("tls: client certificate: %v", err)
+		}
+		cert = &pair
+	}
+	var roots *x509.CertPool
+	if f.cfg.CAFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(f.cfg.CAFile))
+		if err != nil {
+			return fmt.Errorf("tls: %v", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates found in %s", f.cfg.CAFile)
+		}
+	}
+	f.stamps, f.cert, f.roots = stamps, cert, roots
+	return nil
+}
+
+// httpTransport returns a copy of the default HTTP transport using the TLS settings of the node at host.
+// Idle connections are closed when the certificates are reloaded so that new requests handshake with
+// the new certificates.
+func (c TLSConfig) httpTransport(host string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !c.enabled() {
+		return transport, nil
+	}
+	tlsCfg, err := c.clientConfig(host, transport.CloseIdleConnections)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsCfg
+	return transport, nil
+}
+
+// websocketDialer returns a WebSocket dialer using the TLS settings of the node at host (with the buffer
+// sizes of the rpc package default dialer), or false if none were configured.
+func (c TLSConfig) websocketDialer(host string) (websocket.Dialer, bool, error) {
+	if !c.enabled() {
+		return websocket.Dialer{}, false, nil
+	}
+	tlsCfg, err := c.clientConfig(host, nil)
+	if err != nil {
+		return websocket.Dialer{}, false, err
+	}
+	return websocket.Dialer{ReadBufferSize: 1024, WriteBufferSize: 1024, Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsCfg}, true, nil
+}
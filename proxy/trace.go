@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	CallTracer     = "callTracer"     // geth built-in call graph tracer
+	PrestateTracer = "prestateTracer" // geth built-in prestate tracer
+
+	TracerKey = "tracer" // optional query parameter restricting the trace output to a single tracer
+)
+
+// TraceResponse contains the tracer output for a transaction together with the
+// internal ether transfers extracted from the call graph.
+type TraceResponse struct {
+	Txid              string             `json:"txid"`
+	CallTrace         json.RawMessage    `json:"call_trace,omitempty"`
+	PrestateTrace     json.RawMessage    `json:"prestate_trace,omitempty"`
+	InternalTransfers []InternalTransfer `json:"internal_transfers,omitempty"`
+}
+
+// InternalTransfer is an ether transfer made by a message call within a transaction.
+type InternalTransfer struct {
+	Type  string `json:"type"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value string `json:"value"`
+	Depth int    `json:"depth"`
+}
+
+// callFrame mirrors the JSON output of the geth callTracer.
+type callFrame struct {
+	Type  string          `json:"type"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to,omitempty"`
+	Value *hexutil.Big    `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+	Calls []callFrame     `json:"calls,omitempty"`
+}
+
+// internalTransfers flattens a callTracer result into the list of ether transfers made
+// by internal calls. The top level call is skipped (it is the transaction itself) as
+// are reverted frames and any calls nested below them.
+func internalTransfers(callTrace json.RawMessage) ([]InternalTransfer, error) {
+	var root callFrame
+	if err := json.Unmarshal(callTrace, &root); err != nil {
+		return nil, fmt.Errorf("could not decode call trace: %w", err)
+	}
+	if root.Error != "" {
+		return nil, nil
+	}
+	var transfers []InternalTransfer
+	var walk func(frames []callFrame, depth int)
+	walk = func(frames []callFrame, depth int) {
+		for _, f := range frames {
+			if f.Error != "" {
+				continue
+			}
+			if movesValue(f) {
+				transfers = append(transfers, InternalTransfer{
+					Type:  f.Type,
+					From:  f.From.Hex(),
+					To:    f.To.Hex(),
+					Value: f.Value.ToInt().String(),
+					Depth: depth,
+				})
+			}
+			walk(f.Calls, depth+1)
+		}
+	}
+	walk(root.Calls, 1)
+	return transfers, nil
+}
+
+// movesValue reports whether a call frame transferred ether between two accounts.
+// DELEGATECALL and STATICCALL frames never move value and CALLCODE transfers to self.
+func movesValue(f callFrame) bool {
+	if f.Value == nil || f.To == nil || f.Value.ToInt().Cmp(big.NewInt(0)) <= 0 {
+		return false
+	}
+	switch strings.ToUpper(f.Type) {
+	case "CALL", "CREATE", "CREATE2", "SELFDESTRUCT":
+		return true
+	default:
+		return false
+	}
+}
+
+// TxTrace returns a handler for the debug_traceTransaction proxy endpoint.
+func TxTrace(ethClient SimpleEthClient) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+
+		debugClient, ok := ethClient.(DebugEthClient)
+		if !ok {
+			respondWithError(w, http.StatusNotImplemented, ErrDebugNotSupported)
+			return
+		}
+
+		txid := p.ByName(IDKey[1:])
+		if !isHexHash(txid) {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid hash"))
+			return
+		}
+		txHash := common.HexToHash(txid)
+
+		tracers := []string{CallTracer, PrestateTracer}
+		if tracer := r.URL.Query().Get(TracerKey); tracer != "" {
+			if tracer != CallTracer && tracer != PrestateTracer {
+				respondWithError(w, http.StatusBadRequest, fmt.Errorf("unsupported tracer '%s'", tracer))
+				return
+			}
+			tracers = []string{tracer}
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+
+		resp := &TraceResponse{Txid: txHash.Hex()}
+		for _, tracer := range tracers {
+			result, err := debugClient.TraceTransaction(ctx, txHash, tracer)
+			if errors.Is(err, ErrDebugNotSupported) {
+				respondWithError(w, http.StatusNotImplemented, err)
+				return
+			}
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+				return
+			}
+			switch tracer {
+			case CallTracer:
+				transfers, err := internalTransfers(result)
+				if err != nil {
+					respondWithError(w, http.StatusInternalServerError, err)
+					return
+				}
+				resp.CallTrace, resp.InternalTransfers = result, transfers
+			case PrestateTracer:
+				resp.PrestateTrace = result
+			}
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+
+	})
+}
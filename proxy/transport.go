@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// Transport is the kind of connection held to an upstream node.
+type Transport string
+
+const (
+	TransportHTTP Transport = "http" // a request per call over HTTP(S)
+	TransportWS   Transport = "ws"   // a persistent WebSocket connection
+	TransportIPC  Transport = "ipc"  // a persistent connection to a local IPC socket
+
+	TransportUnknown Transport = "" // the URL has an unsupported scheme and cannot be dialled
+)
+
+const ipcScheme = "ipc://"
+
+// TransportOf returns the transport used to reach the node at url. URLs with the ipc:// scheme and
+// absolute file paths are IPC sockets. Any other URL, such as one with a mistyped scheme, has an
+// unknown transport.
+func TransportOf(url string) Transport {
+	lower := strings.ToLower(url)
+	switch {
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return TransportHTTP
+	case strings.HasPrefix(lower, "ws://"), strings.HasPrefix(lower, "wss://"):
+		return TransportWS
+	case strings.HasPrefix(lower, ipcScheme), filepath.IsAbs(url):
+		return TransportIPC
+	default:
+		return TransportUnknown
+	}
+}
+
+// validateNodeURL rejects a node URL whose transport is unknown. The error carries the redacted URL.
+func validateNodeURL(url string) error {
+	if TransportOf(url) == TransportUnknown {
+		return fmt.Errorf("unsupported node url '%s': http(s)://, ws(s)://, ipc:// or an absolute IPC socket path expected", RedactURL(url))
+	}
+	return nil
+}
+
+// persistent reports whether the transport holds a connection open between calls.
+func (t Transport) persistent() bool {
+	return t == TransportWS || t == TransportIPC
+}
+
+// dialURL returns the address passed to the rpc client for url.
+func dialURL(url string) string {
+	if strings.HasPrefix(strings.ToLower(url), ipcScheme) {
+		return url[len(ipcScheme):]
+	}
+	return url
+}
+
+// pinger is implemented by clients which can check that their connection is alive.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// headSubscriber is implemented by clients which can subscribe to new chain heads.
+type headSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// Ping checks that the connection to the node is alive with a net_version call. The rpc client redials
+// a dropped WebSocket or IPC connection when the next call is made.
+func (e *ethClient) Ping(ctx context.Context) error {
+	var version string
+	return e.rpc.CallContext(ctx, &version, "net_version")
+}
+
+// keepalive pings every node with a persistent connection so that a connection dropped while idle is
+// noticed and redialled before requests need it. Failed pings count against the node circuit breaker
+// and the first successful ping after a failure is counted and logged as a reconnect.
+func (m *multiNodeClient) keepalive(parent context.Context) {
+	ctx, cancelFunc := context.WithTimeout(parent, timeout)
+	defer cancelFunc()
+
+	var nodes []*item
+	for _, node := range m.snapshot() {
+		if _, ok := node.client.(pinger); ok && TransportOf(node.url).persistent() {
+			nodes = append(nodes, node)
+		}
+	}
+	results := fanOut(ctx, nodes, func(ctx context.Context, c SimpleEthClient) (struct{}, error) {
+		return struct{}{}, c.(pinger).Ping(ctx)
+	})
+	if parent.Err() != nil {
+		return // health checks were stopped while pinging
+	}
+
+	m.mu.Lock()
+	l := m.logger
+	m.mu.Unlock()
+	for _, r := range results {
+		switch {
+		case r.err != nil && !r.node.connLost.Swap(true):
+			if l != nil {
+				l.WithFields(logrus.Fields{"node": r.node.id, "transport": TransportOf(r.node.url), "error": r.err.Error()}).Warn("nodeConnectionLost")
+			}
+		case r.err == nil && r.node.connLost.Swap(false):
+			r.node.noTxPool.Store(0) // the node may have been restarted with the txpool namespace enabled
+			nodeReconnectsCounter.WithLabelValues(r.node.id).Inc()
+			if l != nil {
+				l.WithFields(logrus.Fields{"node": r.node.id, "transport": TransportOf(r.node.url)}).Info("nodeReconnected")
+			}
+		}
+		if r.err != nil && r.node.breaker.State() == BreakerOpen {
+			continue // wait for the cooldown before counting further failures
+		}
+		m.report(r.node, r.err)
+	}
+}
+
+// subscribeHeads subscribes to new heads on the first node in rotation with a persistent connection,
+// returning nil if no node accepts the subscription.
+func (m *multiNodeClient) subscribeHeads(parent context.Context, ch chan<- *types.Header) ethereum.Subscription {
+	for _, node := range m.rotation(nil) {
+		s, ok := node.client.(headSubscriber)
+		if !ok || !TransportOf(node.url).persistent() {
+			continue
+		}
+		ctx, cancelFunc := context.WithTimeout(parent, timeout)
+		sub, err := s.SubscribeNewHead(ctx, ch)
+		cancelFunc()
+		if err == nil {
+			return sub
+		}
+		m.logHeadError(err)
+	}
+	return nil
+}
+
+// startKeepalive pings the persistent connections at the configured interval until ctx is cancelled.
+func (m *multiNodeClient) startKeepalive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.keepalive(ctx)
+	}
+}
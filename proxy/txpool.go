@@ -0,0 +1,233 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	TxPoolSourceNode  = "txpool" // pool contents reported by an upstream node (txpool_contentFrom)
+	TxPoolSourceProxy = "proxy"  // pool contents reconstructed from transactions sent through the proxy
+
+	maxJournalSenders      = 4096 // maximum number of senders tracked by the TxJournal
+	maxJournalTxsPerSender = 64   // maximum number of transactions tracked per sender
+	maxReportedNonceGaps   = 256  // caps the nonce gap list returned for a sender
+
+	txPoolRetryInterval = 10 * time.Minute // time before a node which reported txpool_contentFrom unsupported is asked again
+)
+
+// ErrTxPoolNotSupported is returned when no upstream node can serve txpool namespace calls.
+var ErrTxPoolNotSupported = errors.New("no upstream node supports the txpool namespace")
+
+// TxPoolEthClient exposes the txpool_contentFrom call.
+type TxPoolEthClient interface {
+	TxPoolContentFrom(ctx context.Context, account common.Address) (*TxPoolContent, error) // returns the pending and queued transactions of a single sender.
+}
+
+// TxPoolContent contains the pending and queued transactions of a single sender keyed by nonce.
+type TxPoolContent struct {
+	Pending map[uint64]*types.Transaction
+	Queued  map[uint64]*types.Transaction
+}
+
+// newTxPoolContent converts a txpool_contentFrom result, which keys transactions by decimal nonce string.
+func newTxPoolContent(result map[string]map[string]*types.Transaction) (*TxPoolContent, error) {
+	content := &TxPoolContent{
+		Pending: make(map[uint64]*types.Transaction),
+		Queued:  make(map[uint64]*types.Transaction),
+	}
+	for section, dst := range map[string]map[uint64]*types.Transaction{"pending": content.Pending, "queued": content.Queued} {
+		for key, tx := range result[section] {
+			nonce, err := strconv.ParseUint(key, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s nonce '%s'", section, key)
+			}
+			dst[nonce] = tx
+		}
+	}
+	return content, nil
+}
+
+// TxJournal records the transactions broadcast through the proxy indexed by sender and nonce. It
+// is used to report pool contents when no upstream node serves the txpool namespace.
+type TxJournal struct {
+	mu  sync.Mutex
+	txs map[common.Address]map[uint64]*types.Transaction
+}
+
+// NewTxJournal returns an empty TxJournal.
+func NewTxJournal() *TxJournal {
+	return &TxJournal{txs: make(map[common.Address]map[uint64]*types.Transaction)}
+}
+
+// record adds a successfully broadcast transaction, replacing any earlier transaction with the same
+// sender and nonce. Transactions whose sender cannot be recovered are ignored.
+func (j *TxJournal) record(tx *types.Transaction) {
+	if j == nil {
+		return
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	txs, ok := j.txs[from]
+	if !ok {
+		if len(j.txs) >= maxJournalSenders {
+			return
+		}
+		txs = make(map[uint64]*types.Transaction)
+		j.txs[from] = txs
+	}
+	if _, replaced := txs[tx.Nonce()]; !replaced && len(txs) >= maxJournalTxsPerSender {
+		return
+	}
+	txs[tx.Nonce()] = tx
+}
+
+// content drops recorded transactions below the confirmed nonce of the sender and classifies the
+// remainder the way a node's pool would: transactions forming a contiguous nonce sequence starting
+// at the confirmed nonce are pending, all others are queued.
+func (j *TxJournal) content(account common.Address, nonce uint64) *TxPoolContent {
+	content := &TxPoolContent{
+		Pending: make(map[uint64]*types.Transaction),
+		Queued:  make(map[uint64]*types.Transaction),
+	}
+	if j == nil {
+		return content
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	txs := j.txs[account]
+	for n, tx := range txs {
+		if n < nonce {
+			delete(txs, n)
+			continue
+		}
+		content.Queued[n] = tx
+	}
+	if len(txs) == 0 {
+		delete(j.txs, account)
+	}
+	for n := nonce; content.Queued[n] != nil; n++ {
+		content.Pending[n] = content.Queued[n]
+		delete(content.Queued, n)
+	}
+	return content
+}
+
+// TxPoolResponse lists the pending and queued transactions of a sender. Nonce is the next nonce
+// expected by the latest block and NonceGaps are the missing nonces preventing queued transactions
+// from becoming executable.
+type TxPoolResponse struct {
+	Address   string        `json:"address"`
+	Nonce     uint64        `json:"nonce"`
+	Source    string        `json:"source"`
+	Pending   []TxPoolEntry `json:"pending"`
+	Queued    []TxPoolEntry `json:"queued"`
+	NonceGaps []uint64      `json:"nonce_gaps,omitempty"`
+}
+
+// TxPoolEntry summarizes a pool transaction. Fee values are formatted as wei strings.
+type TxPoolEntry struct {
+	Nonce     uint64 `json:"nonce"`
+	Txid      string `json:"txid"`
+	To        string `json:"to,omitempty"`
+	Value     string `json:"value"`
+	GasFeeCap string `json:"gas_fee_cap"`
+	GasTipCap string `json:"gas_tip_cap"`
+}
+
+// txPoolEntries converts pool transactions to a list of entries sorted by nonce.
+func txPoolEntries(txs map[uint64]*types.Transaction) []TxPoolEntry {
+	entries := make([]TxPoolEntry, 0, len(txs))
+	for nonce, tx := range txs {
+		entry := TxPoolEntry{
+			Nonce:     nonce,
+			Txid:      tx.Hash().Hex(),
+			Value:     tx.Value().String(),
+			GasFeeCap: tx.GasFeeCap().String(),
+			GasTipCap: tx.GasTipCap().String(),
+		}
+		if to := tx.To(); to != nil {
+			entry.To = to.Hex()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Nonce < entries[j].Nonce })
+	return entries
+}
+
+// nonceGaps returns the nonces between the confirmed nonce and the highest queued nonce for which
+// the pool holds no transaction.
+func nonceGaps(content *TxPoolContent, nonce uint64) []uint64 {
+	var highest uint64
+	for n := range content.Queued {
+		highest = max(highest, n)
+	}
+	var gaps []uint64
+	for n := nonce; n < highest && len(gaps) < maxReportedNonceGaps; n++ {
+		if content.Pending[n] == nil && content.Queued[n] == nil {
+			gaps = append(gaps, n)
+		}
+	}
+	return gaps
+}
+
+// TxPool returns a handler listing the pending and queued transactions of a sender. Pool contents
+// are read with txpool_contentFrom where an upstream node supports it, otherwise they are rebuilt
+// from the transactions recorded in the journal.
+func TxPool(ethClient SimpleEthClient, journal *TxJournal) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+
+		address := p.ByName(AddressKey[1:])
+
+		if !common.IsHexAddress(address) {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid address format"))
+			return
+		}
+		account := common.HexToAddress(address)
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+		defer cancelFunc()
+
+		nonce, err := ethClient.NonceAt(ctx, account, nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("eth client error: %v", err))
+			return
+		}
+
+		var content *TxPoolContent
+		source := TxPoolSourceNode
+		if poolClient, ok := ethClient.(TxPoolEthClient); ok {
+			content, err = poolClient.TxPoolContentFrom(ctx, account)
+		}
+		if content == nil || err != nil {
+			content, source = journal.content(account, nonce), TxPoolSourceProxy
+		}
+
+		resp := &TxPoolResponse{
+			Address:   account.Hex(),
+			Nonce:     nonce,
+			Source:    source,
+			Pending:   txPoolEntries(content.Pending),
+			Queued:    txPoolEntries(content.Queued),
+			NonceGaps: nonceGaps(content, nonce),
+		}
+
+		if err := respondWithJSON(w, http.StatusOK, resp); err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("respond error: %v", err))
+		}
+	})
+}